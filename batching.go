@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/brody192/locomotive/internal/batch"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+// rough per log overhead added by the reconstructors on top of the raw log contents, such as keys, quotes and separators
+const estimatedLogOverhead = 64
+
+// batchOptions returns the batch limits of the destination, lowered to the limits of its webhook mode where needed
+func batchOptions(destination config.Destination) batch.Options {
+	modeConfig := config.WebhookModeToConfig[destination.WebhookMode]
+
+	opts := batch.Options{
+		MaxCount:  destination.BatchMaxCount(),
+		MaxBytes:  destination.MaxRequestBytes(),
+		MaxLinger: destination.BatchMaxLinger(),
+	}
+
+	maxBatchCount := modeConfig.MaxBatchCount
+
+	// the gelf http input takes a single message per request
+	if destination.WebhookMode == config.WebhookModeGelf && !config.IsSocketUrl(destination.WebhookMode, destination.WebhookUrl) {
+		maxBatchCount = 1
+	}
+
+	if maxBatchCount > 0 && (opts.MaxCount <= 0 || maxBatchCount < opts.MaxCount) {
+		opts.MaxCount = maxBatchCount
+	}
+
+	return opts
+}
+
+func estimateMetadataSize(metadata map[string]string) int64 {
+	var size int64
+
+	for key, value := range metadata {
+		size += int64(len(key) + len(value) + 6)
+	}
+
+	return size
+}
+
+func estimateDeployLogSize(log environment_logs.EnvironmentLogWithMetadata) int64 {
+	size := int64(estimatedLogOverhead+len(log.Log.Message)+len(log.Log.Severity)) + estimateMetadataSize(log.Metadata)
+
+	for _, attribute := range log.Log.Attributes {
+		size += int64(len(attribute.Key) + len(attribute.Value) + 4)
+	}
+
+	return size
+}
+
+func estimateHttpLogSize(log http_logs.DeploymentHttpLogWithMetadata) int64 {
+	return int64(estimatedLogOverhead+len(log.Log)+len(log.Path)) + estimateMetadataSize(log.Metadata)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/batch"
+	"github.com/brody192/locomotive/internal/config"
+)
+
+func TestBatchOptions(t *testing.T) {
+	previous := config.Global
+	t.Cleanup(func() { config.Global = previous })
+
+	config.Global.BatchMaxCount = 5000
+	config.Global.BatchMaxBytes = 0
+	config.Global.BatchMaxLinger = time.Second
+
+	maxCount := 10
+	maxLinger := 50 * time.Millisecond
+
+	tests := []struct {
+		name        string
+		destination config.Destination
+
+		want batch.Options
+	}{
+		{
+			name:        "global limits",
+			destination: config.Destination{WebhookMode: config.WebhookModeJson},
+			want:        batch.Options{MaxCount: 5000, MaxLinger: time.Second},
+		},
+		{
+			name: "destination limits",
+			destination: config.Destination{
+				WebhookMode: config.WebhookModeJson,
+				Batch:       config.Batch{MaxCount: &maxCount, MaxLinger: &maxLinger},
+			},
+			want: batch.Options{MaxCount: 10, MaxLinger: 50 * time.Millisecond},
+		},
+		{
+			name:        "lowered to the limits of the mode",
+			destination: config.Destination{WebhookMode: config.WebhookModeDatadog},
+			want:        batch.Options{MaxCount: 1000, MaxBytes: 5 << 20, MaxLinger: time.Second},
+		},
+		{
+			name: "a lower destination limit is kept",
+			destination: config.Destination{
+				WebhookMode: config.WebhookModeDatadog,
+				Batch:       config.Batch{MaxCount: &maxCount},
+			},
+			want: batch.Options{MaxCount: 10, MaxBytes: 5 << 20, MaxLinger: time.Second},
+		},
+		{
+			name: "gelf over http sends a message per request",
+			destination: config.Destination{
+				WebhookMode: config.WebhookModeGelf,
+				WebhookUrl:  url.URL{Scheme: "https", Host: "graylog.example.com"},
+			},
+			want: batch.Options{MaxCount: 1, MaxLinger: time.Second},
+		},
+		{
+			name: "gelf over udp",
+			destination: config.Destination{
+				WebhookMode: config.WebhookModeGelf,
+				WebhookUrl:  url.URL{Scheme: "udp", Host: "graylog.example.com:12201"},
+			},
+			want: batch.Options{MaxCount: 5000, MaxLinger: time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := batchOptions(tt.destination); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/brody192/locomotive/internal/buffer"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+)
+
+func bufferOptions() buffer.Options {
+	return buffer.Options{
+		MaxItems:    config.Global.BufferMaxEvents,
+		MaxBytes:    int64(config.Global.BufferMaxBytes),
+		Policy:      config.Global.BufferPolicy,
+		MinSeverity: config.Global.BufferDropSeverity.Rank(),
+	}
+}
+
+// the severity is only needed once the buffer is full, so it is detected from the message here rather than relying on the handler having done so
+func deployLogSeverity(log environment_logs.EnvironmentLogWithMetadata) int {
+	return detectSeverityFromMessage(serializeRegex.ReplaceAllString(log.Log.Message, "")).Rank()
+}
+
+func httpLogSeverity(log http_logs.DeploymentHttpLogWithMetadata) int {
+	switch {
+	case log.StatusCode >= 500:
+		return config.SeverityError.Rank()
+	case log.StatusCode >= 400:
+		return config.SeverityWarn.Rank()
+	default:
+		return config.SeverityInfo.Rank()
+	}
+}
+
+// bufferLogsAsync moves logs from the subscription onto the buffer, so the subscription is only held up by delivery once the buffer is full and the policy is to block
+func bufferLogsAsync[T any](ctx context.Context, kind string, track chan []T, queue *buffer.Queue[T]) {
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case logs := <-track:
+				// logs still waiting for room when shutting down are counted as dropped
+				dropped, err := queue.Push(ctx, logs)
+
+				if dropped > 0 {
+					logger.Stderr.Warn("log buffer is full, dropping "+kind,
+						slog.Int("log_count", dropped),
+						slog.String("policy", string(config.Global.BufferPolicy)),
+					)
+				}
+
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+}
+
+func logDestinationBufferFull(pipeline *destinationPipeline, kind string, dropped int) {
+	logger.Stderr.Warn("destination buffer is full, dropping "+kind,
+		slog.String("destination", pipeline.destination.Name),
+		slog.Int("log_count", dropped),
+		slog.String("policy", string(config.Global.BufferPolicy)),
+	)
+}
+
+func bufferStatusAttr[T any](name string, queue *buffer.Queue[T]) slog.Attr {
+	events, bytes := queue.Len()
+
+	return slog.Group(name,
+		slog.Int("events", events),
+		slog.String("bytes", util.ByteCountIEC(uint64(bytes))),
+		slog.Int64("dropped_oldest", queue.Stats.DroppedOldest.Load()),
+		slog.Int64("dropped_newest", queue.Stats.DroppedNewest.Load()),
+		slog.Int64("dropped_below_severity", queue.Stats.DroppedBelowSeverity.Load()),
+	)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/brody192/locomotive/internal/buffer"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/webhook"
+)
+
+// the number of incoming log batches a delivery lane can fall behind by before pushing to it waits,
+// which only holds up the destination's own buffer
+const destinationQueueSize = 64
+
+// destinationPipeline buffers, batches and delivers logs for a single destination on its own goroutines,
+// so a slow destination can not hold up delivery to the others
+type destinationPipeline struct {
+	destination *webhook.Destination
+	filter      FilterSettings
+
+	// hold the logs handed to the destination until its lanes take them, once full the buffer policy applies,
+	// under block and drop-below-severity handing logs to a full buffer waits and so holds up the other destinations
+	deployLogBuffer *buffer.Queue[environment_logs.EnvironmentLogWithMetadata]
+	httpLogBuffer   *buffer.Queue[http_logs.DeploymentHttpLogWithMetadata]
+
+	deployLogs *lanes[environment_logs.EnvironmentLogWithMetadata]
+	httpLogs   *lanes[http_logs.DeploymentHttpLogWithMetadata]
+}
+
+func newDestinationPipelines(destinations []*webhook.Destination) ([]*destinationPipeline, error) {
+	pipelines := make([]*destinationPipeline, 0, len(destinations))
+
+	for _, destination := range destinations {
+		filter, err := NewFilterSettings(
+			destination.Config.MinSeverity,
+			destination.Config.Whitelist,
+			destination.Config.Blacklist,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("invalid filter for destination %s: %w", destination.Name, err)
+		}
+
+		pipelines = append(pipelines, &destinationPipeline{
+			destination:     destination,
+			filter:          filter,
+			deployLogBuffer: buffer.New(bufferOptions(), estimateDeployLogSize, deployLogSeverity),
+			httpLogBuffer:   buffer.New(bufferOptions(), estimateHttpLogSize, httpLogSeverity),
+			deployLogs:      newLanes(config.Global.DeliveryConcurrency, destinationQueueSize, deployLogOrderingKey),
+			httpLogs:        newLanes(config.Global.DeliveryConcurrency, destinationQueueSize, httpLogOrderingKey),
+		})
+	}
+
+	return pipelines, nil
+}
+
+// deploy logs are kept in order per deployment instance
+func deployLogOrderingKey(log environment_logs.EnvironmentLogWithMetadata) string {
+	return log.Log.Tags.DeploymentInstanceID.String()
+}
+
+// http logs carry no instance id, they are kept in order per deployment
+func httpLogOrderingKey(log http_logs.DeploymentHttpLogWithMetadata) string {
+	return log.Metadata["deployment_id"]
+}
+
+// start runs the pipeline until its buffers are closed and drained, batches are sent with ctx,
+// which should only be cancelled once the shutdown timeout is up
+func (p *destinationPipeline) start(ctx context.Context) {
+	p.deployLogs.start(ctx, batchOptions(p.destination.Config), estimateDeployLogSize, p.sendDeployLogs)
+	p.httpLogs.start(ctx, batchOptions(p.destination.Config), estimateHttpLogSize, p.sendHttpLogs)
+
+	batchMaxCount := p.destination.Config.BatchMaxCount()
+
+	consumeBufferAsync(p.deployLogBuffer, p.deployLogs, batchMaxCount)
+	consumeBufferAsync(p.httpLogBuffer, p.httpLogs, batchMaxCount)
+}
+
+// consumeBufferAsync moves logs from a destination's buffer onto its lanes, a batch worth at a time
+// so the rest stays in the buffer where the buffer policy applies while the destination pushes back.
+//
+// Once the buffer is closed and drained the lanes are closed, so they send what they are holding.
+func consumeBufferAsync[T any](queue *buffer.Queue[T], lanes *lanes[T], batchMaxCount int) {
+	go func() {
+		defer lanes.close()
+
+		for {
+			logs, err := queue.Pop(context.Background(), batchMaxCount)
+			if err != nil {
+				return
+			}
+
+			lanes.push(logs)
+		}
+	}()
+}
+
+// stop waits for the lanes to send what they were holding once the pipeline's buffers are closed, then closes the destination
+func (p *destinationPipeline) stop() {
+	p.deployLogs.wait()
+	p.httpLogs.wait()
+
+	if err := p.destination.Close(); err != nil {
+		logger.Stderr.Error("error closing destination", slog.String("destination", p.destination.Name), logger.ErrAttr(err))
+	}
+}
+
+// pushDeployLogs hands logs to the destination's buffer, waiting for room until ctx is done where the buffer policy says to,
+// it returns the number of logs the buffer dropped
+func (p *destinationPipeline) pushDeployLogs(ctx context.Context, logs []environment_logs.EnvironmentLogWithMetadata) int {
+	dropped, _ := p.deployLogBuffer.Push(ctx, logs)
+
+	return dropped
+}
+
+func (p *destinationPipeline) pushHttpLogs(ctx context.Context, logs []http_logs.DeploymentHttpLogWithMetadata) int {
+	dropped, _ := p.httpLogBuffer.Push(ctx, logs)
+
+	return dropped
+}
+
+func (p *destinationPipeline) sendDeployLogs(ctx context.Context, logs []environment_logs.EnvironmentLogWithMetadata) {
+	if serializedLogs, err := p.destination.SendDeployLogs(ctx, logs); err != nil {
+		attrs := []any{slog.String("destination", p.destination.Name), logger.ErrAttr(err)}
+
+		if serializedLogs != nil {
+			attrs = append(attrs, slog.String("serialized_logs", string(serializedLogs)))
+		}
+
+		logger.Stderr.Error("error sending deploy logs webhook", attrs...)
+	}
+}
+
+func (p *destinationPipeline) sendHttpLogs(ctx context.Context, logs []http_logs.DeploymentHttpLogWithMetadata) {
+	if serializedLogs, err := p.destination.SendHttpLogs(ctx, logs); err != nil {
+		attrs := []any{slog.String("destination", p.destination.Name), logger.ErrAttr(err)}
+
+		if serializedLogs != nil {
+			attrs = append(attrs, slog.String("serialized_logs", string(serializedLogs)))
+		}
+
+		logger.Stderr.Error("error sending http logs webhook(s)", attrs...)
+	}
+}
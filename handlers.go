@@ -3,16 +3,14 @@ package main
 import (
 	"context"
 	"strings"
-	"log/slog"
 	"sync/atomic"
 	"regexp"
 	"fmt"
 
-	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/buffer"
 	"github.com/brody192/locomotive/internal/config"
 	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
 	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
-	"github.com/brody192/locomotive/internal/webhook"
 )
 
 var (
@@ -88,108 +86,112 @@ func NewFilterSettings(
 }
 
 
+// Allows reports whether a deploy log with the given severity and ansi stripped message passes the filter
+func (f FilterSettings) Allows(severity config.SeverityLevel, logMsg string) bool {
+	if severity.Rank() < f.MinSeverity.Rank() {
+		return false
+	}
+
+	if len(f.Whitelist) > 0 {
+		matched := false
+		for _, re := range f.Whitelist {
+			if re.MatchString(logMsg) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+
+	if len(f.Blacklist) > 0 {
+		for _, re := range f.Blacklist {
+			if re.MatchString(logMsg) {
+				return false // blocked by blacklist
+			}
+		}
+	}
+
+	return true
+}
+
+// handleDeployLogsAsync hands the buffered deploy logs to every destination until the buffer is closed and drained,
+// then closes the destinations' deploy log buffers so they are drained in turn.
+//
+// Handing logs to a full destination buffer waits where the buffer policy says to, until ctx is done.
 func handleDeployLogsAsync(
 	ctx context.Context,
 	deployLogsProcessed *atomic.Int64,
-	serviceLogTrack chan []environment_logs.EnvironmentLogWithMetadata,
-	filter FilterSettings,
+	deployLogBuffer *buffer.Queue[environment_logs.EnvironmentLogWithMetadata],
+	pipelines []*destinationPipeline,
 ) {
 	go func() {
+		defer func() {
+			for _, pipeline := range pipelines {
+				pipeline.deployLogBuffer.Close()
+			}
+		}()
+
 		for {
-			select {
-			case <-ctx.Done():
+			// this only stops once the buffer is closed and drained
+			logs, err := deployLogBuffer.Pop(context.Background(), config.Global.BatchMaxCount)
+			if err != nil {
 				return
-			case logs := <-serviceLogTrack:
-				filteredLogs := make([]environment_logs.EnvironmentLogWithMetadata, 0, len(logs))
-
-				for _, logEntry := range logs {
-					var logMsg string = serializeRegex.ReplaceAllString(logEntry.Log.Message, "")
-					detectedSeverity := detectSeverityFromMessage(logMsg)
-
-					logEntry.Log.Severity = string(detectedSeverity)
+			}
 
-					if detectedSeverity.Rank() < filter.MinSeverity.Rank() {
-						continue
-					}
+			messages := make([]string, len(logs))
 
+			for i := range logs {
+				messages[i] = serializeRegex.ReplaceAllString(logs[i].Log.Message, "")
+				logs[i].Log.Severity = string(detectSeverityFromMessage(messages[i]))
+			}
 
-					if len(filter.Whitelist) > 0 {
-						matched := false
-						for _, re := range filter.Whitelist {
-							if re.MatchString(logMsg) {
-								matched = true
-								break
-							}
-						}
-						if !matched {
-							continue
-						}
-					}
+			for _, pipeline := range pipelines {
+				filteredLogs := make([]environment_logs.EnvironmentLogWithMetadata, 0, len(logs))
 
-					if len(filter.Blacklist) > 0 {
-						blocked := false
-						for _, re := range filter.Blacklist {
-							if re.MatchString(logMsg) {
-								blocked = true
-								break
-							}
-						}
-						if blocked {
-							continue // blocked by blacklist
-						}
+				for i := range logs {
+					if pipeline.filter.Allows(config.SeverityLevel(logs[i].Log.Severity), messages[i]) {
+						filteredLogs = append(filteredLogs, logs[i])
 					}
-
-					filteredLogs = append(filteredLogs, logEntry)
 				}
 
 				if len(filteredLogs) == 0 {
 					continue
 				}
 
-				// fmt.Printfs("Payload: %s\n", filteredLogs)
-
-				for _, log := range filteredLogs {
-					// Send each log individually
-					if serializedLog, err := webhook.SendDeployLogsWebhook([]environment_logs.EnvironmentLogWithMetadata{log}); err != nil {
-						attrs := []any{logger.ErrAttr(err)}
-
-						if serializedLog != nil {
-							attrs = append(attrs, slog.String("serialized_log", string(serializedLog)))
-						}
-
-						logger.Stderr.Error("error sending deploy log webhook", attrs...)
-						continue
-					}
-
-					// Increment processed count by 1 for each log
-					deployLogsProcessed.Add(1)
+				if dropped := pipeline.pushDeployLogs(ctx, filteredLogs); dropped > 0 {
+					logDestinationBufferFull(pipeline, "deploy logs", dropped)
 				}
 			}
+
+			// counted once every destination has taken the logs
+			deployLogsProcessed.Add(int64(len(logs)))
 		}
 	}()
 }
 
-func handleHttpLogsAsync(ctx context.Context, httpLogsProcessed *atomic.Int64, httpLogTrack chan []http_logs.DeploymentHttpLogWithMetadata) {
+func handleHttpLogsAsync(ctx context.Context, httpLogsProcessed *atomic.Int64, httpLogBuffer *buffer.Queue[http_logs.DeploymentHttpLogWithMetadata], pipelines []*destinationPipeline) {
 	go func() {
+		defer func() {
+			for _, pipeline := range pipelines {
+				pipeline.httpLogBuffer.Close()
+			}
+		}()
+
 		for {
-			select {
-			case <-ctx.Done():
+			logs, err := httpLogBuffer.Pop(context.Background(), config.Global.BatchMaxCount)
+			if err != nil {
 				return
-			case logs := <-httpLogTrack:
-				if serializedLogs, err := webhook.SendHttpLogsWebhook(logs); err != nil {
-					attrs := []any{logger.ErrAttr(err)}
-
-					if serializedLogs != nil {
-						attrs = append(attrs, slog.String("serialized_logs", string(serializedLogs)))
-					}
-
-					logger.Stderr.Error("error sending http logs webhook(s)", attrs...)
+			}
 
-					continue
+			for _, pipeline := range pipelines {
+				if dropped := pipeline.pushHttpLogs(ctx, logs); dropped > 0 {
+					logDestinationBufferFull(pipeline, "http logs", dropped)
 				}
-
-				httpLogsProcessed.Add(int64(len(logs)))
 			}
+
+			httpLogsProcessed.Add(int64(len(logs)))
 		}
 	}()
 }
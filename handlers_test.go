@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/buffer"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/webhook"
+)
+
+func TestHandleDeployLogsDestinationBufferPolicy(t *testing.T) {
+	const logCount = 10
+
+	tests := []struct {
+		name   string
+		policy buffer.Policy
+
+		wantDelivered int
+		wantDropped   int64
+	}{
+		{
+			name:          "block waits for room",
+			policy:        buffer.PolicyBlock,
+			wantDelivered: logCount,
+		},
+		{
+			name:          "drop newest drops what does not fit",
+			policy:        buffer.PolicyDropNewest,
+			wantDelivered: 1,
+			wantDropped:   logCount - 1,
+		},
+	}
+
+	batchMaxCount := config.Global.BatchMaxCount
+	t.Cleanup(func() { config.Global.BatchMaxCount = batchMaxCount })
+
+	config.Global.BatchMaxCount = 1
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := buffer.Options{MaxItems: 1, Policy: tt.policy}
+
+			pipeline := &destinationPipeline{
+				destination:     &webhook.Destination{Name: "test"},
+				deployLogBuffer: buffer.New(opts, estimateDeployLogSize, deployLogSeverity),
+			}
+
+			logs := make([]environment_logs.EnvironmentLogWithMetadata, logCount)
+			want := make([]string, logCount)
+
+			for i := range logs {
+				want[i] = fmt.Sprintf("log %d", i)
+				logs[i] = environment_logs.EnvironmentLogWithMetadata{Log: subscriptions.EnvironmentLog{Message: want[i]}}
+			}
+
+			deployLogBuffer := buffer.New(buffer.Options{Policy: buffer.PolicyBlock}, estimateDeployLogSize, deployLogSeverity)
+
+			if _, err := deployLogBuffer.Push(context.Background(), logs); err != nil {
+				t.Fatalf("push: %v", err)
+			}
+
+			deployLogBuffer.Close()
+
+			processed := atomic.Int64{}
+
+			handleDeployLogsAsync(context.Background(), &processed, deployLogBuffer, []*destinationPipeline{pipeline})
+
+			// let the handler run into the full destination buffer before it is drained
+			time.Sleep(20 * time.Millisecond)
+
+			delivered := []string{}
+
+			for {
+				logs, err := pipeline.deployLogBuffer.Pop(context.Background(), 0)
+				if errors.Is(err, buffer.ErrClosed) {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("pop: %v", err)
+				}
+
+				for _, log := range logs {
+					delivered = append(delivered, log.Log.Message)
+				}
+			}
+
+			if len(delivered) != tt.wantDelivered || !slices.Equal(delivered, want[:len(delivered)]) {
+				t.Fatalf("got delivered %q, want the first %d of %q", delivered, tt.wantDelivered, want)
+			}
+
+			if got := pipeline.deployLogBuffer.Stats.DroppedNewest.Load(); got != tt.wantDropped {
+				t.Fatalf("got %d dropped, want %d", got, tt.wantDropped)
+			}
+
+			if got := processed.Load(); got != logCount {
+				t.Fatalf("got %d processed, want %d", got, logCount)
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package batch
+
+import "time"
+
+type Options struct {
+	// flush once a batch holds this many items, zero means no limit
+	MaxCount int
+	// flush before a batch would grow past this many bytes, zero means no limit
+	MaxBytes int64
+	// flush a non empty batch once its oldest item has waited this long, zero flushes it as soon as the owner checks Linger
+	MaxLinger time.Duration
+}
+
+// Batcher groups items into batches bounded by count, size and linger time.
+//
+// It is not safe for concurrent use, it is meant to be owned by the single goroutine that reads the incoming items.
+type Batcher[T any] struct {
+	opts Options
+	size func(T) int64
+
+	items []T
+	bytes int64
+
+	timer *time.Timer
+}
+
+func New[T any](opts Options, size func(T) int64) *Batcher[T] {
+	return &Batcher[T]{
+		opts: opts,
+		size: size,
+	}
+}
+
+// Add appends an item and returns the batches that are ready to be sent as a result
+func (b *Batcher[T]) Add(item T) [][]T {
+	ready := [][]T{}
+
+	itemSize := b.size(item)
+
+	if len(b.items) > 0 && b.opts.MaxBytes > 0 && b.bytes+itemSize > b.opts.MaxBytes {
+		ready = append(ready, b.Flush())
+	}
+
+	if len(b.items) == 0 {
+		b.timer = time.NewTimer(max(b.opts.MaxLinger, 0))
+	}
+
+	b.items = append(b.items, item)
+	b.bytes += itemSize
+
+	if (b.opts.MaxCount > 0 && len(b.items) >= b.opts.MaxCount) || (b.opts.MaxBytes > 0 && b.bytes >= b.opts.MaxBytes) {
+		ready = append(ready, b.Flush())
+	}
+
+	return ready
+}
+
+// Flush returns the pending items and starts a new batch, it returns nil if there is nothing pending
+func (b *Batcher[T]) Flush() []T {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	if len(b.items) == 0 {
+		return nil
+	}
+
+	items := b.items
+
+	b.items = nil
+	b.bytes = 0
+
+	return items
+}
+
+// Linger fires once the pending batch has waited for MaxLinger, the returned channel is nil while there is nothing pending
+func (b *Batcher[T]) Linger() <-chan time.Time {
+	if b.timer == nil {
+		return nil
+	}
+
+	return b.timer.C
+}
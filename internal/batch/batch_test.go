@@ -0,0 +1,131 @@
+package batch
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+type item struct {
+	name string
+	size int64
+}
+
+func newTestBatcher(opts Options) *Batcher[item] {
+	return New(opts, func(i item) int64 { return i.size })
+}
+
+func names(batches [][]item) [][]string {
+	result := make([][]string, len(batches))
+
+	for i, batch := range batches {
+		for _, item := range batch {
+			result[i] = append(result[i], item.name)
+		}
+	}
+
+	return result
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		items []item
+
+		wantReady [][]string
+		// the batch left over once every item was added
+		wantPending []string
+	}{
+		{
+			name:        "under the limits",
+			opts:        Options{MaxCount: 3, MaxBytes: 100},
+			items:       []item{{"a", 1}, {"b", 1}},
+			wantReady:   [][]string{},
+			wantPending: []string{"a", "b"},
+		},
+		{
+			name:        "full by count",
+			opts:        Options{MaxCount: 2},
+			items:       []item{{"a", 1}, {"b", 1}, {"c", 1}, {"d", 1}, {"e", 1}},
+			wantReady:   [][]string{{"a", "b"}, {"c", "d"}},
+			wantPending: []string{"e"},
+		},
+		{
+			name:        "flushed before growing past the size",
+			opts:        Options{MaxBytes: 10},
+			items:       []item{{"a", 4}, {"b", 4}, {"c", 4}},
+			wantReady:   [][]string{{"a", "b"}},
+			wantPending: []string{"c"},
+		},
+		{
+			name:        "full by size exactly",
+			opts:        Options{MaxBytes: 8},
+			items:       []item{{"a", 4}, {"b", 4}, {"c", 4}},
+			wantReady:   [][]string{{"a", "b"}},
+			wantPending: []string{"c"},
+		},
+		{
+			name:        "an oversized item is sent on its own",
+			opts:        Options{MaxBytes: 10},
+			items:       []item{{"a", 4}, {"b", 20}, {"c", 4}},
+			wantReady:   [][]string{{"a"}, {"b"}},
+			wantPending: []string{"c"},
+		},
+		{
+			name:        "no limits",
+			opts:        Options{},
+			items:       []item{{"a", 100}, {"b", 100}},
+			wantReady:   [][]string{},
+			wantPending: []string{"a", "b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := newTestBatcher(tt.opts)
+
+			ready := [][]item{}
+
+			for _, item := range tt.items {
+				ready = append(ready, b.Add(item)...)
+			}
+
+			if got := names(ready); !slices.EqualFunc(got, tt.wantReady, slices.Equal) {
+				t.Fatalf("got ready batches %q, want %q", got, tt.wantReady)
+			}
+
+			if got := names([][]item{b.Flush()})[0]; !slices.Equal(got, tt.wantPending) {
+				t.Fatalf("got pending %q, want %q", got, tt.wantPending)
+			}
+		})
+	}
+}
+
+func TestLinger(t *testing.T) {
+	b := newTestBatcher(Options{MaxCount: 10, MaxLinger: 10 * time.Millisecond})
+
+	if b.Linger() != nil {
+		t.Fatal("got a linger channel with nothing pending, want nil")
+	}
+
+	b.Add(item{"a", 1})
+
+	select {
+	case <-b.Linger():
+	case <-time.After(time.Second):
+		t.Fatal("linger did not fire")
+	}
+
+	if got := b.Flush(); len(got) != 1 {
+		t.Fatalf("got %d pending items, want 1", len(got))
+	}
+
+	if b.Linger() != nil {
+		t.Fatal("got a linger channel after flushing, want nil")
+	}
+
+	if got := b.Flush(); got != nil {
+		t.Fatalf("got %v from flushing an empty batch, want nil", got)
+	}
+}
@@ -0,0 +1,313 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+)
+
+// ErrClosed is returned by Pop once the queue is closed and empty, and by Push once the queue is closed
+var ErrClosed = errors.New("queue is closed")
+
+// returned by waitForRoom when the caller does not wait for room
+var errNoWait = errors.New("queue is full")
+
+// Policy decides what happens to incoming items once the queue is full
+type Policy string
+
+const (
+	// wait for room, pushing back on the producer
+	PolicyBlock Policy = "block"
+	// evict the oldest queued items to make room
+	PolicyDropOldest Policy = "drop-oldest"
+	// drop the incoming items
+	PolicyDropNewest Policy = "drop-newest"
+	// drop incoming and queued items below the minimum severity, then wait for room
+	PolicyDropBelowSeverity Policy = "drop-below-severity"
+)
+
+func (p Policy) IsValid() bool {
+	return p == PolicyBlock || p == PolicyDropOldest || p == PolicyDropNewest || p == PolicyDropBelowSeverity
+}
+
+type Options struct {
+	// the queue is full once it holds this many items, zero means no limit
+	MaxItems int
+	// the queue is full once it would grow past this many bytes, zero means no limit
+	MaxBytes int64
+
+	Policy Policy
+	// items with a severity rank below this are dropped first under PolicyDropBelowSeverity
+	MinSeverity int
+}
+
+// Stats counts the items dropped by the queue, by the reason they were dropped
+type Stats struct {
+	DroppedOldest        atomic.Int64
+	DroppedNewest        atomic.Int64
+	DroppedBelowSeverity atomic.Int64
+}
+
+type entry[T any] struct {
+	item T
+	size int64
+}
+
+// Queue is a bounded in memory queue of items, bounded by both the number of items and their total size.
+//
+// It is safe for concurrent use by a single producer and a single consumer.
+type Queue[T any] struct {
+	opts     Options
+	size     func(T) int64
+	severity func(T) int
+
+	Stats Stats
+
+	mu      sync.Mutex
+	entries []entry[T]
+	bytes   int64
+
+	// signalled whenever items are added to or removed from the queue
+	added   chan struct{}
+	removed chan struct{}
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func New[T any](opts Options, size func(T) int64, severity func(T) int) *Queue[T] {
+	return &Queue[T]{
+		opts:     opts,
+		size:     size,
+		severity: severity,
+		added:    make(chan struct{}, 1),
+		removed:  make(chan struct{}, 1),
+		closed:   make(chan struct{}),
+	}
+}
+
+// Push adds the items to the queue, making room as the policy dictates.
+//
+// It returns the number of items dropped to do so, under PolicyBlock and PolicyDropBelowSeverity it may wait for room until the context is done
+// or the queue is closed, the items that were not queued by then are counted as dropped newest.
+func (q *Queue[T]) Push(ctx context.Context, items []T) (dropped int, err error) {
+	return q.push(ctx, items, true)
+}
+
+// Offer adds the items to the queue like Push, but never waits for room, where Push would wait the incoming item is dropped instead.
+//
+// It returns the number of items dropped.
+func (q *Queue[T]) Offer(items []T) (dropped int) {
+	dropped, _ = q.push(context.Background(), items, false)
+
+	return dropped
+}
+
+func (q *Queue[T]) push(ctx context.Context, items []T, wait bool) (dropped int, err error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	defer signal(q.added)
+
+	if q.isClosed() {
+		q.Stats.DroppedNewest.Add(int64(len(items)))
+
+		return len(items), ErrClosed
+	}
+
+	for i, item := range items {
+		e := entry[T]{item: item, size: q.size(item)}
+
+		admitted := true
+
+		for !q.fits(e.size) {
+			switch q.opts.Policy {
+			case PolicyDropOldest:
+				q.removeAt(0)
+				q.Stats.DroppedOldest.Add(1)
+				dropped++
+				continue
+			case PolicyDropNewest:
+				q.Stats.DroppedNewest.Add(1)
+				dropped++
+				admitted = false
+			case PolicyDropBelowSeverity:
+				if q.severity(e.item) < q.opts.MinSeverity {
+					q.Stats.DroppedBelowSeverity.Add(1)
+					dropped++
+					admitted = false
+					break
+				}
+
+				if i := q.indexBelowSeverity(); i >= 0 {
+					q.removeAt(i)
+					q.Stats.DroppedBelowSeverity.Add(1)
+					dropped++
+					continue
+				}
+
+				err = q.waitForRoom(ctx, wait)
+			default:
+				err = q.waitForRoom(ctx, wait)
+			}
+
+			if errors.Is(err, errNoWait) {
+				q.Stats.DroppedNewest.Add(1)
+				dropped++
+				admitted = false
+				err = nil
+			}
+
+			if err != nil {
+				q.Stats.DroppedNewest.Add(int64(len(items) - i))
+
+				return dropped + len(items) - i, err
+			}
+
+			if !admitted {
+				break
+			}
+		}
+
+		if admitted {
+			q.entries = append(q.entries, e)
+			q.bytes += e.size
+		}
+	}
+
+	return dropped, nil
+}
+
+// Pop waits until the queue holds items, then removes and returns up to maxItems of the oldest ones in the order they were pushed,
+// zero means all of them. Once the queue is closed it returns the remaining items without waiting, then ErrClosed.
+//
+// Items stay subject to the policy until they are popped, so a consumer that is held up should pop no more than it can hand on.
+func (q *Queue[T]) Pop(ctx context.Context, maxItems int) ([]T, error) {
+	for {
+		q.mu.Lock()
+
+		// checked with the lock held, items pushed before closing are queued by the time it is released
+		closed := q.isClosed()
+
+		if len(q.entries) > 0 {
+			n := len(q.entries)
+
+			if maxItems > 0 {
+				n = min(n, maxItems)
+			}
+
+			items := make([]T, n)
+
+			for i := range n {
+				items[i] = q.entries[i].item
+				q.bytes -= q.entries[i].size
+			}
+
+			q.entries = slices.Delete(q.entries, 0, n)
+
+			q.mu.Unlock()
+
+			signal(q.removed)
+
+			return items, nil
+		}
+
+		q.mu.Unlock()
+
+		if closed {
+			return nil, ErrClosed
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-q.closed:
+		case <-q.added:
+		}
+	}
+}
+
+// Close stops the queue from taking new items and wakes up any waiting Push or Pop, the queued items can still be popped
+func (q *Queue[T]) Close() {
+	q.closeOnce.Do(func() {
+		close(q.closed)
+	})
+}
+
+func (q *Queue[T]) isClosed() bool {
+	select {
+	case <-q.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Len returns the number of queued items and their total size
+func (q *Queue[T]) Len() (items int, bytes int64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.entries), q.bytes
+}
+
+// fits reports whether an item of the given size can be queued, an empty queue accepts any single item so oversized items can not get stuck
+func (q *Queue[T]) fits(size int64) bool {
+	if len(q.entries) == 0 {
+		return true
+	}
+
+	if q.opts.MaxItems > 0 && len(q.entries) >= q.opts.MaxItems {
+		return false
+	}
+
+	return q.opts.MaxBytes <= 0 || q.bytes+size <= q.opts.MaxBytes
+}
+
+func (q *Queue[T]) indexBelowSeverity() int {
+	return slices.IndexFunc(q.entries, func(e entry[T]) bool {
+		return q.severity(e.item) < q.opts.MinSeverity
+	})
+}
+
+func (q *Queue[T]) removeAt(i int) {
+	q.bytes -= q.entries[i].size
+	q.entries = slices.Delete(q.entries, i, i+1)
+}
+
+// waitForRoom releases the lock until items are removed from the queue, the queue is closed or the context is done, it must be called with the lock held.
+//
+// If wait is false it returns errNoWait right away.
+func (q *Queue[T]) waitForRoom(ctx context.Context, wait bool) error {
+	if !wait {
+		return errNoWait
+	}
+
+	q.mu.Unlock()
+	defer q.mu.Lock()
+
+	// let the consumer drain what was queued so far
+	signal(q.added)
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-q.closed:
+		return ErrClosed
+	case <-q.removed:
+		if q.isClosed() {
+			return ErrClosed
+		}
+
+		return nil
+	}
+}
+
+func signal(c chan struct{}) {
+	select {
+	case c <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,279 @@
+package buffer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type item struct {
+	name     string
+	size     int64
+	severity int
+}
+
+func newTestQueue(opts Options) *Queue[item] {
+	return New(opts, func(i item) int64 { return i.size }, func(i item) int { return i.severity })
+}
+
+func names(items []item) []string {
+	names := make([]string, len(items))
+
+	for i, item := range items {
+		names[i] = item.name
+	}
+
+	return names
+}
+
+func popAll(t *testing.T, q *Queue[item]) []item {
+	t.Helper()
+
+	if n, _ := q.Len(); n == 0 {
+		return nil
+	}
+
+	items, err := q.Pop(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+
+	return items
+}
+
+func TestPolicies(t *testing.T) {
+	tests := []struct {
+		name  string
+		opts  Options
+		items []item
+
+		wantQueued               []string
+		wantDropped              int
+		wantDroppedOldest        int64
+		wantDroppedNewest        int64
+		wantDroppedBelowSeverity int64
+	}{
+		{
+			name:       "under the limits",
+			opts:       Options{MaxItems: 3, Policy: PolicyDropNewest},
+			items:      []item{{name: "a"}, {name: "b"}, {name: "c"}},
+			wantQueued: []string{"a", "b", "c"},
+		},
+		{
+			name:              "drop oldest by count",
+			opts:              Options{MaxItems: 2, Policy: PolicyDropOldest},
+			items:             []item{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}},
+			wantQueued:        []string{"c", "d"},
+			wantDropped:       2,
+			wantDroppedOldest: 2,
+		},
+		{
+			name:              "drop oldest by size",
+			opts:              Options{MaxBytes: 10, Policy: PolicyDropOldest},
+			items:             []item{{name: "a", size: 4}, {name: "b", size: 4}, {name: "c", size: 6}},
+			wantQueued:        []string{"b", "c"},
+			wantDropped:       1,
+			wantDroppedOldest: 1,
+		},
+		{
+			name:              "drop newest by count",
+			opts:              Options{MaxItems: 2, Policy: PolicyDropNewest},
+			items:             []item{{name: "a"}, {name: "b"}, {name: "c"}, {name: "d"}},
+			wantQueued:        []string{"a", "b"},
+			wantDropped:       2,
+			wantDroppedNewest: 2,
+		},
+		{
+			name:              "drop newest by size keeps smaller items that still fit",
+			opts:              Options{MaxBytes: 10, Policy: PolicyDropNewest},
+			items:             []item{{name: "a", size: 6}, {name: "b", size: 6}, {name: "c", size: 4}},
+			wantQueued:        []string{"a", "c"},
+			wantDropped:       1,
+			wantDroppedNewest: 1,
+		},
+		{
+			name:       "an oversized item is taken by an empty queue",
+			opts:       Options{MaxBytes: 10, Policy: PolicyDropNewest},
+			items:      []item{{name: "a", size: 100}},
+			wantQueued: []string{"a"},
+		},
+		{
+			name:                     "drop incoming below severity",
+			opts:                     Options{MaxItems: 2, Policy: PolicyDropBelowSeverity, MinSeverity: 2},
+			items:                    []item{{name: "a", severity: 3}, {name: "b", severity: 3}, {name: "c", severity: 1}},
+			wantQueued:               []string{"a", "b"},
+			wantDropped:              1,
+			wantDroppedBelowSeverity: 1,
+		},
+		{
+			name:                     "drop queued below severity",
+			opts:                     Options{MaxItems: 2, Policy: PolicyDropBelowSeverity, MinSeverity: 2},
+			items:                    []item{{name: "a", severity: 3}, {name: "b", severity: 1}, {name: "c", severity: 3}},
+			wantQueued:               []string{"a", "c"},
+			wantDropped:              1,
+			wantDroppedBelowSeverity: 1,
+		},
+		{
+			name:              "drop below severity without anything to drop",
+			opts:              Options{MaxItems: 2, Policy: PolicyDropBelowSeverity, MinSeverity: 2},
+			items:             []item{{name: "a", severity: 3}, {name: "b", severity: 3}, {name: "c", severity: 3}},
+			wantQueued:        []string{"a", "b"},
+			wantDropped:       1,
+			wantDroppedNewest: 1,
+		},
+		{
+			name:              "block",
+			opts:              Options{MaxItems: 2, Policy: PolicyBlock},
+			items:             []item{{name: "a"}, {name: "b"}, {name: "c"}},
+			wantQueued:        []string{"a", "b"},
+			wantDropped:       1,
+			wantDroppedNewest: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := newTestQueue(tt.opts)
+
+			// Offer behaves like Push, except that it drops the incoming item where Push would wait
+			dropped := q.Offer(tt.items)
+
+			if dropped != tt.wantDropped {
+				t.Errorf("got %d dropped, want %d", dropped, tt.wantDropped)
+			}
+
+			if got := names(popAll(t, q)); !slices.Equal(got, tt.wantQueued) {
+				t.Errorf("got queued %q, want %q", got, tt.wantQueued)
+			}
+
+			if got := q.Stats.DroppedOldest.Load(); got != tt.wantDroppedOldest {
+				t.Errorf("got %d dropped oldest, want %d", got, tt.wantDroppedOldest)
+			}
+
+			if got := q.Stats.DroppedNewest.Load(); got != tt.wantDroppedNewest {
+				t.Errorf("got %d dropped newest, want %d", got, tt.wantDroppedNewest)
+			}
+
+			if got := q.Stats.DroppedBelowSeverity.Load(); got != tt.wantDroppedBelowSeverity {
+				t.Errorf("got %d dropped below severity, want %d", got, tt.wantDroppedBelowSeverity)
+			}
+		})
+	}
+}
+
+func TestPushWaitsForRoom(t *testing.T) {
+	q := newTestQueue(Options{MaxItems: 1, Policy: PolicyBlock})
+
+	if _, err := q.Push(context.Background(), []item{{name: "a"}}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	pushed := make(chan error, 1)
+
+	go func() {
+		_, err := q.Push(context.Background(), []item{{name: "b"}})
+		pushed <- err
+	}()
+
+	select {
+	case err := <-pushed:
+		t.Fatalf("push returned before there was room: %v", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	if got := names(popAll(t, q)); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("got %q, want %q", got, []string{"a"})
+	}
+
+	if err := <-pushed; err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	if got := names(popAll(t, q)); !slices.Equal(got, []string{"b"}) {
+		t.Fatalf("got %q, want %q", got, []string{"b"})
+	}
+}
+
+func TestPushCancelled(t *testing.T) {
+	q := newTestQueue(Options{MaxItems: 1, Policy: PolicyBlock})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	dropped, err := q.Push(ctx, []item{{name: "a"}, {name: "b"}, {name: "c"}})
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got error %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if dropped != 2 || q.Stats.DroppedNewest.Load() != 2 {
+		t.Fatalf("got %d dropped and %d dropped newest, want 2", dropped, q.Stats.DroppedNewest.Load())
+	}
+}
+
+func TestClose(t *testing.T) {
+	q := newTestQueue(Options{MaxItems: 1, Policy: PolicyBlock})
+
+	if _, err := q.Push(context.Background(), []item{{name: "a"}}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	pushed := make(chan error, 1)
+
+	go func() {
+		_, err := q.Push(context.Background(), []item{{name: "b"}})
+		pushed <- err
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	q.Close()
+
+	if err := <-pushed; !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v from a waiting push, want %v", err, ErrClosed)
+	}
+
+	if dropped, err := q.Push(context.Background(), []item{{name: "c"}}); !errors.Is(err, ErrClosed) || dropped != 1 {
+		t.Fatalf("got %d dropped and error %v from a push after closing, want 1 and %v", dropped, err, ErrClosed)
+	}
+
+	items, err := q.Pop(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+
+	if got := names(items); !slices.Equal(got, []string{"a"}) {
+		t.Fatalf("got %q, want %q", got, []string{"a"})
+	}
+
+	if _, err := q.Pop(context.Background(), 0); !errors.Is(err, ErrClosed) {
+		t.Fatalf("got error %v from popping a closed and empty queue, want %v", err, ErrClosed)
+	}
+
+	if got := q.Stats.DroppedNewest.Load(); got != 2 {
+		t.Fatalf("got %d dropped newest, want 2", got)
+	}
+}
+
+func TestPopMaxItems(t *testing.T) {
+	q := newTestQueue(Options{Policy: PolicyBlock})
+
+	if _, err := q.Push(context.Background(), []item{{name: "a", size: 1}, {name: "b", size: 2}, {name: "c", size: 3}}); err != nil {
+		t.Fatalf("push: %v", err)
+	}
+
+	items, err := q.Pop(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("pop: %v", err)
+	}
+
+	if got := names(items); !slices.Equal(got, []string{"a", "b"}) {
+		t.Fatalf("got %q, want %q", got, []string{"a", "b"})
+	}
+
+	if n, bytes := q.Len(); n != 1 || bytes != 3 {
+		t.Fatalf("got %d items of %d bytes left, want 1 of 3", n, bytes)
+	}
+}
@@ -0,0 +1,201 @@
+package circuit
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+type Options struct {
+	// open the circuit after this many consecutive failures, zero disables the breaker
+	FailureThreshold int
+	// close the circuit again after this many consecutive successful probes
+	SuccessThreshold int
+	// how long the circuit stays open before a probe request is let through
+	ProbeInterval time.Duration
+
+	// called every time the state changes, without the breaker lock held
+	OnStateChange func(from, to State)
+}
+
+// Breaker fails requests fast while the endpoint behind it is considered down.
+//
+// Closed lets every request through, open rejects every request until the probe interval has passed,
+// half-open lets a single probe request through at a time to decide whether to close or open again.
+type Breaker struct {
+	opts Options
+
+	mu        sync.Mutex
+	state     State
+	failures  int
+	successes int
+	openedAt  time.Time
+	probing   bool
+}
+
+func New(opts Options) *Breaker {
+	if opts.SuccessThreshold < 1 {
+		opts.SuccessThreshold = 1
+	}
+
+	return &Breaker{opts: opts}
+}
+
+// Allow reports whether a request may be sent, every allowed request must be followed by a call to Done
+func (b *Breaker) Allow() error {
+	if b.opts.FailureThreshold <= 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+
+	switch b.state {
+	case StateOpen:
+		if time.Since(b.openedAt) < b.opts.ProbeInterval {
+			b.mu.Unlock()
+			return ErrOpen
+		}
+
+		from := b.setStateLocked(StateHalfOpen)
+		b.probing = true
+		b.mu.Unlock()
+
+		b.notify(from, StateHalfOpen)
+
+		return nil
+	case StateHalfOpen:
+		defer b.mu.Unlock()
+
+		if b.probing {
+			return ErrOpen
+		}
+
+		b.probing = true
+
+		return nil
+	default:
+		b.mu.Unlock()
+		return nil
+	}
+}
+
+// Done records the outcome of an allowed request, failed should be true if the endpoint looked unavailable.
+//
+// A request that was neither a success nor a failure, such as one that was cancelled, should call Abort instead.
+func (b *Breaker) Done(failed bool) {
+	if b.opts.FailureThreshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+
+	from, to := b.state, b.state
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+			break
+		}
+
+		b.failures++
+
+		if b.failures >= b.opts.FailureThreshold {
+			to = StateOpen
+		}
+	case StateHalfOpen:
+		b.probing = false
+
+		if failed {
+			to = StateOpen
+			break
+		}
+
+		b.successes++
+
+		if b.successes >= b.opts.SuccessThreshold {
+			to = StateClosed
+		}
+	}
+
+	if to != from {
+		b.setStateLocked(to)
+	}
+
+	b.mu.Unlock()
+
+	if to != from {
+		b.notify(from, to)
+	}
+}
+
+// Abort gives up an allowed request without recording an outcome, letting another probe through when half-open
+func (b *Breaker) Abort() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateHalfOpen {
+		b.probing = false
+	}
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+// ProbeIn returns how long until the next probe request is let through, zero if requests are allowed right away
+func (b *Breaker) ProbeIn() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state != StateOpen {
+		return 0
+	}
+
+	return max(b.opts.ProbeInterval-time.Since(b.openedAt), 0)
+}
+
+func (b *Breaker) setStateLocked(state State) State {
+	from := b.state
+
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+
+	if state == StateOpen {
+		b.openedAt = time.Now()
+	}
+
+	return from
+}
+
+func (b *Breaker) notify(from, to State) {
+	if b.opts.OnStateChange != nil {
+		b.opts.OnStateChange(from, to)
+	}
+}
@@ -0,0 +1,188 @@
+package circuit
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+type action int
+
+const (
+	allow action = iota
+	succeed
+	fail
+	abort
+	// sleep past the probe interval
+	wait
+)
+
+type step struct {
+	action action
+	// only checked for allow
+	wantErr   error
+	wantState State
+}
+
+func TestTransitions(t *testing.T) {
+	const probeInterval = 10 * time.Millisecond
+
+	tests := []struct {
+		name  string
+		opts  Options
+		steps []step
+
+		wantTransitions []string
+	}{
+		{
+			name: "failures below the threshold stay closed",
+			opts: Options{FailureThreshold: 3},
+			steps: []step{
+				{action: allow, wantState: StateClosed},
+				{action: fail, wantState: StateClosed},
+				{action: allow, wantState: StateClosed},
+				{action: fail, wantState: StateClosed},
+			},
+		},
+		{
+			name: "a success resets the failure count",
+			opts: Options{FailureThreshold: 2},
+			steps: []step{
+				{action: fail, wantState: StateClosed},
+				{action: succeed, wantState: StateClosed},
+				{action: fail, wantState: StateClosed},
+			},
+		},
+		{
+			name: "consecutive failures open and reject until the probe interval passed",
+			opts: Options{FailureThreshold: 2, ProbeInterval: time.Hour},
+			steps: []step{
+				{action: fail, wantState: StateClosed},
+				{action: fail, wantState: StateOpen},
+				{action: allow, wantErr: ErrOpen, wantState: StateOpen},
+			},
+			wantTransitions: []string{"closed>open"},
+		},
+		{
+			name: "a single probe is let through when half-open",
+			opts: Options{FailureThreshold: 1, ProbeInterval: probeInterval},
+			steps: []step{
+				{action: fail, wantState: StateOpen},
+				{action: wait, wantState: StateOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: allow, wantErr: ErrOpen, wantState: StateHalfOpen},
+			},
+			wantTransitions: []string{"closed>open", "open>half-open"},
+		},
+		{
+			name: "a successful probe closes",
+			opts: Options{FailureThreshold: 1, ProbeInterval: probeInterval},
+			steps: []step{
+				{action: fail, wantState: StateOpen},
+				{action: wait, wantState: StateOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: succeed, wantState: StateClosed},
+				{action: allow, wantState: StateClosed},
+			},
+			wantTransitions: []string{"closed>open", "open>half-open", "half-open>closed"},
+		},
+		{
+			name: "closing takes the success threshold of probes",
+			opts: Options{FailureThreshold: 1, SuccessThreshold: 2, ProbeInterval: probeInterval},
+			steps: []step{
+				{action: fail, wantState: StateOpen},
+				{action: wait, wantState: StateOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: succeed, wantState: StateHalfOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: succeed, wantState: StateClosed},
+			},
+			wantTransitions: []string{"closed>open", "open>half-open", "half-open>closed"},
+		},
+		{
+			name: "a failed probe reopens",
+			opts: Options{FailureThreshold: 1, ProbeInterval: probeInterval},
+			steps: []step{
+				{action: fail, wantState: StateOpen},
+				{action: wait, wantState: StateOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: fail, wantState: StateOpen},
+				{action: allow, wantErr: ErrOpen, wantState: StateOpen},
+			},
+			wantTransitions: []string{"closed>open", "open>half-open", "half-open>open"},
+		},
+		{
+			name: "an aborted probe lets another probe through",
+			opts: Options{FailureThreshold: 1, ProbeInterval: probeInterval},
+			steps: []step{
+				{action: fail, wantState: StateOpen},
+				{action: wait, wantState: StateOpen},
+				{action: allow, wantState: StateHalfOpen},
+				{action: abort, wantState: StateHalfOpen},
+				{action: allow, wantState: StateHalfOpen},
+			},
+			wantTransitions: []string{"closed>open", "open>half-open"},
+		},
+		{
+			name: "a zero failure threshold disables the breaker",
+			opts: Options{},
+			steps: []step{
+				{action: fail, wantState: StateClosed},
+				{action: fail, wantState: StateClosed},
+				{action: allow, wantState: StateClosed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transitions := []string{}
+
+			tt.opts.OnStateChange = func(from, to State) {
+				transitions = append(transitions, from.String()+">"+to.String())
+			}
+
+			b := New(tt.opts)
+
+			for i, s := range tt.steps {
+				switch s.action {
+				case allow:
+					if err := b.Allow(); !errors.Is(err, s.wantErr) {
+						t.Fatalf("step %d: got error %v, want %v", i, err, s.wantErr)
+					}
+				case succeed:
+					b.Done(false)
+				case fail:
+					b.Done(true)
+				case abort:
+					b.Abort()
+				case wait:
+					time.Sleep(2 * probeInterval)
+				}
+
+				if got := b.State(); got != s.wantState {
+					t.Fatalf("step %d: got state %s, want %s", i, got, s.wantState)
+				}
+			}
+
+			if !slices.Equal(transitions, tt.wantTransitions) {
+				t.Fatalf("got transitions %q, want %q", transitions, tt.wantTransitions)
+			}
+		})
+	}
+}
+
+func TestProbeIn(t *testing.T) {
+	b := New(Options{FailureThreshold: 1, ProbeInterval: time.Hour})
+
+	if got := b.ProbeIn(); got != 0 {
+		t.Fatalf("got %s while closed, want 0", got)
+	}
+
+	b.Done(true)
+
+	if got := b.ProbeIn(); got <= 59*time.Minute || got > time.Hour {
+		t.Fatalf("got %s while open, want close to an hour", got)
+	}
+}
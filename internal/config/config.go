@@ -1,23 +1,33 @@
 package config
 
 import (
+	"cmp"
 	"fmt"
 	"log/slog"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
+	"github.com/brody192/locomotive/internal/buffer"
 	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_splunk"
+	"github.com/brody192/locomotive/internal/spool"
 	"github.com/caarlos0/env/v11"
 	"github.com/flexstack/uuid"
 	"github.com/joho/godotenv"
 )
 
+// Global holds the configuration once Load has been called
 var Global = config{}
 
-func init() {
+// Load parses the configuration from the environment into Global, exiting with every error found if it is invalid
+func Load() {
 	if _, err := os.Stat(".env"); err == nil {
 		if err := godotenv.Load(); err != nil {
 			logger.Stderr.Error("error loading .env file", logger.ErrAttr(err))
@@ -110,30 +120,290 @@ func init() {
 		errors = append(errors, fmt.Errorf("at least one of ENABLE_DEPLOY_LOGS or ENABLE_HTTP_LOGS must be true"))
 	}
 
+	if Global.RetryMaxAttempts == 0 {
+		errors = append(errors, fmt.Errorf("RETRY_MAX_ATTEMPTS must be at least 1"))
+	}
+
+	for name, proxyUrl := range map[string]url.URL{"WEBHOOK_PROXY_URL": Global.WebhookProxyUrl, "RAILWAY_PROXY_URL": Global.RailwayProxyUrl} {
+		if proxyUrl.Host != "" && !slices.Contains(proxySchemes, proxyUrl.Scheme) {
+			errors = append(errors, fmt.Errorf("%s must use one of the http, https, socks5 or socks5h schemes", name))
+		}
+	}
+
+	if Global.DeliveryConcurrency < 1 {
+		errors = append(errors, fmt.Errorf("DELIVERY_CONCURRENCY must be at least 1"))
+	}
+
+	if Global.CircuitBreakerFailureThreshold < 0 {
+		errors = append(errors, fmt.Errorf("CIRCUIT_BREAKER_FAILURE_THRESHOLD must not be negative"))
+	}
+
+	if Global.DeadLetterMaxFiles < 1 {
+		errors = append(errors, fmt.Errorf("DEAD_LETTER_MAX_FILES must be at least 1"))
+	}
+
+	if Global.BatchMaxLinger < 0 {
+		errors = append(errors, fmt.Errorf("BATCH_MAX_LINGER must not be negative"))
+	}
+
+	if Global.ShutdownTimeout < 0 {
+		errors = append(errors, fmt.Errorf("SHUTDOWN_TIMEOUT must not be negative"))
+	}
+
+	if Global.BufferMaxEvents < 1 {
+		errors = append(errors, fmt.Errorf("BUFFER_MAX_EVENTS must be at least 1"))
+	}
+
+	if Global.BufferMaxBytes < 1 {
+		errors = append(errors, fmt.Errorf("BUFFER_MAX_BYTES must be at least 1"))
+	}
+
+	Global.BufferPolicy = buffer.Policy(strings.ToLower(strings.TrimSpace(string(Global.BufferPolicy))))
+
+	if !Global.BufferPolicy.IsValid() {
+		errors = append(errors, fmt.Errorf("BUFFER_POLICY must be one of block, drop-oldest, drop-newest or drop-below-severity"))
+	}
+
+	if !Global.BufferDropSeverity.IsValid() {
+		errors = append(errors, fmt.Errorf("BUFFER_DROP_SEVERITY has an invalid value: %s", Global.BufferDropSeverity))
+	}
+
+	Global.SpoolFsync = spool.FsyncPolicy(strings.ToLower(strings.TrimSpace(string(Global.SpoolFsync))))
+
+	if Global.SpoolDir != "" && !Global.SpoolFsync.IsValid() {
+		errors = append(errors, fmt.Errorf("SPOOL_FSYNC must be one of always, interval or never"))
+	}
+
+	errors = append(errors, resolveDestinations()...)
+
 	if len(errors) > 0 {
 		logger.Stderr.Error("error parsing environment variables", logger.ErrorsAttr(errors...))
 		os.Exit(1)
 	}
 
-	Global.WebhookMode = WebhookMode(strings.ToLower(strings.TrimSpace(string(Global.WebhookMode))))
+	for i := range Global.Destinations {
+		warnOnDestinationMisconfiguration(Global.Destinations[i])
+	}
+}
+
+// resolveDestinations merges the un-indexed destination into the list of destinations, then normalizes and validates every destination
+func resolveDestinations() []error {
+	errors := []error{}
+
+	// unnamed indexed destinations are named after their index before the un-indexed destination is prepended
+	for i := range Global.Destinations {
+		Global.Destinations[i].Name = strings.TrimSpace(Global.Destinations[i].Name)
+
+		if Global.Destinations[i].Name == "" {
+			Global.Destinations[i].Name = fmt.Sprintf("destination-%d", i)
+		}
+	}
+
+	if Global.WebhookUrl.Host != "" {
+		Global.Destinations = append([]NamedDestination{{
+			Name:        "default",
+			Destination: Global.Destination,
+		}}, Global.Destinations...)
+	}
+
+	if len(Global.Destinations) == 0 {
+		return append(errors, fmt.Errorf("WEBHOOK_URL or at least one DESTINATION_<N>_WEBHOOK_URL must be set"))
+	}
+
+	names := map[string]bool{}
+
+	for i := range Global.Destinations {
+		d := &Global.Destinations[i]
+
+		if names[d.Name] {
+			errors = append(errors, fmt.Errorf("destination name %s is used more than once", d.Name))
+		}
+
+		names[d.Name] = true
+
+		if d.WebhookUrl.Host == "" {
+			errors = append(errors, fmt.Errorf("destination %s must have a webhook url", d.Name))
+		}
+
+		d.HttpMethod = strings.ToUpper(strings.TrimSpace(d.HttpMethod))
+
+		if !slices.Contains([]string{http.MethodPost, http.MethodPut, http.MethodPatch}, d.HttpMethod) {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid HTTP_METHOD value, must be one of POST, PUT or PATCH: %s", d.Name, d.HttpMethod))
+		}
+
+		if d.FailoverWebhookUrl.Host != "" && Global.CircuitBreakerFailureThreshold == 0 {
+			errors = append(errors, fmt.Errorf("destination %s has a FAILOVER_WEBHOOK_URL, which requires the circuit breaker to be enabled", d.Name))
+		}
+
+		if !d.MinSeverity.IsValid() {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid MIN_SEVERITY value: %s", d.Name, d.MinSeverity))
+		}
+
+		d.WebhookMode = WebhookMode(strings.ToLower(strings.TrimSpace(string(d.WebhookMode))))
+
+		if _, ok := WebhookModeToConfig[d.WebhookMode]; !ok {
+			logger.Stderr.Warn(fmt.Sprintf("invalid or unsupported webhook mode: %s, using default mode: %s", d.WebhookMode, DefaultWebhookMode),
+				slog.String("destination", d.Name),
+			)
+
+			d.WebhookMode = DefaultWebhookMode
+		}
+
+		d.Compression = Compression(strings.ToLower(strings.TrimSpace(string(d.Compression))))
+
+		if !slices.Contains([]Compression{CompressionAuto, CompressionNone, CompressionGzip, CompressionDeflate, CompressionZstd}, d.Compression) {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid COMPRESSION value: %s", d.Name, d.Compression))
+		}
+
+		if d.WebhookMode == WebhookModeTemplate {
+			if Global.EnableDeployLogs && d.TemplateDeployLogs == "" {
+				errors = append(errors, fmt.Errorf("destination %s must set TEMPLATE_DEPLOY_LOGS to send deploy logs in template mode", d.Name))
+			}
+
+			if Global.EnableHttpLogs && d.TemplateHttpLogs == "" {
+				errors = append(errors, fmt.Errorf("destination %s must set TEMPLATE_HTTP_LOGS to send http logs in template mode", d.Name))
+			}
+		}
+
+		d.OtlpEncoding = OtlpEncoding(strings.ToLower(strings.TrimSpace(string(d.OtlpEncoding))))
+
+		if d.WebhookMode == WebhookModeOtlp {
+			if !slices.Contains([]OtlpEncoding{OtlpEncodingProtobuf, OtlpEncodingJson}, d.OtlpEncoding) {
+				errors = append(errors, fmt.Errorf("destination %s has an invalid OTLP_ENCODING value: %s", d.Name, d.OtlpEncoding))
+			}
+
+			// like the otlp exporters, a url without a path is taken as the collector's base url
+			for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+				if u.Host != "" && strings.Trim(u.Path, "/") == "" {
+					u.Path = "/v1/logs"
+				}
+			}
+		}
+
+		if d.WebhookMode == WebhookModeElasticsearch {
+			for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+				if u.Host != "" && strings.Trim(u.Path, "/") == "" {
+					u.Path = "/_bulk"
+				}
+			}
+		}
+
+		if d.WebhookMode == WebhookModeSplunk {
+			errors = append(errors, resolveSplunk(d)...)
+		}
+
+		if d.WebhookMode == WebhookModeLoki {
+			errors = append(errors, resolveLoki(d)...)
+		}
+
+		if d.WebhookMode == WebhookModeSyslog {
+			errors = append(errors, resolveSyslog(d)...)
+		}
+
+		if d.WebhookMode == WebhookModeGelf {
+			// the path of graylog's gelf http input
+			for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+				if (u.Scheme == "http" || u.Scheme == "https") && strings.Trim(u.Path, "/") == "" {
+					u.Path = "/gelf"
+				}
+			}
+		}
+
+		for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+			if u.Host == "" || u.Scheme == "http" || u.Scheme == "https" {
+				continue
+			}
+
+			port, ok := WebhookModeToConfig[d.WebhookMode].SocketPorts[u.Scheme]
+			if !ok {
+				errors = append(errors, fmt.Errorf("destination %s has a webhook url with a scheme the %s webhook mode does not support: %s", d.Name, d.WebhookMode, u.Scheme))
+				continue
+			}
+
+			if u.Port() == "" {
+				u.Host = net.JoinHostPort(u.Hostname(), port)
+			}
+
+			if d.Compression != CompressionAuto && d.Compression != CompressionNone {
+				errors = append(errors, fmt.Errorf("destination %s sets COMPRESSION, which is not supported over %s", d.Name, u.Scheme))
+			}
+
+			// sockets can only be tunneled through a socks5 proxy, and datagrams not at all
+			if Global.WebhookProxyUrl.Host != "" {
+				if u.Scheme == "udp" {
+					errors = append(errors, fmt.Errorf("destination %s uses udp, which can not be sent through WEBHOOK_PROXY_URL", d.Name))
+				} else if Global.WebhookProxyUrl.Scheme != "socks5" && Global.WebhookProxyUrl.Scheme != "socks5h" {
+					errors = append(errors, fmt.Errorf("destination %s uses %s, which can only be sent through a socks5 or socks5h WEBHOOK_PROXY_URL", d.Name, u.Scheme))
+				}
+			}
+		}
+
+		// payloads are framed for the transport of the primary url, so the failover url must use the same kind of transport
+		if d.FailoverWebhookUrl.Host != "" && IsSocketUrl(d.WebhookMode, d.WebhookUrl) != IsSocketUrl(d.WebhookMode, d.FailoverWebhookUrl) {
+			errors = append(errors, fmt.Errorf("destination %s must use http for both or neither of its WEBHOOK_URL and FAILOVER_WEBHOOK_URL", d.Name))
+		}
+
+		if d.Signing.Secret != "" && !slices.Contains([]WebhookMode{WebhookModeJson, WebhookModeJsonl, WebhookModeTemplate}, d.WebhookMode) {
+			errors = append(errors, fmt.Errorf("destination %s sets SIGNING_SECRET, which is only supported with the json, jsonl and template webhook modes", d.Name))
+		}
+
+		if d.Signing.Secret != "" && (strings.TrimSpace(d.Signing.SignatureHeader) == "" || strings.TrimSpace(d.Signing.TimestampHeader) == "") {
+			errors = append(errors, fmt.Errorf("destination %s must have a SIGNATURE_HEADER and SIGNATURE_TIMESTAMP_HEADER when signing", d.Name))
+		}
+
+		if (d.TLS.ClientCert == "") != (d.TLS.ClientKey == "") {
+			errors = append(errors, fmt.Errorf("destination %s must set both TLS_CLIENT_CERT and TLS_CLIENT_KEY, or neither", d.Name))
+		}
+
+		if d.OAuth2.TokenUrl.Host != "" {
+			if d.OAuth2.ClientId == "" || d.OAuth2.ClientSecret == "" {
+				errors = append(errors, fmt.Errorf("destination %s must set OAUTH2_CLIENT_ID and OAUTH2_CLIENT_SECRET when OAUTH2_TOKEN_URL is set", d.Name))
+			}
+
+			d.OAuth2.ClientAuth = OAuth2ClientAuth(strings.ToLower(strings.TrimSpace(string(d.OAuth2.ClientAuth))))
+
+			if !slices.Contains([]OAuth2ClientAuth{OAuth2ClientAuthBasic, OAuth2ClientAuthBody}, d.OAuth2.ClientAuth) {
+				errors = append(errors, fmt.Errorf("destination %s has an invalid OAUTH2_CLIENT_AUTH value: %s", d.Name, d.OAuth2.ClientAuth))
+			}
+		}
+
+		d.RateLimitPolicy = RateLimitPolicy(strings.ToLower(strings.TrimSpace(string(d.RateLimitPolicy))))
+
+		if !slices.Contains([]RateLimitPolicy{RateLimitPolicyBlock, RateLimitPolicyDrop}, d.RateLimitPolicy) {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid RATE_LIMIT_POLICY value: %s", d.Name, d.RateLimitPolicy))
+		}
+
+		if (d.Batch.MaxCount != nil && *d.Batch.MaxCount < 0) || (d.Batch.MaxLinger != nil && *d.Batch.MaxLinger < 0) {
+			errors = append(errors, fmt.Errorf("destination %s must not have a negative BATCH_MAX_COUNT or BATCH_MAX_LINGER", d.Name))
+		}
 
-	if _, ok := WebhookModeToConfig[Global.WebhookMode]; !ok {
-		logger.Stderr.Warn(fmt.Sprintf("invalid or unsupported webhook mode: %s, using default mode: %s", Global.WebhookMode, DefaultWebhookMode))
-		Global.WebhookMode = DefaultWebhookMode
+		// spooled batches always wait for the budget, dropping them would throw away logs that were already kept on disk
+		if d.RateLimitPolicy == RateLimitPolicyDrop && Global.SpoolDir != "" {
+			errors = append(errors, fmt.Errorf("destination %s sets RATE_LIMIT_POLICY to drop, which can not be combined with SPOOL_DIR", d.Name))
+		}
+
+		if d.RateLimitRequestsPerSecond < 0 || d.RateLimitEventsPerSecond < 0 {
+			errors = append(errors, fmt.Errorf("destination %s must not have a negative rate limit", d.Name))
+		}
 	}
 
+	return errors
+}
+
+func warnOnDestinationMisconfiguration(d NamedDestination) {
 	hostAttrs := []any{
-		slog.Any("configured_mode", Global.WebhookMode),
-		slog.String("webhook_host", Global.WebhookUrl.Hostname()),
+		slog.String("destination", d.Name),
+		slog.Any("configured_mode", d.WebhookMode),
+		slog.String("webhook_host", d.WebhookUrl.Hostname()),
 	}
 
 	for mode, config := range WebhookModeToConfig {
-		if mode == Global.WebhookMode {
-			if !containsAnyHost(Global.WebhookUrl.Hostname(), config.ExpectedHostContains) {
+		if mode == d.WebhookMode {
+			if !containsAnyHost(d.WebhookUrl.Hostname(), config.ExpectedHostContains) {
 				hostAttrs = append(hostAttrs, slog.String("expected_host_contains", strings.Join(config.ExpectedHostContains, " OR ")))
 			}
 		} else {
-			if len(config.ExpectedHostContains) > 0 && containsAnyHost(Global.WebhookUrl.Hostname(), config.ExpectedHostContains) {
+			if len(config.ExpectedHostContains) > 0 && containsAnyHost(d.WebhookUrl.Hostname(), config.ExpectedHostContains) {
 				hostAttrs = append(hostAttrs, slog.Any("suggested_mode", mode))
 				break
 			}
@@ -141,30 +411,33 @@ func init() {
 	}
 
 	// Warn if we added any validation attributes beyond the basic ones
-	if len(hostAttrs) > 2 {
+	if len(hostAttrs) > 3 {
 		logger.Stderr.Warn("possible webhook misconfiguration", hostAttrs...)
 	}
 
 	// Header validation with separate attributes and logging
 	headerAttrs := []any{
-		slog.Any("configured_mode", Global.WebhookMode),
-		slog.Any("configured_headers", Global.AdditionalHeaders.Keys()),
+		slog.String("destination", d.Name),
+		slog.Any("configured_mode", d.WebhookMode),
+		slog.Any("configured_headers", d.AdditionalHeaders.Keys()),
 	}
 
-	if len(WebhookModeToConfig[Global.WebhookMode].ExpectedHeaders) > 0 {
+	if len(WebhookModeToConfig[d.WebhookMode].ExpectedHeaders) > 0 {
 		missingHeaders := []string{}
 
-		for _, expectedHeader := range WebhookModeToConfig[Global.WebhookMode].ExpectedHeaders {
-			if !func(expectedHeader string) bool {
-				for configuredHeader := range Global.AdditionalHeaders {
+		for _, expectedHeader := range WebhookModeToConfig[d.WebhookMode].ExpectedHeaders {
+			alternatives := strings.Split(expectedHeader, "|")
+
+			if !slices.ContainsFunc(alternatives, func(expectedHeader string) bool {
+				for configuredHeader := range d.AdditionalHeaders {
 					if strings.EqualFold(configuredHeader, expectedHeader) {
 						return true
 					}
 				}
 
 				return false
-			}(expectedHeader) {
-				missingHeaders = append(missingHeaders, expectedHeader)
+			}) {
+				missingHeaders = append(missingHeaders, strings.Join(alternatives, " OR "))
 			}
 		}
 
@@ -174,7 +447,157 @@ func init() {
 	}
 
 	// Warn if we added any header validation attributes beyond the basic ones
-	if len(headerAttrs) > 2 && len(hostAttrs) <= 2 {
+	if len(headerAttrs) > 3 && len(hostAttrs) <= 3 {
 		logger.Stderr.Warn("possible webhook header misconfiguration", headerAttrs...)
 	}
+
+	supportedCompressions := WebhookModeToConfig[d.WebhookMode].SupportedCompressions
+
+	if d.Compression != CompressionAuto && d.Compression != CompressionNone && len(supportedCompressions) > 0 && !slices.Contains(supportedCompressions, d.Compression) {
+		logger.Stderr.Warn("compression may not be supported by the webhook mode",
+			slog.String("destination", d.Name),
+			slog.Any("configured_mode", d.WebhookMode),
+			slog.Any("configured_compression", d.Compression),
+			slog.Any("supported_compressions", supportedCompressions),
+		)
+	}
+}
+
+// resolveSplunk validates the splunk settings of a destination and fills in the collector endpoint and channel
+func resolveSplunk(d *NamedDestination) []error {
+	errors := []error{}
+
+	d.Splunk.Endpoint = SplunkEndpoint(strings.ToLower(strings.TrimSpace(string(d.Splunk.Endpoint))))
+
+	if !slices.Contains([]SplunkEndpoint{SplunkEndpointEvent, SplunkEndpointRaw}, d.Splunk.Endpoint) {
+		errors = append(errors, fmt.Errorf("destination %s has an invalid SPLUNK_ENDPOINT value: %s", d.Name, d.Splunk.Endpoint))
+		return errors
+	}
+
+	if d.Splunk.Ack && d.Splunk.AckTimeout <= 0 {
+		errors = append(errors, fmt.Errorf("destination %s must have a positive SPLUNK_ACK_TIMEOUT when SPLUNK_ACK is enabled", d.Name))
+	}
+
+	// the raw endpoint and acknowledgements both need a channel, any guid identifies this process to the collector
+	d.Splunk.Channel = strings.TrimSpace(d.Splunk.Channel)
+
+	if d.Splunk.Channel == "" {
+		d.Splunk.Channel = uuid.Must(uuid.NewV4()).String()
+	}
+
+	// without defaults on the raw endpoint an unset field is left to the defaults of the token
+	if d.Splunk.Endpoint == SplunkEndpointEvent {
+		d.Splunk.Host = cmp.Or(d.Splunk.Host, defaultSplunkHost)
+		d.Splunk.Source = cmp.Or(d.Splunk.Source, defaultSplunkSource)
+		d.Splunk.Sourcetype = cmp.Or(d.Splunk.Sourcetype, defaultSplunkSourcetype)
+	}
+
+	query := url.Values{}
+
+	for _, field := range []struct {
+		name  string
+		key   string
+		value string
+	}{
+		{"SPLUNK_HOST", "host", d.Splunk.Host},
+		{"SPLUNK_SOURCE", "source", d.Splunk.Source},
+		{"SPLUNK_SOURCETYPE", "sourcetype", d.Splunk.Sourcetype},
+		{"SPLUNK_INDEX", "index", d.Splunk.Index},
+	} {
+		pattern, err := reconstructor.ParsePattern(field.value)
+		if err != nil {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid %s value: %w", d.Name, field.name, err))
+			continue
+		}
+
+		if d.Splunk.Endpoint != SplunkEndpointRaw || field.value == "" {
+			continue
+		}
+
+		// the raw endpoint takes the metadata of a whole request from its query string, so only fixed values can be sent
+		if pattern.HasPlaceholders() {
+			errors = append(errors, fmt.Errorf("destination %s can not use placeholders in %s with SPLUNK_ENDPOINT=raw: %s", d.Name, field.name, field.value))
+			continue
+		}
+
+		query.Set(field.key, field.value)
+	}
+
+	for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+		if u.Host == "" {
+			continue
+		}
+
+		if strings.Trim(u.Path, "/") == "" {
+			u.Path = reconstruct_splunk.EventPath
+
+			if d.Splunk.Endpoint == SplunkEndpointRaw {
+				u.Path = reconstruct_splunk.RawPath
+			}
+		}
+
+		if len(query) > 0 {
+			values := u.Query()
+
+			for key := range query {
+				if !values.Has(key) {
+					values.Set(key, query.Get(key))
+				}
+			}
+
+			u.RawQuery = values.Encode()
+		}
+	}
+
+	return errors
+}
+
+// resolveSyslog validates the syslog settings of a destination, which is only sent to over sockets
+func resolveSyslog(d *NamedDestination) []error {
+	errors := []error{}
+
+	d.Syslog.Format = SyslogFormat(strings.ToLower(strings.TrimSpace(string(d.Syslog.Format))))
+
+	if !slices.Contains([]SyslogFormat{SyslogFormatRFC5424, SyslogFormatRFC3164}, d.Syslog.Format) {
+		errors = append(errors, fmt.Errorf("destination %s has an invalid SYSLOG_FORMAT value: %s", d.Name, d.Syslog.Format))
+	}
+
+	for name, value := range map[string]string{"SYSLOG_HOSTNAME": d.Syslog.Hostname, "SYSLOG_APP_NAME": d.Syslog.AppName} {
+		if _, err := reconstructor.ParsePattern(value); err != nil {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid %s value: %w", d.Name, name, err))
+		}
+	}
+
+	for _, u := range []*url.URL{&d.WebhookUrl, &d.FailoverWebhookUrl} {
+		if u.Scheme == "http" || u.Scheme == "https" {
+			errors = append(errors, fmt.Errorf("destination %s must use a tcp://, tls:// or udp:// webhook url in syslog mode: %s", d.Name, u.String()))
+		}
+	}
+
+	return errors
+}
+
+// resolveLoki validates the loki settings of a destination
+func resolveLoki(d *NamedDestination) []error {
+	errors := []error{}
+
+	d.Loki.Encoding = LokiEncoding(strings.ToLower(strings.TrimSpace(string(d.Loki.Encoding))))
+
+	if !slices.Contains([]LokiEncoding{LokiEncodingProtobuf, LokiEncodingJson}, d.Loki.Encoding) {
+		errors = append(errors, fmt.Errorf("destination %s has an invalid LOKI_ENCODING value: %s", d.Name, d.Loki.Encoding))
+	}
+
+	if len(d.Loki.Labels) == 0 {
+		errors = append(errors, fmt.Errorf("destination %s must have at least one LOKI_LABELS key, loki rejects streams without labels", d.Name))
+	}
+
+	for _, label := range d.Loki.Labels {
+		if !lokiLabelNameRegex.MatchString(label) {
+			errors = append(errors, fmt.Errorf("destination %s has an invalid label name in LOKI_LABELS: %s", d.Name, label))
+		}
+	}
+
+	d.Loki.TenantId = strings.TrimSpace(d.Loki.TenantId)
+
+	return errors
 }
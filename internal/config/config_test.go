@@ -0,0 +1,93 @@
+package config
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestResolveSplunk(t *testing.T) {
+	tests := []struct {
+		name   string
+		splunk Splunk
+
+		wantErr   string
+		wantPath  string
+		wantQuery url.Values
+		wantHost  string
+	}{
+		{
+			name:     "event endpoint fills in the default patterns",
+			splunk:   Splunk{Endpoint: SplunkEndpointEvent},
+			wantPath: "/services/collector/event",
+			wantHost: defaultSplunkHost,
+		},
+		{
+			name:     "event endpoint keeps set patterns",
+			splunk:   Splunk{Endpoint: SplunkEndpointEvent, Host: "{service_name}"},
+			wantPath: "/services/collector/event",
+			wantHost: "{service_name}",
+		},
+		{
+			name:      "raw endpoint leaves unset fields to the token",
+			splunk:    Splunk{Endpoint: SplunkEndpointRaw},
+			wantPath:  "/services/collector/raw",
+			wantQuery: url.Values{},
+		},
+		{
+			name:      "raw endpoint sends fixed values in the query string",
+			splunk:    Splunk{Endpoint: SplunkEndpointRaw, Host: "railway", Index: "logs"},
+			wantPath:  "/services/collector/raw",
+			wantQuery: url.Values{"host": {"railway"}, "index": {"logs"}},
+			wantHost:  "railway",
+		},
+		{
+			name:    "raw endpoint rejects placeholders",
+			splunk:  Splunk{Endpoint: SplunkEndpointRaw, Source: "{service_name}"},
+			wantErr: "can not use placeholders in SPLUNK_SOURCE",
+		},
+		{
+			name:    "invalid endpoint",
+			splunk:  Splunk{Endpoint: "stream"},
+			wantErr: "invalid SPLUNK_ENDPOINT value",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &NamedDestination{
+				Name: "splunk",
+				Destination: Destination{
+					WebhookUrl: url.URL{Scheme: "https", Host: "splunk.example.com:8088"},
+					Splunk:     tt.splunk,
+				},
+			}
+
+			errors := resolveSplunk(d)
+
+			if tt.wantErr != "" {
+				if len(errors) != 1 || !strings.Contains(errors[0].Error(), tt.wantErr) {
+					t.Fatalf("got errors %v, want one containing %q", errors, tt.wantErr)
+				}
+
+				return
+			}
+
+			if len(errors) > 0 {
+				t.Fatalf("got errors %v, want none", errors)
+			}
+
+			if d.WebhookUrl.Path != tt.wantPath {
+				t.Fatalf("got path %s, want %s", d.WebhookUrl.Path, tt.wantPath)
+			}
+
+			if tt.wantQuery != nil && d.WebhookUrl.RawQuery != tt.wantQuery.Encode() {
+				t.Fatalf("got query %s, want %s", d.WebhookUrl.RawQuery, tt.wantQuery.Encode())
+			}
+
+			if d.Splunk.Host != tt.wantHost {
+				t.Fatalf("got host %q, want %q", d.Splunk.Host, tt.wantHost)
+			}
+		})
+	}
+}
@@ -7,22 +7,36 @@ import (
 	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_betterstack"
 	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_datadog"
 	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_json"
-	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_loki"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_newrelic"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_otlp"
 	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_papertrail"
 	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_sentry"
 )
 
 var schemeRegex = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
 
+// https://prometheus.io/docs/concepts/data_model/#metric-names-and-labels
+var lokiLabelNameRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// proxy schemes supported by net/http, socks5h resolves host names on the proxy
+var proxySchemes = []string{"http", "https", "socks5", "socks5h"}
+
 const (
-	WebhookModeJson        WebhookMode = "json"
-	WebhookModeJsonl       WebhookMode = "jsonl"
-	WebhookModePapertrail  WebhookMode = "papertrail"
-	WebhookModeDatadog     WebhookMode = "datadog"
-	WebhookModeAxiom       WebhookMode = "axiom"
-	WebhookModeBetterstack WebhookMode = "betterstack"
-	WebhookModeLoki        WebhookMode = "loki"
-	WebhookModeSentry      WebhookMode = "sentry"
+	WebhookModeJson          WebhookMode = "json"
+	WebhookModeJsonl         WebhookMode = "jsonl"
+	WebhookModePapertrail    WebhookMode = "papertrail"
+	WebhookModeDatadog       WebhookMode = "datadog"
+	WebhookModeAxiom         WebhookMode = "axiom"
+	WebhookModeBetterstack   WebhookMode = "betterstack"
+	WebhookModeLoki          WebhookMode = "loki"
+	WebhookModeSentry        WebhookMode = "sentry"
+	WebhookModeTemplate      WebhookMode = "template"
+	WebhookModeOtlp          WebhookMode = "otlp"
+	WebhookModeElasticsearch WebhookMode = "elasticsearch"
+	WebhookModeSplunk        WebhookMode = "splunk"
+	WebhookModeSyslog        WebhookMode = "syslog"
+	WebhookModeGelf          WebhookMode = "gelf"
+	WebhookModeNewRelic      WebhookMode = "newrelic"
 
 	DefaultWebhookMode = WebhookModeJson
 )
@@ -41,10 +55,15 @@ var WebhookModeToConfig = map[WebhookMode]WebhookConfig{
 		HTTPLogReconstructorFunc:        reconstruct_json.HttpLogsJsonLines,
 	},
 	WebhookModeLoki: {
-		ExpectedHostContains:            []string{"loki", "grafana"},
-		Headers:                         map[string]string{},
-		EnvironmentLogReconstructorFunc: reconstruct_loki.EnvironmentLogStreams,
-		HTTPLogReconstructorFunc:        reconstruct_loki.HttpLogStreams,
+		ExpectedHostContains: []string{"loki", "grafana"},
+		Headers: map[string]string{
+			// snappy compressed protobuf, json encoded requests override the content type per destination
+			"Content-Type": "application/x-protobuf",
+		},
+		// only applies to json encoded requests, protobuf requests are already compressed with snappy
+		SupportedCompressions: []Compression{CompressionGzip},
+		MaxBatchBytes:         4 << 20,   // Loki's default gRPC receive limit
+		MaxEntryBytes:         256 << 10, // Loki's default max_line_size on Grafana Cloud
 	},
 	WebhookModePapertrail: {
 		ExpectedHostContains: []string{"solarwinds"},
@@ -60,6 +79,10 @@ var WebhookModeToConfig = map[WebhookMode]WebhookConfig{
 		ExpectedHostContains:            []string{"datadog"},
 		ExpectedHeaders:                 []string{"DD-API-KEY", "DD-APPLICATION-KEY"},
 		Headers:                         map[string]string{},
+		SupportedCompressions:           []Compression{CompressionGzip, CompressionDeflate},
+		MaxBatchCount:                   1000,    // https://docs.datadoghq.com/api/latest/logs/#send-logs
+		MaxBatchBytes:                   5 << 20, // https://docs.datadoghq.com/api/latest/logs/#send-logs
+		MaxEntryBytes:                   1 << 20, // https://docs.datadoghq.com/api/latest/logs/#send-logs
 		EnvironmentLogReconstructorFunc: reconstruct_datadog.EnvironmentLogsJsonArray,
 		HTTPLogReconstructorFunc:        reconstruct_datadog.HttpLogsJsonArray,
 	},
@@ -67,6 +90,7 @@ var WebhookModeToConfig = map[WebhookMode]WebhookConfig{
 		ExpectedHostContains:            []string{"axiom"},
 		ExpectedHeaders:                 []string{"Authorization"},
 		Headers:                         map[string]string{},
+		SupportedCompressions:           []Compression{CompressionGzip, CompressionZstd},
 		EnvironmentLogReconstructorFunc: reconstruct_axiom.EnvironmentLogsJsonArray,
 		HTTPLogReconstructorFunc:        reconstruct_axiom.HttpLogsJsonArray,
 	},
@@ -74,6 +98,8 @@ var WebhookModeToConfig = map[WebhookMode]WebhookConfig{
 		ExpectedHostContains:            []string{"betterstack"},
 		ExpectedHeaders:                 []string{"Authorization"},
 		Headers:                         map[string]string{},
+		SupportedCompressions:           []Compression{CompressionGzip},
+		MaxBatchBytes:                   10 << 20, // https://betterstack.com/docs/logs/ingesting-data/http/logs/
 		EnvironmentLogReconstructorFunc: reconstruct_betterstack.EnvironmentLogsJsonArray,
 		HTTPLogReconstructorFunc:        reconstruct_betterstack.HttpLogsJsonArray,
 	},
@@ -83,7 +109,71 @@ var WebhookModeToConfig = map[WebhookMode]WebhookConfig{
 		Headers: map[string]string{
 			"Content-Type": "application/x-sentry-envelope",
 		},
+		// an envelope carries a single event, so every log is sent in its own request
+		MaxBatchCount:                   1,
+		MaxBatchBytes:                   1 << 20, // https://develop.sentry.dev/sdk/data-model/envelopes/#size-limits
 		EnvironmentLogReconstructorFunc: reconstruct_sentry.EnvironmentLogsEnvelope,
 		HTTPLogReconstructorFunc:        reconstruct_sentry.HttpLogsEnvelope,
 	},
+	WebhookModeOtlp: {
+		Headers: map[string]string{
+			// json encoded requests override the content type per destination
+			"Content-Type": "application/x-protobuf",
+		},
+		SupportedCompressions:           []Compression{CompressionGzip},
+		EnvironmentLogReconstructorFunc: reconstruct_otlp.EnvironmentLogsProtobuf,
+		HTTPLogReconstructorFunc:        reconstruct_otlp.HttpLogsProtobuf,
+	},
+	WebhookModeElasticsearch: {
+		ExpectedHostContains: []string{"elastic", "opensearch", "bonsai"},
+		ExpectedHeaders:      []string{"Authorization"},
+		Headers: map[string]string{
+			"Content-Type": "application/x-ndjson",
+		},
+		SupportedCompressions: []Compression{CompressionGzip},
+	},
+	WebhookModeSplunk: {
+		ExpectedHostContains: []string{"splunk"},
+		ExpectedHeaders:      []string{"Authorization"},
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		SupportedCompressions: []Compression{CompressionGzip},
+		MaxBatchBytes:         1 << 20, // max_content_length of the http event collector https://docs.splunk.com/Documentation/Splunk/latest/Admin/Limitsconf
+	},
+	WebhookModeSyslog: {
+		Headers: map[string]string{},
+		SocketPorts: map[string]string{
+			"tcp": "514",
+			"tls": "6514", // https://datatracker.ietf.org/doc/html/rfc5425#section-4.1
+			"udp": "514",
+		},
+	},
+	WebhookModeGelf: {
+		ExpectedHostContains: []string{"graylog"},
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		// only applies to http, messages sent over udp are compressed with gzip on their own and tcp does not support compression
+		SupportedCompressions: []Compression{CompressionGzip, CompressionDeflate},
+		SocketPorts: map[string]string{
+			"tcp": "12201",
+			"tls": "12201",
+			"udp": "12201",
+		},
+	},
+	WebhookModeNewRelic: {
+		ExpectedHostContains: []string{"newrelic"},
+		ExpectedHeaders:      []string{"Api-Key|X-License-Key"},
+		Headers: map[string]string{
+			"Content-Type": "application/json",
+		},
+		SupportedCompressions:           []Compression{CompressionGzip},
+		MaxBatchBytes:                   1_000_000, // https://docs.newrelic.com/docs/logs/log-api/introduction-log-api/#limits
+		EnvironmentLogReconstructorFunc: reconstruct_newrelic.EnvironmentLogs,
+		HTTPLogReconstructorFunc:        reconstruct_newrelic.HttpLogs,
+	},
+	WebhookModeTemplate: {
+		Headers: map[string]string{},
+	},
 }
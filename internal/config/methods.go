@@ -1,8 +1,13 @@
 package config
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 )
 
 func (h *AdditionalHeaders) UnmarshalText(envByte []byte) error {
@@ -46,3 +51,206 @@ func (h *AdditionalHeaders) Keys() []string {
 
 	return keys
 }
+
+var byteSizeUnits = map[string]int64{
+	"":    1,
+	"b":   1,
+	"kb":  1000,
+	"kib": 1 << 10,
+	"mb":  1000 * 1000,
+	"mib": 1 << 20,
+	"gb":  1000 * 1000 * 1000,
+	"gib": 1 << 30,
+}
+
+func (b *ByteSize) UnmarshalText(envByte []byte) error {
+	envStringTrimmed := strings.ToLower(strings.TrimSpace(string(envByte)))
+
+	numberEnd := strings.IndexFunc(envStringTrimmed, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+
+	if numberEnd == -1 {
+		numberEnd = len(envStringTrimmed)
+	}
+
+	number, err := strconv.ParseFloat(envStringTrimmed[:numberEnd], 64)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid byte size: %s", envStringTrimmed)
+	}
+
+	unit, ok := byteSizeUnits[strings.TrimSpace(envStringTrimmed[numberEnd:])]
+	if !ok {
+		return fmt.Errorf("invalid byte size unit: %s", envStringTrimmed)
+	}
+
+	*b = ByteSize(number * float64(unit))
+
+	return nil
+}
+
+var tlsVersions = map[string]TLSVersion{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+func (v *TLSVersion) UnmarshalText(envByte []byte) error {
+	envStringTrimmed := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(string(envByte))), "tls")
+
+	version, ok := tlsVersions[strings.TrimSpace(envStringTrimmed)]
+	if !ok {
+		return fmt.Errorf("invalid tls version, must be one of 1.0, 1.1, 1.2 or 1.3: %s", envByte)
+	}
+
+	*v = version
+
+	return nil
+}
+
+// IsSet reports whether any setting beyond the default minimum version is configured
+func (t TLS) IsSet() bool {
+	return t.ClientCert != "" || t.ClientKey != "" || t.CABundle != "" || t.ServerName != "" || (t.MinVersion != 0 && t.MinVersion != tls.VersionTLS12)
+}
+
+// facility codes of RFC 5424 section 6.2.1
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news", "uucp", "cron", "authpriv", "ftp",
+	"ntp", "security", "console", "solaris-cron", "local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+func (f *SyslogFacility) UnmarshalText(envByte []byte) error {
+	envStringTrimmed := strings.ToLower(strings.TrimSpace(string(envByte)))
+
+	if facility := slices.Index(syslogFacilities, envStringTrimmed); facility >= 0 {
+		*f = SyslogFacility(facility)
+		return nil
+	}
+
+	facility, err := strconv.Atoi(envStringTrimmed)
+	if err != nil || facility < 0 || facility >= len(syslogFacilities) {
+		return fmt.Errorf("invalid syslog facility, must be a facility name such as user or local0, or a number between 0 and 23: %s", envByte)
+	}
+
+	*f = SyslogFacility(facility)
+
+	return nil
+}
+
+func (c *StatusCodes) UnmarshalText(envByte []byte) error {
+	codes := StatusCodes{}
+
+	for _, part := range strings.Split(string(envByte), ",") {
+		part = strings.TrimSpace(part)
+
+		if part == "" {
+			continue
+		}
+
+		first, last, isRange := strings.Cut(part, "-")
+
+		from, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return fmt.Errorf("invalid status code: %s", part)
+		}
+
+		to := from
+
+		if isRange {
+			if to, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+				return fmt.Errorf("invalid status code range: %s", part)
+			}
+		}
+
+		if from < 100 || to > 599 || from > to {
+			return fmt.Errorf("invalid status code or range, must be between 100 and 599: %s", part)
+		}
+
+		for code := from; code <= to; code++ {
+			codes = append(codes, code)
+		}
+	}
+
+	*c = codes
+
+	return nil
+}
+
+func (a *ResponseAssertions) UnmarshalText(envByte []byte) error {
+	assertions := ResponseAssertions{}
+
+	for _, part := range strings.Split(string(envByte), ";") {
+		if strings.TrimSpace(part) == "" {
+			continue
+		}
+
+		path, value, ok := strings.Cut(part, "=")
+		if !ok || strings.TrimSpace(path) == "" {
+			return fmt.Errorf("response assertion must be in format path=value or path!=value; found %s", part)
+		}
+
+		assertion := ResponseAssertion{
+			Path:  strings.TrimSpace(path),
+			Value: strings.TrimSpace(value),
+		}
+
+		if strings.HasSuffix(assertion.Path, "!") {
+			assertion.Path = strings.TrimSpace(strings.TrimSuffix(assertion.Path, "!"))
+			assertion.Negate = true
+		}
+
+		assertions = append(assertions, assertion)
+	}
+
+	*a = assertions
+
+	return nil
+}
+
+func (a ResponseAssertion) String() string {
+	if a.Negate {
+		return a.Path + "!=" + a.Value
+	}
+
+	return a.Path + "=" + a.Value
+}
+
+// IsSocketUrl reports whether the url has one of the socket schemes of the webhook mode, so it is sent to over a socket rather than http
+func IsSocketUrl(mode WebhookMode, u url.URL) bool {
+	_, ok := WebhookModeToConfig[mode].SocketPorts[u.Scheme]
+	return ok
+}
+
+// MaxRequestBytes returns the maximum request size of the destination lowered to the limit of its webhook mode where needed, zero means no limit
+func (d Destination) MaxRequestBytes() int64 {
+	maxBytes := int64(Global.BatchMaxBytes)
+
+	if d.Batch.MaxBytes != nil {
+		maxBytes = int64(*d.Batch.MaxBytes)
+	}
+
+	if modeMax := WebhookModeToConfig[d.WebhookMode].MaxBatchBytes; modeMax > 0 && (maxBytes <= 0 || modeMax < maxBytes) {
+		maxBytes = modeMax
+	}
+
+	return maxBytes
+}
+
+// BatchMaxCount returns the maximum number of logs in a batch of the destination, falling back to the global limit
+func (d Destination) BatchMaxCount() int {
+	if d.Batch.MaxCount != nil {
+		return *d.Batch.MaxCount
+	}
+
+	return Global.BatchMaxCount
+}
+
+// BatchMaxLinger returns how long a batch of the destination waits to fill up, falling back to the global limit
+func (d Destination) BatchMaxLinger() time.Duration {
+	if d.Batch.MaxLinger != nil {
+		return *d.Batch.MaxLinger
+	}
+
+	return Global.BatchMaxLinger
+}
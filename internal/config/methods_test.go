@@ -0,0 +1,41 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestSyslogFacilityUnmarshalText(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+
+		want    SyslogFacility
+		wantErr bool
+	}{
+		{name: "name", value: "user", want: 1},
+		{name: "name in upper case with spaces", value: " LOCAL0 ", want: 16},
+		{name: "last name", value: "local7", want: 23},
+		{name: "number", value: "3", want: 3},
+		{name: "zero", value: "0", want: 0},
+		{name: "number out of range", value: "24", wantErr: true},
+		{name: "negative number", value: "-1", wantErr: true},
+		{name: "unknown name", value: "local8", wantErr: true},
+		{name: "empty", value: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got SyslogFacility
+
+			err := got.UnmarshalText([]byte(tt.value))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && got != tt.want {
+				t.Fatalf("got %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"net/url"
 	"time"
 
+	"github.com/brody192/locomotive/internal/buffer"
 	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
 	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/spool"
 	"github.com/flexstack/uuid"
 )
 
@@ -50,34 +52,310 @@ type (
 	AdditionalHeaders map[string]string
 
 	WebhookMode string
+
+	// a size in bytes, parsed from values such as 512, 64KB, 16MiB or 1GiB
+	ByteSize int64
+
+	Compression string
+)
+
+const (
+	// use the compression preferred by the webhook mode, if any
+	CompressionAuto    Compression = ""
+	CompressionNone    Compression = "none"
+	CompressionGzip    Compression = "gzip"
+	CompressionDeflate Compression = "deflate"
+	CompressionZstd    Compression = "zstd"
+)
+
+// RateLimitPolicy decides what happens to logs when a destination's rate limit is exhausted
+type RateLimitPolicy string
+
+const (
+	// wait for the budget, holding up delivery to the destination
+	RateLimitPolicyBlock RateLimitPolicy = "block"
+	// drop the logs and count them
+	RateLimitPolicyDrop RateLimitPolicy = "drop"
+)
+
+// OtlpEncoding is the encoding of requests sent in the otlp webhook mode
+type OtlpEncoding string
+
+const (
+	OtlpEncodingProtobuf OtlpEncoding = "protobuf"
+	OtlpEncodingJson     OtlpEncoding = "json"
 )
 
 type WebhookConfig struct {
 	ExpectedHostContains []string
-	ExpectedHeaders      []string
+	// every expected header should be configured, an entry can list alternatives separated by |, such as Api-Key|X-License-Key
+	ExpectedHeaders []string
 
 	Headers AdditionalHeaders
 
+	// request body encodings accepted by the destination, the first one is used unless configured otherwise
+	SupportedCompressions []Compression
+
+	// limits imposed by the destination on a single request, zero means no documented limit
+	MaxBatchCount int
+	MaxBatchBytes int64
+
+	// limit imposed by the destination on the message of a single log, longer messages are truncated, zero means no documented limit
+	MaxEntryBytes int
+
+	// the socket schemes, such as tcp, tls or udp, a mode can be sent over instead of http, mapped to their default port
+	SocketPorts map[string]string
+
+	// nil for modes whose payloads depend on settings of the destination, those are reconstructed by the destination itself
 	EnvironmentLogReconstructorFunc func([]environment_logs.EnvironmentLogWithMetadata) ([]byte, error)
 	HTTPLogReconstructorFunc        func([]http_logs.DeploymentHttpLogWithMetadata) ([]byte, error)
 }
 
-type config struct {
-	RailwayApiKey uuid.UUID   `env:"RAILWAY_API_KEY,required,notEmpty"`
-	EnvironmentId uuid.UUID   `env:"ENVIRONMENT_ID,required,notEmpty"`
-	ServiceIds    []uuid.UUID `env:"SERVICE_IDS,required,notEmpty"`
-
-	WebhookUrl        url.URL           `env:"WEBHOOK_URL,required,notEmpty"`
+// Destination holds the settings of a single place logs are delivered to
+type Destination struct {
+	WebhookUrl        url.URL           `env:"WEBHOOK_URL"`
 	AdditionalHeaders AdditionalHeaders `env:"ADDITIONAL_HEADERS"`
 	WebhookMode       WebhookMode       `env:"WEBHOOK_MODE" envDefault:"json"`
 
+	// receives the logs while WebhookUrl is unavailable
+	FailoverWebhookUrl url.URL `env:"FAILOVER_WEBHOOK_URL"`
+
+	HttpMethod          string             `env:"HTTP_METHOD" envDefault:"POST"`
+	AcceptedStatusCodes StatusCodes        `env:"ACCEPTED_STATUS_CODES"`
+	ResponseAssertions  ResponseAssertions `env:"RESPONSE_ASSERTIONS"`
+
 	MinSeverity SeverityLevel `env:"MIN_SEVERITY" envDefault:"debug"`
 
 	Whitelist []string `env:"WHITELIST" envSeparator:"," envDefault:""`
 	Blacklist []string `env:"BLACKLIST" envSeparator:"," envDefault:""`
-	
+
+	Compression        Compression `env:"COMPRESSION"`
+	CompressionMinSize ByteSize    `env:"COMPRESSION_MIN_SIZE" envDefault:"1KiB"`
+
+	RateLimitRequestsPerSecond float64         `env:"RATE_LIMIT_REQUESTS_PER_SECOND"`
+	RateLimitEventsPerSecond   float64         `env:"RATE_LIMIT_EVENTS_PER_SECOND"`
+	RateLimitPolicy            RateLimitPolicy `env:"RATE_LIMIT_POLICY" envDefault:"block"`
+
+	Batch Batch
+
+	Signing Signing
+
+	// text/template files used by the template webhook mode
+	TemplateDeployLogs  string `env:"TEMPLATE_DEPLOY_LOGS"`
+	TemplateHttpLogs    string `env:"TEMPLATE_HTTP_LOGS"`
+	TemplateContentType string `env:"TEMPLATE_CONTENT_TYPE" envDefault:"application/json"`
+
+	OtlpEncoding OtlpEncoding `env:"OTLP_ENCODING" envDefault:"protobuf"`
+
+	ElasticsearchIndex      string `env:"ELASTICSEARCH_INDEX"`
+	ElasticsearchDataStream bool   `env:"ELASTICSEARCH_DATA_STREAM"`
+
+	Splunk Splunk
+
+	Syslog Syslog
+
+	Loki Loki
+
+	TLS TLS
+
+	OAuth2 OAuth2
+}
+
+// OAuth2 holds the settings for authorizing requests with tokens from the client credentials grant, disabled when the token url is empty
+type OAuth2 struct {
+	TokenUrl     url.URL          `env:"OAUTH2_TOKEN_URL"`
+	ClientId     string           `env:"OAUTH2_CLIENT_ID"`
+	ClientSecret string           `env:"OAUTH2_CLIENT_SECRET"`
+	Scopes       []string         `env:"OAUTH2_SCOPES" envSeparator:","`
+	Audience     string           `env:"OAUTH2_AUDIENCE"`
+	ClientAuth   OAuth2ClientAuth `env:"OAUTH2_CLIENT_AUTH" envDefault:"basic"`
+}
+
+// OAuth2ClientAuth is how the client id and secret are sent to the token endpoint
+type OAuth2ClientAuth string
+
+const (
+	// HTTP basic authentication, the method every token endpoint must support
+	OAuth2ClientAuthBasic OAuth2ClientAuth = "basic"
+	// form parameters in the request body
+	OAuth2ClientAuthBody OAuth2ClientAuth = "body"
+)
+
+// TLS holds the TLS settings used to connect to a destination, certificates and keys are either PEM contents or paths to PEM files
+type TLS struct {
+	ClientCert string     `env:"TLS_CLIENT_CERT"`
+	ClientKey  string     `env:"TLS_CLIENT_KEY"`
+	CABundle   string     `env:"TLS_CA_BUNDLE"`
+	ServerName string     `env:"TLS_SERVER_NAME"`
+	MinVersion TLSVersion `env:"TLS_MIN_VERSION" envDefault:"1.2"`
+}
+
+// TLSVersion is a crypto/tls version constant parsed from a version number such as 1.2
+type TLSVersion uint16
+
+// StatusCodes is a list of http status codes parsed from a comma separated list of codes and ranges, such as 200,204 or 200-299
+type StatusCodes []int
+
+// ResponseAssertion checks that the value at a gjson path of a response body equals, or with Negate does not equal, a value
+type ResponseAssertion struct {
+	Path   string
+	Value  string
+	Negate bool
+}
+
+// ResponseAssertions is a list of assertions parsed from semicolon separated path=value or path!=value pairs
+type ResponseAssertions []ResponseAssertion
+
+// Batch holds the batch limits of a single destination, limits that are not set fall back to the global LOCOMOTIVE_BATCH_* values
+type Batch struct {
+	MaxCount  *int           `env:"BATCH_MAX_COUNT"`
+	MaxBytes  *ByteSize      `env:"BATCH_MAX_BYTES"`
+	MaxLinger *time.Duration `env:"BATCH_MAX_LINGER"`
+}
+
+// Signing holds the settings for signing request bodies with HMAC-SHA256, signing is disabled when the secret is empty
+type Signing struct {
+	Secret          string `env:"SIGNING_SECRET"`
+	SignatureHeader string `env:"SIGNATURE_HEADER" envDefault:"X-Locomotive-Signature"`
+	TimestampHeader string `env:"SIGNATURE_TIMESTAMP_HEADER" envDefault:"X-Locomotive-Timestamp"`
+}
+
+// SplunkEndpoint is the http event collector endpoint logs are sent to in the splunk webhook mode
+type SplunkEndpoint string
+
+const (
+	// json event objects carrying their own metadata
+	SplunkEndpointEvent SplunkEndpoint = "event"
+	// json lines, with the metadata of the whole request in the query string
+	SplunkEndpointRaw SplunkEndpoint = "raw"
+)
+
+// the event metadata patterns used when none are set for the event endpoint, the raw endpoint can only send fixed values
+const (
+	defaultSplunkHost       = "{project_name}-{environment_name}"
+	defaultSplunkSource     = "{service_name}"
+	defaultSplunkSourcetype = "railway:{log_type}"
+)
+
+// Splunk holds the settings of the splunk webhook mode, the event metadata fields are patterns filled in from the log metadata
+type Splunk struct {
+	// left empty here so the defaults can depend on the endpoint, they are filled in by resolveSplunk
+	Host       string `env:"SPLUNK_HOST"`
+	Source     string `env:"SPLUNK_SOURCE"`
+	Sourcetype string `env:"SPLUNK_SOURCETYPE"`
+	Index      string `env:"SPLUNK_INDEX"`
+
+	Endpoint SplunkEndpoint `env:"SPLUNK_ENDPOINT" envDefault:"event"`
+
+	// identifies the client to the collector, required by the raw endpoint and acknowledgements, a random channel is used if empty
+	Channel string `env:"SPLUNK_CHANNEL"`
+
+	// wait for the collector to acknowledge that every request was indexed
+	Ack        bool          `env:"SPLUNK_ACK"`
+	AckTimeout time.Duration `env:"SPLUNK_ACK_TIMEOUT" envDefault:"1m"`
+	// send requests that were not acknowledged in time again, which can index their events twice
+	AckRetry bool `env:"SPLUNK_ACK_RETRY"`
+}
+
+// SyslogFormat is the message format of the syslog webhook mode
+type SyslogFormat string
+
+const (
+	SyslogFormatRFC5424 SyslogFormat = "rfc5424"
+	// the legacy bsd format, for receivers that do not understand RFC 5424
+	SyslogFormatRFC3164 SyslogFormat = "rfc3164"
+)
+
+// SyslogFacility is a syslog facility code parsed from its name, such as user or local0, or its number
+type SyslogFacility int
+
+// Syslog holds the settings of the syslog webhook mode, the hostname and app-name are patterns filled in from the log metadata
+type Syslog struct {
+	Format   SyslogFormat   `env:"SYSLOG_FORMAT" envDefault:"rfc5424"`
+	Facility SyslogFacility `env:"SYSLOG_FACILITY" envDefault:"user"`
+	Hostname string         `env:"SYSLOG_HOSTNAME" envDefault:"{project_name}-{environment_name}"`
+	AppName  string         `env:"SYSLOG_APP_NAME" envDefault:"{service_name}"`
+}
+
+// LokiEncoding is the encoding of requests sent in the loki webhook mode
+type LokiEncoding string
+
+const (
+	// snappy compressed protobuf, loki's native push format
+	LokiEncodingProtobuf LokiEncoding = "protobuf"
+	LokiEncodingJson     LokiEncoding = "json"
+)
+
+// Loki holds the settings of the loki webhook mode
+type Loki struct {
+	// metadata keys indexed as stream labels, the rest of the metadata is sent as structured metadata
+	Labels   []string     `env:"LOKI_LABELS" envSeparator:"," envDefault:"project_name,environment_name,service_name,log_type"`
+	Encoding LokiEncoding `env:"LOKI_ENCODING" envDefault:"protobuf"`
+
+	// sent as the X-Scope-OrgID header of multi-tenant loki deployments
+	TenantId string `env:"LOKI_TENANT_ID"`
+}
+
+type NamedDestination struct {
+	Name string `env:"NAME"`
+
+	Destination
+}
+
+type config struct {
+	RailwayApiKey uuid.UUID   `env:"RAILWAY_API_KEY,required,notEmpty"`
+	EnvironmentId uuid.UUID   `env:"ENVIRONMENT_ID,required,notEmpty"`
+	ServiceIds    []uuid.UUID `env:"SERVICE_IDS,required,notEmpty"`
+
+	// the destination configured by the un-indexed variables, e.g. LOCOMOTIVE_WEBHOOK_URL
+	Destination
+
+	// destinations configured by indexed variables, e.g. LOCOMOTIVE_DESTINATION_0_WEBHOOK_URL
+	//
+	// after parsing, this also holds the un-indexed destination under the name "default" if it was configured
+	Destinations []NamedDestination `envPrefix:"DESTINATION_"`
+
 	ReportStatusEvery time.Duration `env:"REPORT_STATUS_EVERY" envDefault:"1m"`
 
+	// how long pending batches are delivered for after a shutdown signal
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"8s"`
+
+	RetryMaxAttempts     uint64        `env:"RETRY_MAX_ATTEMPTS" envDefault:"5"`
+	RetryInitialInterval time.Duration `env:"RETRY_INITIAL_INTERVAL" envDefault:"500ms"`
+	RetryMaxInterval     time.Duration `env:"RETRY_MAX_INTERVAL" envDefault:"30s"`
+	RetryMaxElapsedTime  time.Duration `env:"RETRY_MAX_ELAPSED_TIME" envDefault:"2m"`
+	RetryJitterPercent   uint64        `env:"RETRY_JITTER_PERCENT" envDefault:"20"`
+
+	CircuitBreakerFailureThreshold int           `env:"CIRCUIT_BREAKER_FAILURE_THRESHOLD" envDefault:"5"`
+	CircuitBreakerSuccessThreshold int           `env:"CIRCUIT_BREAKER_SUCCESS_THRESHOLD" envDefault:"1"`
+	CircuitBreakerProbeInterval    time.Duration `env:"CIRCUIT_BREAKER_PROBE_INTERVAL" envDefault:"30s"`
+
+	WebhookProxyUrl url.URL `env:"WEBHOOK_PROXY_URL"`
+	RailwayProxyUrl url.URL `env:"RAILWAY_PROXY_URL"`
+
+	DeliveryConcurrency int `env:"DELIVERY_CONCURRENCY" envDefault:"4"`
+
+	BufferMaxEvents    int           `env:"BUFFER_MAX_EVENTS" envDefault:"10000"`
+	BufferMaxBytes     ByteSize      `env:"BUFFER_MAX_BYTES" envDefault:"64MiB"`
+	BufferPolicy       buffer.Policy `env:"BUFFER_POLICY" envDefault:"block"`
+	BufferDropSeverity SeverityLevel `env:"BUFFER_DROP_SEVERITY" envDefault:"warn"`
+
+	BatchMaxCount  int           `env:"BATCH_MAX_COUNT" envDefault:"500"`
+	BatchMaxBytes  ByteSize      `env:"BATCH_MAX_BYTES" envDefault:"1MiB"`
+	BatchMaxLinger time.Duration `env:"BATCH_MAX_LINGER" envDefault:"1s"`
+
+	SpoolDir           string            `env:"SPOOL_DIR"`
+	SpoolMaxSize       ByteSize          `env:"SPOOL_MAX_SIZE" envDefault:"1GiB"`
+	SpoolSegmentSize   ByteSize          `env:"SPOOL_SEGMENT_SIZE" envDefault:"16MiB"`
+	SpoolFsync         spool.FsyncPolicy `env:"SPOOL_FSYNC" envDefault:"interval"`
+	SpoolFsyncInterval time.Duration     `env:"SPOOL_FSYNC_INTERVAL" envDefault:"1s"`
+
+	DeadLetterDir               string            `env:"DEAD_LETTER_DIR"`
+	DeadLetterMaxFileSize       ByteSize          `env:"DEAD_LETTER_MAX_FILE_SIZE" envDefault:"64MiB"`
+	DeadLetterMaxFiles          int               `env:"DEAD_LETTER_MAX_FILES" envDefault:"10"`
+	DeadLetterWebhookUrl        url.URL           `env:"DEAD_LETTER_WEBHOOK_URL"`
+	DeadLetterAdditionalHeaders AdditionalHeaders `env:"DEAD_LETTER_ADDITIONAL_HEADERS"`
+
 	EnableHttpLogs   bool `env:"ENABLE_HTTP_LOGS" envDefault:"false"`
 	EnableDeployLogs bool `env:"ENABLE_DEPLOY_LOGS" envDefault:"true"`
 }
@@ -0,0 +1,242 @@
+package deadletter
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	filePrefix    = "deadletter-"
+	fileExtension = ".jsonl"
+
+	// files are renamed to this extension while they are being re-driven so new entries go to a fresh file
+	redrivingExtension = ".redriving"
+)
+
+// Entry is a payload that was permanently rejected by a destination, along with why it was rejected
+type Entry struct {
+	Time         time.Time `json:"time"`
+	Destination  string    `json:"destination"`
+	Mode         string    `json:"mode"`
+	LogKind      string    `json:"log_kind"`
+	LogCount     int       `json:"log_count"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	ResponseBody string    `json:"response_body,omitempty"`
+	Error        string    `json:"error"`
+
+	// the serialized payload before compression, base64 encoded in the file
+	Payload []byte `json:"payload"`
+}
+
+// Writer appends entries as json lines to a directory of size capped files, removing the oldest files once there are too many.
+//
+// Files claimed by a re-drive are not counted or removed.
+type Writer struct {
+	dir         string
+	maxFileSize int64
+	maxFiles    int
+
+	mu      sync.Mutex
+	current string
+}
+
+// NewWriter returns a writer keeping at most maxFiles files in dir, zero means no limit
+func NewWriter(dir string, maxFileSize int64, maxFiles int) (*Writer, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create dead letter directory: %w", err)
+	}
+
+	return &Writer{
+		dir:         dir,
+		maxFileSize: maxFileSize,
+		maxFiles:    maxFiles,
+	}, nil
+}
+
+func (w *Writer) Write(entry Entry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to marshal dead letter entry: %w", err)
+	}
+
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if err := w.rotateLocked(int64(len(line))); err != nil {
+		return err
+	}
+
+	// the file is opened for every entry so a re-drive renaming it away is picked up right away
+	f, err := os.OpenFile(w.current, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+
+	if _, err := f.Write(line); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write dead letter entry: %w", err)
+	}
+
+	return f.Close()
+}
+
+// rotateLocked starts a new file if there is none yet or the current one would grow past the maximum size
+func (w *Writer) rotateLocked(lineSize int64) error {
+	if w.current != "" {
+		info, err := os.Stat(w.current)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+
+		if err == nil && info.Size()+lineSize <= w.maxFileSize {
+			return nil
+		}
+	}
+
+	w.current = filepath.Join(w.dir, filePrefix+time.Now().UTC().Format("20060102T150405.000000000")+fileExtension)
+
+	files, err := Files(w.dir)
+	if err != nil {
+		return err
+	}
+
+	// make room for the file about to be created
+	for len(files) >= w.maxFiles && w.maxFiles > 0 {
+		if err := os.Remove(files[0]); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove old dead letter file: %w", err)
+		}
+
+		files = files[1:]
+	}
+
+	return nil
+}
+
+// Files lists the dead letter files in the directory, oldest first
+func Files(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read dead letter directory: %w", err)
+	}
+
+	files := []string{}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), filePrefix) || !strings.HasSuffix(entry.Name(), fileExtension) {
+			continue
+		}
+
+		files = append(files, filepath.Join(dir, entry.Name()))
+	}
+
+	slices.Sort(files)
+
+	return files, nil
+}
+
+// Claim renames a dead letter file out of the way of the writer and returns its entries,
+// the returned path must be removed with Release once the entries have been dealt with
+func Claim(path string) ([]Entry, string, error) {
+	claimedPath := path + redrivingExtension
+
+	if err := os.Rename(path, claimedPath); err != nil {
+		return nil, "", fmt.Errorf("failed to claim dead letter file: %w", err)
+	}
+
+	entries, err := readEntries(claimedPath)
+	if err != nil {
+		// hand the file back untouched so nothing is lost
+		os.Rename(claimedPath, path)
+
+		return nil, "", err
+	}
+
+	return entries, claimedPath, nil
+}
+
+func Release(claimedPath string) error {
+	return os.Remove(claimedPath)
+}
+
+// Unclaim hands a claimed file back to the directory untouched, so it is re-driven again later.
+//
+// A writer that was appending to the file before it was claimed starts it anew under the same name,
+// in that case the claimed file is handed back next to it rather than replacing it.
+func Unclaim(claimedPath string) error {
+	path := strings.TrimSuffix(claimedPath, redrivingExtension)
+
+	if _, err := os.Lstat(path); err == nil {
+		path = strings.TrimSuffix(path, fileExtension) + "-unclaimed" + fileExtension
+	}
+
+	return os.Rename(claimedPath, path)
+}
+
+// Recover hands back the files left claimed by a re-drive that was killed before it could release or unclaim them,
+// it must not be called while another re-drive is running. It returns the number of files handed back.
+func Recover(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read dead letter directory: %w", err)
+	}
+
+	recovered := 0
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), filePrefix) || !strings.HasSuffix(entry.Name(), fileExtension+redrivingExtension) {
+			continue
+		}
+
+		if err := Unclaim(filepath.Join(dir, entry.Name())); err != nil {
+			return recovered, fmt.Errorf("failed to recover claimed dead letter file: %w", err)
+		}
+
+		recovered++
+	}
+
+	return recovered, nil
+}
+
+func readEntries(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open dead letter file: %w", err)
+	}
+
+	defer f.Close()
+
+	entries := []Entry{}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+
+	for scanner.Scan() {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		entry := Entry{}
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse dead letter entry in %s: %w", path, err)
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read dead letter file: %w", err)
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,290 @@
+package deadletter
+
+import (
+	"encoding/json"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// writeFiles creates the named files in dir, each holding its own name as content
+func writeFiles(t *testing.T, dir string, names ...string) {
+	t.Helper()
+
+	for _, name := range names {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// dirContents returns the content of every file in dir by name
+func dirContents(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	contents := map[string]string{}
+
+	for _, entry := range entries {
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		contents[entry.Name()] = string(content)
+	}
+
+	return contents
+}
+
+func TestRecover(t *testing.T) {
+	tests := []struct {
+		name  string
+		files []string
+
+		wantRecovered int
+		// the content of every file afterwards, by name
+		wantFiles map[string]string
+	}{
+		{
+			name:      "nothing claimed",
+			files:     []string{"deadletter-1.jsonl"},
+			wantFiles: map[string]string{"deadletter-1.jsonl": "deadletter-1.jsonl"},
+		},
+		{
+			name:          "claimed files are handed back",
+			files:         []string{"deadletter-1.jsonl.redriving", "deadletter-2.jsonl.redriving", "deadletter-3.jsonl"},
+			wantRecovered: 2,
+			wantFiles: map[string]string{
+				"deadletter-1.jsonl": "deadletter-1.jsonl.redriving",
+				"deadletter-2.jsonl": "deadletter-2.jsonl.redriving",
+				"deadletter-3.jsonl": "deadletter-3.jsonl",
+			},
+		},
+		{
+			name:          "a file started anew under the claimed name is kept",
+			files:         []string{"deadletter-1.jsonl.redriving", "deadletter-1.jsonl"},
+			wantRecovered: 1,
+			wantFiles: map[string]string{
+				"deadletter-1.jsonl":           "deadletter-1.jsonl",
+				"deadletter-1-unclaimed.jsonl": "deadletter-1.jsonl.redriving",
+			},
+		},
+		{
+			name:      "unrelated files are left alone",
+			files:     []string{"notes.redriving", "deadletter-1.redriving"},
+			wantFiles: map[string]string{"notes.redriving": "notes.redriving", "deadletter-1.redriving": "deadletter-1.redriving"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			writeFiles(t, dir, tt.files...)
+
+			recovered, err := Recover(dir)
+			if err != nil {
+				t.Fatalf("recover: %v", err)
+			}
+
+			if recovered != tt.wantRecovered {
+				t.Fatalf("got %d recovered, want %d", recovered, tt.wantRecovered)
+			}
+
+			if got := dirContents(t, dir); !maps.Equal(got, tt.wantFiles) {
+				t.Fatalf("got files %v, want %v", got, tt.wantFiles)
+			}
+		})
+	}
+}
+
+func TestRecoveredFilesAreListed(t *testing.T) {
+	dir := t.TempDir()
+
+	writeFiles(t, dir, "deadletter-1.jsonl.redriving", "deadletter-2.jsonl")
+
+	if _, err := Recover(dir); err != nil {
+		t.Fatalf("recover: %v", err)
+	}
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatalf("files: %v", err)
+	}
+
+	want := []string{filepath.Join(dir, "deadletter-1.jsonl"), filepath.Join(dir, "deadletter-2.jsonl")}
+
+	if !slices.Equal(files, want) {
+		t.Fatalf("got %q, want %q", files, want)
+	}
+}
+
+// readAll returns the entries of every dead letter file in dir, oldest first
+func readAll(t *testing.T, dir string) [][]Entry {
+	t.Helper()
+
+	files, err := Files(dir)
+	if err != nil {
+		t.Fatalf("files: %v", err)
+	}
+
+	entries := [][]Entry{}
+
+	for _, file := range files {
+		fileEntries, err := readEntries(file)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+
+		entries = append(entries, fileEntries)
+	}
+
+	return entries
+}
+
+func TestWriter(t *testing.T) {
+	// every entry is written as a line of the same size, so the file size limit is given in entries
+	entry := Entry{Destination: "api", Mode: "json", LogKind: "deploy", LogCount: 1, Error: "rejected", Payload: []byte("payload")}
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lineSize := int64(len(line) + 1)
+
+	tests := []struct {
+		name           string
+		entriesPerFile int64
+		maxFiles       int
+		entries        int
+
+		// the number of entries in every file left, oldest first
+		wantFiles []int
+	}{
+		{
+			name:           "a single file",
+			entriesPerFile: 10,
+			entries:        3,
+			wantFiles:      []int{3},
+		},
+		{
+			name:           "rotated at the file size",
+			entriesPerFile: 2,
+			entries:        5,
+			wantFiles:      []int{2, 2, 1},
+		},
+		{
+			name:           "oldest files removed past the file limit",
+			entriesPerFile: 2,
+			maxFiles:       2,
+			entries:        7,
+			wantFiles:      []int{2, 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "deadletter")
+
+			w, err := NewWriter(dir, tt.entriesPerFile*lineSize, tt.maxFiles)
+			if err != nil {
+				t.Fatalf("new writer: %v", err)
+			}
+
+			for range tt.entries {
+				if err := w.Write(entry); err != nil {
+					t.Fatalf("write: %v", err)
+				}
+			}
+
+			files := readAll(t, dir)
+
+			got := make([]int, len(files))
+
+			for i, entries := range files {
+				got[i] = len(entries)
+			}
+
+			if !slices.Equal(got, tt.wantFiles) {
+				t.Fatalf("got files with %v entries, want %v", got, tt.wantFiles)
+			}
+
+			if files[0][0].Destination != entry.Destination || string(files[0][0].Payload) != string(entry.Payload) {
+				t.Fatalf("got entry %+v, want %+v", files[0][0], entry)
+			}
+		})
+	}
+}
+
+func TestClaim(t *testing.T) {
+	tests := []struct {
+		name string
+		// release or unclaim the claimed file
+		release bool
+
+		wantFiles int
+	}{
+		{name: "released", release: true, wantFiles: 1},
+		{name: "unclaimed", release: false, wantFiles: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			w, err := NewWriter(dir, 1<<20, 1)
+			if err != nil {
+				t.Fatalf("new writer: %v", err)
+			}
+
+			if err := w.Write(Entry{Destination: "api"}); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			files, err := Files(dir)
+			if err != nil {
+				t.Fatalf("files: %v", err)
+			}
+
+			entries, claimedPath, err := Claim(files[0])
+			if err != nil {
+				t.Fatalf("claim: %v", err)
+			}
+
+			if len(entries) != 1 || entries[0].Destination != "api" {
+				t.Fatalf("got entries %+v, want the written entry", entries)
+			}
+
+			// a new file is started, the claimed file is neither listed nor removed by the rotation of the writer
+			if err := w.Write(Entry{Destination: "worker"}); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			if _, err := os.Stat(claimedPath); err != nil {
+				t.Fatalf("claimed file: %v", err)
+			}
+
+			if tt.release {
+				err = Release(claimedPath)
+			} else {
+				err = Unclaim(claimedPath)
+			}
+
+			if err != nil {
+				t.Fatalf("release: %v", err)
+			}
+
+			if got := len(readAll(t, dir)); got != tt.wantFiles {
+				t.Fatalf("got %d files, want %d", got, tt.wantFiles)
+			}
+		})
+	}
+}
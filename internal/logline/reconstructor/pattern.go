@@ -0,0 +1,107 @@
+package reconstructor
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// date tokens of a pattern such as {date:yyyy.MM} mapped to their go layout
+var dateTokens = strings.NewReplacer(
+	"yyyy", "2006",
+	"MM", "01",
+	"dd", "02",
+	"HH", "15",
+)
+
+const defaultDateFormat = "yyyy.MM.dd"
+
+// Pattern renders strings such as index names from literal text, {<metadata key>} and {date} or {date:<format>} placeholders
+type Pattern struct {
+	parts []patternPart
+}
+
+type patternPart struct {
+	literal string
+	// the metadata key to insert, empty for literals and dates
+	metadataKey string
+	// the go layout of a date, empty for literals and metadata
+	dateLayout string
+}
+
+func ParsePattern(pattern string) (*Pattern, error) {
+	p := &Pattern{}
+
+	rest := pattern
+
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			p.parts = append(p.parts, patternPart{literal: rest})
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed placeholder in pattern: %s", pattern)
+		}
+
+		end += start
+
+		if start > 0 {
+			p.parts = append(p.parts, patternPart{literal: rest[:start]})
+		}
+
+		placeholder := strings.TrimSpace(rest[start+1 : end])
+
+		switch {
+		case placeholder == "":
+			return nil, fmt.Errorf("empty placeholder in pattern: %s", pattern)
+		case placeholder == "date":
+			p.parts = append(p.parts, patternPart{dateLayout: dateTokens.Replace(defaultDateFormat)})
+		case strings.HasPrefix(placeholder, "date:"):
+			p.parts = append(p.parts, patternPart{dateLayout: dateTokens.Replace(strings.TrimPrefix(placeholder, "date:"))})
+		default:
+			p.parts = append(p.parts, patternPart{metadataKey: placeholder})
+		}
+
+		rest = rest[end+1:]
+	}
+
+	return p, nil
+}
+
+// HasPlaceholders reports whether the rendered string can differ between logs
+func (p *Pattern) HasPlaceholders() bool {
+	for _, part := range p.parts {
+		if part.metadataKey != "" || part.dateLayout != "" {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Render returns the pattern filled in for a log, metadata values are passed through transform if it is not nil
+func (p *Pattern) Render(metadata map[string]string, timestamp time.Time, transform func(string) string) string {
+	rendered := strings.Builder{}
+
+	for _, part := range p.parts {
+		switch {
+		case part.metadataKey != "":
+			value := metadata[part.metadataKey]
+
+			if transform != nil {
+				value = transform(value)
+			}
+
+			rendered.WriteString(value)
+		case part.dateLayout != "":
+			rendered.WriteString(timestamp.UTC().Format(part.dateLayout))
+		default:
+			rendered.WriteString(part.literal)
+		}
+	}
+
+	return rendered.String()
+}
@@ -0,0 +1,123 @@
+package reconstruct_elasticsearch
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_json"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/tidwall/sjson"
+)
+
+// Bulk reconstructs logs into bulk api requests, every log is an action line followed by its document
+type Bulk struct {
+	index *reconstructor.Pattern
+	// data streams only accept the create action
+	action string
+}
+
+// New parses the index pattern, an empty pattern falls back to the default pattern for indices or data streams
+func New(pattern string, dataStream bool) (*Bulk, error) {
+	b := &Bulk{
+		action: "index",
+	}
+
+	if dataStream {
+		b.action = "create"
+	}
+
+	if strings.TrimSpace(pattern) == "" {
+		pattern = DefaultIndexPattern
+
+		if dataStream {
+			pattern = DefaultDataStreamPattern
+		}
+	}
+
+	index, err := reconstructor.ParsePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	b.index = index
+
+	return b, nil
+}
+
+// reconstruct multiple deployment logs into a bulk request
+func (b *Bulk) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	request := bytes.Buffer{}
+
+	for i := range logs {
+		document, err := reconstruct_json.EnvironmentLogsJsonLinesWithConfig(logs[i:i+1], reconstruct_json.Config{
+			TimestampAttribute: timestampAttribute,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp := cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp)
+
+		b.writeItem(&request, logs[i].Metadata, timestamp, document)
+	}
+
+	return request.Bytes(), nil
+}
+
+// reconstruct multiple http logs into a bulk request
+func (b *Bulk) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	request := bytes.Buffer{}
+
+	for i := range logs {
+		document, err := reconstruct_json.HttpLogsJsonLinesWithConfig(logs[i:i+1], reconstruct_json.Config{
+			TimestampAttribute: timestampAttribute,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		b.writeItem(&request, logs[i].Metadata, logs[i].Timestamp, document)
+	}
+
+	return request.Bytes(), nil
+}
+
+func (b *Bulk) writeItem(request *bytes.Buffer, metadata map[string]string, timestamp time.Time, document []byte) {
+	// index names must be lowercase and can not contain most symbols
+	index := strings.ToLower(b.index.Render(metadata, timestamp, util.SanitizeString))
+
+	action, _ := sjson.Set(`{}`, fmt.Sprintf("%s._index", b.action), index)
+
+	request.WriteString(action)
+	request.WriteByte('\n')
+	request.Write(document)
+	request.WriteByte('\n')
+}
+
+// Items returns a bulk request holding only the items at the given positions of the given bulk request
+func Items(request []byte, positions []int) []byte {
+	lines := bytes.SplitAfter(request, []byte{'\n'})
+	items := bytes.Buffer{}
+
+	for _, position := range positions {
+		if position < 0 || 2*position+1 >= len(lines) {
+			continue
+		}
+
+		items.Write(lines[2*position])
+		items.Write(lines[2*position+1])
+	}
+
+	return items.Bytes()
+}
+
+// Count returns the number of items in a bulk request
+func Count(request []byte) int {
+	return bytes.Count(request, []byte{'\n'}) / 2
+}
@@ -0,0 +1,99 @@
+package reconstruct_elasticsearch
+
+import (
+	"strings"
+	"testing"
+)
+
+// bulkRequest builds a bulk request with an action line and a document for every id
+func bulkRequest(ids ...string) []byte {
+	b := strings.Builder{}
+
+	for _, id := range ids {
+		b.WriteString(`{"index":{"_index":"logs"}}` + "\n")
+		b.WriteString(`{"id":"` + id + `"}` + "\n")
+	}
+
+	return []byte(b.String())
+}
+
+func TestItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		request   []byte
+		positions []int
+
+		want []byte
+	}{
+		{
+			name:      "failed items in the middle and at the end",
+			request:   bulkRequest("0", "1", "2", "3"),
+			positions: []int{1, 3},
+			want:      bulkRequest("1", "3"),
+		},
+		{
+			name:      "the first item",
+			request:   bulkRequest("0", "1", "2"),
+			positions: []int{0},
+			want:      bulkRequest("0"),
+		},
+		{
+			name:      "every item",
+			request:   bulkRequest("0", "1"),
+			positions: []int{0, 1},
+			want:      bulkRequest("0", "1"),
+		},
+		{
+			name:      "positions outside of the request are ignored",
+			request:   bulkRequest("0", "1"),
+			positions: []int{-1, 1, 2, 5},
+			want:      bulkRequest("1"),
+		},
+		{
+			name:      "no positions",
+			request:   bulkRequest("0", "1"),
+			positions: nil,
+			want:      []byte{},
+		},
+		{
+			name:      "narrowing twice",
+			request:   Items(bulkRequest("0", "1", "2", "3", "4"), []int{0, 2, 4}),
+			positions: []int{1},
+			want:      bulkRequest("2"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Items(tt.request, tt.positions)
+
+			if string(got) != string(tt.want) {
+				t.Fatalf("got request %q, want %q", got, tt.want)
+			}
+
+			if Count(got) != Count(tt.want) {
+				t.Fatalf("got %d items, want %d", Count(got), Count(tt.want))
+			}
+		})
+	}
+}
+
+func TestCount(t *testing.T) {
+	tests := []struct {
+		name    string
+		request []byte
+		want    int
+	}{
+		{name: "empty", request: nil, want: 0},
+		{name: "single item", request: bulkRequest("0"), want: 1},
+		{name: "multiple items", request: bulkRequest("0", "1", "2"), want: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Count(tt.request); got != tt.want {
+				t.Fatalf("got %d items, want %d", got, tt.want)
+			}
+		})
+	}
+}
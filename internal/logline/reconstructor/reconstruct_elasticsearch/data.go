@@ -0,0 +1,11 @@
+package reconstruct_elasticsearch
+
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html
+
+// escaped since a leading @ marks a modifier in sjson paths
+const timestampAttribute = `\@timestamp`
+
+const (
+	DefaultIndexPattern      = "railway-{log_type}-{date}"
+	DefaultDataStreamPattern = "logs-railway.{log_type}-default"
+)
@@ -0,0 +1,20 @@
+package reconstruct_gelf
+
+// https://go2docs.graylog.org/current/getting_in_log_data/gelf.html
+
+const version = "1.1"
+
+// the host of logs without a service name
+const defaultHost = "railway"
+
+// chunked udp messages start with these magic bytes, followed by an 8 byte message id, the sequence number and the sequence count
+var chunkMagic = []byte{0x1e, 0x0f}
+
+const (
+	// fits the typical internet mtu, larger chunks can be fragmented or dropped on the way
+	chunkSize       = 1420
+	chunkHeaderSize = 12
+
+	// receivers discard messages split into more chunks than this
+	maxChunks = 128
+)
@@ -0,0 +1,160 @@
+package reconstruct_gelf
+
+import (
+	"bytes"
+	"cmp"
+	"crypto/rand"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// GELF reconstructs logs into gelf messages, null byte terminated for socket transports or newline separated for http
+type GELF struct {
+	separator byte
+}
+
+// New returns a reconstructor for messages sent over a socket, framed with null bytes, or over http if framed is false
+func New(framed bool) *GELF {
+	if framed {
+		return &GELF{separator: 0}
+	}
+
+	return &GELF{separator: '\n'}
+}
+
+// reconstruct multiple deployment logs into gelf messages
+func (g *GELF) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	messages := bytes.Buffer{}
+
+	for i := range logs {
+		message := newMessage(
+			logs[i].Metadata,
+			cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp),
+			levelFromSeverity(logs[i].Log.Severity),
+			util.StripAnsi(logs[i].Log.Message),
+		)
+
+		for _, attribute := range logs[i].Log.Attributes {
+			message = setField(message, attribute.Key, gjson.Parse(attribute.Value))
+		}
+
+		g.write(&messages, message, i == len(logs)-1)
+	}
+
+	return messages.Bytes(), nil
+}
+
+// reconstruct multiple http logs into gelf messages
+func (g *GELF) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	messages := bytes.Buffer{}
+
+	for i := range logs {
+		log := gjson.ParseBytes(logs[i].Log)
+
+		message := newMessage(
+			logs[i].Metadata,
+			logs[i].Timestamp,
+			levelFromStatusCode(logs[i].StatusCode),
+			fmt.Sprintf("%s %s %d", log.Get("method").String(), logs[i].Path, logs[i].StatusCode),
+		)
+
+		log.ForEach(func(key, value gjson.Result) bool {
+			message = setField(message, key.String(), value)
+			return true
+		})
+
+		g.write(&messages, message, i == len(logs)-1)
+	}
+
+	return messages.Bytes(), nil
+}
+
+// write appends a message, null bytes terminate every message while newlines only separate them
+func (g *GELF) write(messages *bytes.Buffer, message string, last bool) {
+	messages.WriteString(message)
+
+	if g.separator == 0 || !last {
+		messages.WriteByte(g.separator)
+	}
+}
+
+// newMessage builds the gelf fields shared by both log types, the metadata is added as additional fields
+func newMessage(metadata map[string]string, timestamp time.Time, level int, text string) string {
+	message := `{}`
+
+	message, _ = sjson.Set(message, "version", version)
+	message, _ = sjson.Set(message, "host", cmp.Or(metadata["service_name"], defaultHost))
+
+	// the short message is the first line, the full message is only sent when there is more
+	shortMessage, _, multiline := strings.Cut(strings.TrimSpace(text), "\n")
+
+	message, _ = sjson.Set(message, "short_message", cmp.Or(strings.TrimSpace(shortMessage), "-"))
+
+	if multiline {
+		message, _ = sjson.Set(message, "full_message", text)
+	}
+
+	// seconds since the epoch with millisecond precision
+	message, _ = sjson.SetRaw(message, "timestamp", strconv.FormatFloat(float64(timestamp.UnixMilli())/1000, 'f', 3, 64))
+	message, _ = sjson.Set(message, "level", level)
+
+	for key, value := range metadata {
+		message = setField(message, key, gjson.Result{Type: gjson.String, Str: value})
+	}
+
+	return message
+}
+
+// Messages splits null byte terminated messages into the messages themselves
+func Messages(payload []byte) [][]byte {
+	messages := bytes.Split(payload, []byte{0})
+
+	// the final terminator leaves an empty element behind
+	if len(messages) > 0 && len(messages[len(messages)-1]) == 0 {
+		messages = messages[:len(messages)-1]
+	}
+
+	return messages
+}
+
+// Chunks splits a message into udp datagrams of at most chunkSize bytes, messages that fit are returned as a single datagram
+func Chunks(message []byte) ([][]byte, error) {
+	if len(message) <= chunkSize {
+		return [][]byte{message}, nil
+	}
+
+	dataSize := chunkSize - chunkHeaderSize
+	count := (len(message) + dataSize - 1) / dataSize
+
+	if count > maxChunks {
+		return nil, fmt.Errorf("gelf message of %d bytes needs %d chunks, more than the maximum of %d", len(message), count, maxChunks)
+	}
+
+	id := make([]byte, 8)
+	rand.Read(id)
+
+	chunks := make([][]byte, 0, count)
+
+	for i := range count {
+		data := message[i*dataSize : min((i+1)*dataSize, len(message))]
+
+		chunk := make([]byte, 0, chunkHeaderSize+len(data))
+		chunk = append(chunk, chunkMagic...)
+		chunk = append(chunk, id...)
+		chunk = append(chunk, byte(i), byte(count))
+		chunk = append(chunk, data...)
+
+		chunks = append(chunks, chunk)
+	}
+
+	return chunks, nil
+}
@@ -0,0 +1,271 @@
+package reconstruct_gelf
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/tidwall/gjson"
+)
+
+func TestEnvironmentLogs(t *testing.T) {
+	logs := []environment_logs.EnvironmentLogWithMetadata{
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123_000_000, time.UTC),
+				Message:   "\x1b[31mpanic: boom\x1b[0m\ngoroutine 1 [running]:",
+				Severity:  "error",
+				Attributes: []subscriptions.EnvironmentLogAttributes{
+					{Key: "attempt", Value: "3"},
+					{Key: "request", Value: `{"method":"GET","headers":{"x-id":"abc"}}`},
+				},
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{"service_name": "api", "deployment_id": "d-1"},
+		},
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+				Message:   "  ",
+				Severity:  "debug",
+			},
+		},
+	}
+
+	tests := []struct {
+		name   string
+		framed bool
+
+		wantSeparator string
+	}{
+		{name: "null byte terminated for sockets", framed: true, wantSeparator: "\x00"},
+		{name: "newline separated for http", framed: false, wantSeparator: "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload, err := New(tt.framed).EnvironmentLogs(logs)
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			messages := strings.Split(string(payload), tt.wantSeparator)
+
+			// null bytes terminate the last message as well, newlines only go between messages
+			if tt.framed {
+				if messages[len(messages)-1] != "" {
+					t.Fatalf("got %q, want the last message terminated", payload)
+				}
+
+				messages = messages[:len(messages)-1]
+			}
+
+			if len(messages) != len(logs) {
+				t.Fatalf("got %d messages, want %d", len(messages), len(logs))
+			}
+
+			first := gjson.Parse(messages[0])
+
+			for path, want := range map[string]string{
+				"version":               "1.1",
+				"host":                  "api",
+				"short_message":         "panic: boom",
+				"full_message":          "panic: boom\ngoroutine 1 [running]:",
+				"timestamp":             "1704164645.123",
+				"level":                 "3",
+				"_attempt":              "3",
+				"_request_method":       "GET",
+				`_request_headers_x-id`: "abc",
+				"_service_name":         "api",
+				"_deployment_id":        "d-1",
+			} {
+				if got := first.Get(path).String(); got != want {
+					t.Fatalf("got %s = %s, want %s", path, got, want)
+				}
+			}
+
+			second := gjson.Parse(messages[1])
+
+			for path, want := range map[string]string{
+				"host":          "railway",
+				"short_message": "-",
+				"level":         "7",
+			} {
+				if got := second.Get(path).String(); got != want {
+					t.Fatalf("got %s = %s, want %s", path, got, want)
+				}
+			}
+
+			if second.Get("full_message").Exists() {
+				t.Fatalf("got full_message in %s, want it left out of a single line message", messages[1])
+			}
+		})
+	}
+}
+
+func TestHttpLogs(t *testing.T) {
+	logs := []http_logs.DeploymentHttpLogWithMetadata{{
+		Timestamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Log:        json.RawMessage(`{"method":"POST","path":"/orders","httpStatus":503,"totalDuration":12}`),
+		Path:       "/orders",
+		StatusCode: 503,
+		Metadata:   http_logs.DeploymentHttpLogMetadata{"service_name": "api"},
+	}}
+
+	payload, err := New(true).HttpLogs(logs)
+	if err != nil {
+		t.Fatalf("reconstruct: %v", err)
+	}
+
+	message := gjson.ParseBytes(bytes.TrimSuffix(payload, []byte{0}))
+
+	for path, want := range map[string]string{
+		"short_message":  `"POST /orders 503"`,
+		"level":          "3",
+		"_httpStatus":    "503",
+		"_totalDuration": "12",
+		"_path":          `"/orders"`,
+	} {
+		if got := message.Get(path).Raw; got != want {
+			t.Fatalf("got %s = %s, want %s", path, got, want)
+		}
+	}
+}
+
+func TestSetField(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   string
+		value string
+
+		// the message with the field set
+		want string
+	}{
+		{name: "string", key: "user", value: `"alice"`, want: `{"_user":"alice"}`},
+		{name: "number", key: "attempt", value: `3.5`, want: `{"_attempt":3.5}`},
+		{name: "bool as its json text", key: "cached", value: `true`, want: `{"_cached":"true"}`},
+		{name: "array as its json text", key: "tags", value: `["a","b"]`, want: `{"_tags":"[\"a\",\"b\"]"}`},
+		{name: "null is left out", key: "parent", value: `null`, want: `{}`},
+		{name: "object is flattened", key: "error", value: `{"code":1,"cause":{"kind":"io"}}`, want: `{"_error_code":1,"_error_cause_kind":"io"}`},
+		{name: "invalid characters are replaced", key: "http status/code", value: `200`, want: `{"_http_status_code":200}`},
+		{name: "dots are kept", key: "k8s.pod", value: `"web-1"`, want: `{"_k8s.pod":"web-1"}`},
+		{name: "reserved id", key: "id", value: `"abc"`, want: `{"__id":"abc"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := setField(`{}`, tt.key, gjson.Parse(tt.value)); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMessages(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+		want    []string
+	}{
+		{name: "empty", payload: "", want: []string{}},
+		{name: "terminated", payload: "a\x00b\x00", want: []string{"a", "b"}},
+		{name: "unterminated last message", payload: "a\x00b", want: []string{"a", "b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := []string{}
+
+			for _, message := range Messages([]byte(tt.payload)) {
+				got = append(got, string(message))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunks(t *testing.T) {
+	dataSize := chunkSize - chunkHeaderSize
+
+	tests := []struct {
+		name string
+		size int
+
+		wantChunks int
+		wantErr    bool
+	}{
+		{name: "small message", size: 100, wantChunks: 1},
+		{name: "exactly one datagram", size: chunkSize, wantChunks: 1},
+		{name: "one byte over", size: chunkSize + 1, wantChunks: 2},
+		{name: "several chunks", size: dataSize*3 + 10, wantChunks: 4},
+		{name: "the most chunks", size: dataSize * maxChunks, wantChunks: maxChunks},
+		{name: "too many chunks", size: dataSize*maxChunks + 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := make([]byte, tt.size)
+
+			for i := range message {
+				message[i] = byte(i % 251)
+			}
+
+			chunks, err := Chunks(message)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if len(chunks) != tt.wantChunks {
+				t.Fatalf("got %d chunks, want %d", len(chunks), tt.wantChunks)
+			}
+
+			// a message that fits is sent as is, without a chunk header
+			if tt.wantChunks == 1 {
+				if !bytes.Equal(chunks[0], message) {
+					t.Fatal("got the message changed, want it sent as is")
+				}
+
+				return
+			}
+
+			reassembled := []byte{}
+
+			for i, chunk := range chunks {
+				if len(chunk) > chunkSize {
+					t.Fatalf("got chunk %d of %d bytes, want at most %d", i, len(chunk), chunkSize)
+				}
+
+				if !bytes.Equal(chunk[:2], chunkMagic) {
+					t.Fatalf("got chunk %d starting with %x, want the magic bytes %x", i, chunk[:2], chunkMagic)
+				}
+
+				if !bytes.Equal(chunk[2:10], chunks[0][2:10]) {
+					t.Fatalf("got message id %x in chunk %d, want %x", chunk[2:10], i, chunks[0][2:10])
+				}
+
+				if int(chunk[10]) != i || int(chunk[11]) != len(chunks) {
+					t.Fatalf("got sequence %d of %d in chunk %d, want %d of %d", chunk[10], chunk[11], i, i, len(chunks))
+				}
+
+				reassembled = append(reassembled, chunk[chunkHeaderSize:]...)
+			}
+
+			if !bytes.Equal(reassembled, message) {
+				t.Fatal("got the chunks reassembled into another message, want the original")
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package reconstruct_gelf
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// additional field names may only hold word characters, dots and dashes
+var invalidFieldCharacters = regexp.MustCompile(`[^\w.\-]`)
+
+func levelFromSeverity(severity string) int {
+	switch strings.ToLower(severity) {
+	case "debug":
+		return 7
+	case "warn", "warning":
+		return 4
+	case "error", "err":
+		return 3
+	case "fatal", "critical":
+		return 2
+	case "alert":
+		return 1
+	case "emergency":
+		return 0
+	default:
+		return 6 // info
+	}
+}
+
+func levelFromStatusCode(statusCode int64) int {
+	if statusCode >= 500 && statusCode <= 599 {
+		return 3 // error
+	}
+
+	if statusCode >= 400 && statusCode <= 499 {
+		return 4 // warning
+	}
+
+	return 6 // info
+}
+
+// setField sets an additional field, objects are flattened into one field per leaf joined by underscores
+func setField(message string, key string, value gjson.Result) string {
+	if value.IsObject() {
+		value.ForEach(func(k, v gjson.Result) bool {
+			message = setField(message, key+"_"+k.String(), v)
+			return true
+		})
+
+		return message
+	}
+
+	name := invalidFieldCharacters.ReplaceAllString(key, "_")
+
+	// _id is reserved by graylog
+	if name == "" || name == "id" {
+		name = "_" + name
+	}
+
+	// escaped since sjson treats dots in paths as nesting
+	path := strings.ReplaceAll("_"+name, ".", `\.`)
+
+	switch value.Type {
+	case gjson.Number:
+		message, _ = sjson.SetRaw(message, path, value.Raw)
+	case gjson.String:
+		message, _ = sjson.Set(message, path, value.Str)
+	case gjson.Null:
+	default:
+		// additional fields are strings or numbers, anything else is sent as its json text
+		message, _ = sjson.Set(message, path, value.Raw)
+	}
+
+	return message
+}
@@ -1,5 +1,11 @@
 package reconstruct_loki
 
-const lokiJSON string = `{"streams":[{"stream":{},"values": [[]]}]}`
+// https://grafana.com/docs/loki/latest/reference/loki-http-api/#ingest-logs
 
 var httpAttributesToSkip = []string{"timestamp", "path"}
+
+// loki rejects streams without labels, so logs missing every configured label are sent with this one
+const (
+	fallbackLabelName  = "job"
+	fallbackLabelValue = "railway"
+)
@@ -0,0 +1,108 @@
+package reconstruct_loki
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// https://github.com/grafana/loki/blob/main/pkg/push/push.proto
+
+func encodeProtobuf(streams []*stream) []byte {
+	request := []byte{}
+
+	for _, stream := range streams {
+		// PushRequest.streams
+		request = appendMessage(request, 1, func(b []byte) []byte {
+			// StreamAdapter.labels
+			b = protowire.AppendTag(b, 1, protowire.BytesType)
+			b = protowire.AppendString(b, labelString(stream.labels))
+
+			for _, entry := range stream.entries {
+				// StreamAdapter.entries
+				b = appendMessage(b, 2, func(b []byte) []byte {
+					return appendEntry(b, entry)
+				})
+			}
+
+			return b
+		})
+	}
+
+	return request
+}
+
+func appendEntry(b []byte, e entry) []byte {
+	// EntryAdapter.timestamp, a google.protobuf.Timestamp
+	b = appendMessage(b, 1, func(b []byte) []byte {
+		b = protowire.AppendTag(b, 1, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(e.timestamp.Unix()))
+
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		return protowire.AppendVarint(b, uint64(e.timestamp.Nanosecond()))
+	})
+
+	// EntryAdapter.line
+	b = protowire.AppendTag(b, 2, protowire.BytesType)
+	b = protowire.AppendString(b, e.line)
+
+	for _, pair := range e.structuredMetadata {
+		// EntryAdapter.structuredMetadata
+		b = appendMessage(b, 3, func(b []byte) []byte {
+			b = protowire.AppendTag(b, 1, protowire.BytesType)
+			b = protowire.AppendString(b, pair.name)
+
+			b = protowire.AppendTag(b, 2, protowire.BytesType)
+			return protowire.AppendString(b, pair.value)
+		})
+	}
+
+	return b
+}
+
+// appendMessage appends an embedded message, encoded by the given function, as a length delimited field
+func appendMessage(b []byte, num protowire.Number, encode func(b []byte) []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, encode(nil))
+}
+
+func encodeJson(streams []*stream) ([]byte, error) {
+	jsonStreams := make([]any, 0, len(streams))
+
+	for _, stream := range streams {
+		labels := make(map[string]string, len(stream.labels))
+
+		for _, label := range stream.labels {
+			labels[label.name] = label.value
+		}
+
+		values := make([]any, 0, len(stream.entries))
+
+		for _, entry := range stream.entries {
+			value := []any{strconv.FormatInt(entry.timestamp.UnixNano(), 10), entry.line}
+
+			if len(entry.structuredMetadata) > 0 {
+				structuredMetadata := make(map[string]string, len(entry.structuredMetadata))
+
+				for _, pair := range entry.structuredMetadata {
+					structuredMetadata[pair.name] = pair.value
+				}
+
+				value = append(value, structuredMetadata)
+			}
+
+			values = append(values, value)
+		}
+
+		jsonStreams = append(jsonStreams, map[string]any{
+			"stream": labels,
+			"values": values,
+		})
+	}
+
+	return json.Marshal(map[string]any{
+		"streams": jsonStreams,
+	})
+}
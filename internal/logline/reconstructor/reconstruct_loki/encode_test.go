@@ -0,0 +1,178 @@
+package reconstruct_loki
+
+import (
+	"testing"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// decode splits a protobuf message into the values of its fields by field number, in the order they were encoded.
+// Varint values are returned as uint64, length delimited values as []byte.
+func decode(t *testing.T, b []byte) map[protowire.Number][]any {
+	t.Helper()
+
+	fields := map[protowire.Number][]any{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		var value any
+
+		switch typ {
+		case protowire.VarintType:
+			value, n = protowire.ConsumeVarint(b)
+		case protowire.BytesType:
+			value, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d for field %d", typ, num)
+		}
+
+		if n < 0 {
+			t.Fatalf("invalid value for field %d: %v", num, protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		fields[num] = append(fields[num], value)
+	}
+
+	return fields
+}
+
+func TestEncodeProtobuf(t *testing.T) {
+	tests := []struct {
+		name    string
+		streams []*stream
+
+		wantLabels []string
+		// the entries of every stream
+		wantEntries [][]entry
+	}{
+		{
+			name: "single entry",
+			streams: []*stream{{
+				labels:  []labelPair{{name: "service_name", value: "api"}},
+				entries: []entry{{timestamp: time.Unix(1700000000, 123456789), line: "hello"}},
+			}},
+			wantLabels: []string{`{service_name="api"}`},
+			wantEntries: [][]entry{
+				{{timestamp: time.Unix(1700000000, 123456789), line: "hello"}},
+			},
+		},
+		{
+			name: "structured metadata",
+			streams: []*stream{{
+				labels: []labelPair{{name: "service_name", value: "api"}, {name: "environment_name", value: "prod"}},
+				entries: []entry{{
+					timestamp:          time.Unix(1700000000, 0),
+					line:               "hello",
+					structuredMetadata: []labelPair{{name: "deployment_id", value: "d1"}, {name: "replica", value: "r1"}},
+				}},
+			}},
+			wantLabels: []string{`{service_name="api", environment_name="prod"}`},
+			wantEntries: [][]entry{{{
+				timestamp:          time.Unix(1700000000, 0),
+				line:               "hello",
+				structuredMetadata: []labelPair{{name: "deployment_id", value: "d1"}, {name: "replica", value: "r1"}},
+			}}},
+		},
+		{
+			name: "escaped label values",
+			streams: []*stream{{
+				labels:  []labelPair{{name: "service_name", value: `a "quoted" \ name`}},
+				entries: []entry{{timestamp: time.Unix(1, 0), line: "hello"}},
+			}},
+			wantLabels:  []string{`{service_name="a \"quoted\" \\ name"}`},
+			wantEntries: [][]entry{{{timestamp: time.Unix(1, 0), line: "hello"}}},
+		},
+		{
+			name: "multiple streams keep their order",
+			streams: []*stream{
+				{
+					labels:  []labelPair{{name: "service_name", value: "api"}},
+					entries: []entry{{timestamp: time.Unix(1, 0), line: "a"}, {timestamp: time.Unix(2, 0), line: "b"}},
+				},
+				{
+					labels:  []labelPair{{name: "service_name", value: "worker"}},
+					entries: []entry{{timestamp: time.Unix(3, 0), line: "c"}},
+				},
+			},
+			wantLabels: []string{`{service_name="api"}`, `{service_name="worker"}`},
+			wantEntries: [][]entry{
+				{{timestamp: time.Unix(1, 0), line: "a"}, {timestamp: time.Unix(2, 0), line: "b"}},
+				{{timestamp: time.Unix(3, 0), line: "c"}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			streams := decode(t, encodeProtobuf(tt.streams))[1]
+
+			if len(streams) != len(tt.wantLabels) {
+				t.Fatalf("got %d streams, want %d", len(streams), len(tt.wantLabels))
+			}
+
+			for i, s := range streams {
+				stream := decode(t, s.([]byte))
+
+				if got := string(stream[1][0].([]byte)); got != tt.wantLabels[i] {
+					t.Fatalf("stream %d: got labels %s, want %s", i, got, tt.wantLabels[i])
+				}
+
+				if len(stream[2]) != len(tt.wantEntries[i]) {
+					t.Fatalf("stream %d: got %d entries, want %d", i, len(stream[2]), len(tt.wantEntries[i]))
+				}
+
+				for j, e := range stream[2] {
+					checkEntry(t, decode(t, e.([]byte)), tt.wantEntries[i][j])
+				}
+			}
+		})
+	}
+}
+
+func checkEntry(t *testing.T, got map[protowire.Number][]any, want entry) {
+	t.Helper()
+
+	timestamp := decode(t, got[1][0].([]byte))
+
+	var seconds, nanos uint64
+
+	// a field that is left out decodes as zero
+	if len(timestamp[1]) > 0 {
+		seconds = timestamp[1][0].(uint64)
+	}
+
+	if len(timestamp[2]) > 0 {
+		nanos = timestamp[2][0].(uint64)
+	}
+
+	if gotTimestamp := time.Unix(int64(seconds), int64(nanos)); !gotTimestamp.Equal(want.timestamp) {
+		t.Fatalf("got timestamp %s, want %s", gotTimestamp, want.timestamp)
+	}
+
+	if line := string(got[2][0].([]byte)); line != want.line {
+		t.Fatalf("got line %q, want %q", line, want.line)
+	}
+
+	if len(got[3]) != len(want.structuredMetadata) {
+		t.Fatalf("got %d structured metadata pairs, want %d", len(got[3]), len(want.structuredMetadata))
+	}
+
+	for i, p := range got[3] {
+		pair := decode(t, p.([]byte))
+
+		name, value := string(pair[1][0].([]byte)), string(pair[2][0].([]byte))
+
+		if name != want.structuredMetadata[i].name || value != want.structuredMetadata[i].value {
+			t.Fatalf("got structured metadata %s=%s, want %s=%s", name, value, want.structuredMetadata[i].name, want.structuredMetadata[i].value)
+		}
+	}
+}
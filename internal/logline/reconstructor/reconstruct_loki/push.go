@@ -0,0 +1,185 @@
+package reconstruct_loki
+
+import (
+	"cmp"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/klauspost/compress/s2"
+)
+
+// Push reconstructs logs into push requests, logs are grouped into one stream per set of labels
+type Push struct {
+	labels   []string
+	protobuf bool
+}
+
+type labelPair struct {
+	name  string
+	value string
+}
+
+type entry struct {
+	timestamp time.Time
+	line      string
+
+	structuredMetadata []labelPair
+}
+
+type stream struct {
+	labels  []labelPair
+	entries []entry
+}
+
+// New returns a reconstructor indexing the given metadata keys as labels, the rest of the metadata is sent as structured metadata.
+//
+// Requests are encoded as snappy compressed protobuf if protobuf is set, as json otherwise.
+func New(labels []string, protobuf bool) *Push {
+	return &Push{
+		labels:   labels,
+		protobuf: protobuf,
+	}
+}
+
+// reconstruct multiple deployment logs into a push request
+func (p *Push) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	grouper := newStreamGrouper()
+
+	for i := range logs {
+		labels, structuredMetadata := p.splitMetadata(logs[i].Metadata)
+
+		for j := range logs[i].Log.Attributes {
+			structuredMetadata = appendAttributes(structuredMetadata, jsonToAttributes(logs[i].Log.Attributes[j].Key, logs[i].Log.Attributes[j].Value))
+		}
+
+		grouper.add(labels, entry{
+			timestamp:          cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp),
+			line:               util.StripAnsi(logs[i].Log.Message),
+			structuredMetadata: structuredMetadata,
+		})
+	}
+
+	return p.encode(grouper.streams)
+}
+
+// reconstruct multiple http logs into a push request
+func (p *Push) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	grouper := newStreamGrouper()
+
+	for i := range logs {
+		labels, structuredMetadata := p.splitMetadata(logs[i].Metadata)
+
+		attributes := jsonBytesToAttributes("", logs[i].Log)
+
+		for _, key := range httpAttributesToSkip {
+			delete(attributes, key)
+		}
+
+		grouper.add(labels, entry{
+			timestamp:          logs[i].Timestamp,
+			line:               logs[i].Path,
+			structuredMetadata: appendAttributes(structuredMetadata, attributes),
+		})
+	}
+
+	return p.encode(grouper.streams)
+}
+
+func (p *Push) encode(streams []*stream) ([]byte, error) {
+	if p.protobuf {
+		// loki expects the raw snappy block format rather than the framed stream format
+		return s2.EncodeSnappy(nil, encodeProtobuf(streams)), nil
+	}
+
+	return encodeJson(streams)
+}
+
+// splitMetadata returns the configured labels, sorted by name, and the rest of the metadata as structured metadata
+func (p *Push) splitMetadata(metadata map[string]string) ([]labelPair, []labelPair) {
+	labels := []labelPair{}
+	structuredMetadata := []labelPair{}
+
+	for _, key := range slices.Sorted(maps.Keys(metadata)) {
+		pair := labelPair{name: key, value: metadata[key]}
+
+		if slices.Contains(p.labels, key) {
+			if pair.value != "" {
+				labels = append(labels, pair)
+			}
+
+			continue
+		}
+
+		structuredMetadata = append(structuredMetadata, pair)
+	}
+
+	if len(labels) == 0 {
+		labels = append(labels, labelPair{name: fallbackLabelName, value: fallbackLabelValue})
+	}
+
+	return labels, structuredMetadata
+}
+
+// appendAttributes appends flattened attributes as structured metadata, sorted by name
+func appendAttributes(structuredMetadata []labelPair, attributes map[string]string) []labelPair {
+	for _, key := range slices.Sorted(maps.Keys(attributes)) {
+		structuredMetadata = append(structuredMetadata, labelPair{name: key, value: attributes[key]})
+	}
+
+	return structuredMetadata
+}
+
+// streamGrouper groups entries by their labels, keeping the order in which streams were first seen
+type streamGrouper struct {
+	index   map[string]int
+	streams []*stream
+}
+
+func newStreamGrouper() *streamGrouper {
+	return &streamGrouper{
+		index: map[string]int{},
+	}
+}
+
+func (g *streamGrouper) add(labels []labelPair, e entry) {
+	key := labelString(labels)
+
+	i, ok := g.index[key]
+	if !ok {
+		i = len(g.streams)
+		g.index[key] = i
+		g.streams = append(g.streams, &stream{labels: labels})
+	}
+
+	g.streams[i].entries = append(g.streams[i].entries, e)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labelString returns labels in the prometheus text format, such as {service_name="api"}, which is how the protobuf format carries them
+func labelString(labels []labelPair) string {
+	b := strings.Builder{}
+
+	b.WriteByte('{')
+
+	for i, label := range labels {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+
+		b.WriteString(label.name)
+		b.WriteString(`="`)
+		b.WriteString(labelValueEscaper.Replace(label.value))
+		b.WriteByte('"')
+	}
+
+	b.WriteByte('}')
+
+	return b.String()
+}
@@ -0,0 +1,13 @@
+package reconstruct_newrelic
+
+// https://docs.newrelic.com/docs/logs/log-api/introduction-log-api/#json-content
+
+// metadata shared by every log of a project, environment and service, sent once per group in the common attributes
+var commonMetadataKeys = []string{
+	"project_id",
+	"project_name",
+	"environment_id",
+	"environment_name",
+	"service_id",
+	"service_name",
+}
@@ -0,0 +1,31 @@
+package reconstruct_newrelic
+
+import (
+	"cmp"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/tidwall/sjson"
+)
+
+// reconstruct multiple deployment logs into the detailed json format, grouped by their project, environment and service
+func EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	grouper := newLogGrouper()
+
+	for i := range logs {
+		log := `{}`
+
+		log, _ = sjson.Set(log, "timestamp", cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp).UnixMilli())
+		log, _ = sjson.Set(log, "message", util.StripAnsi(logs[i].Log.Message))
+		log, _ = sjson.Set(log, "attributes.level", logs[i].Log.Severity)
+
+		for _, attribute := range logs[i].Log.Attributes {
+			log, _ = sjson.SetRaw(log, "attributes."+attribute.Key, attribute.Value)
+		}
+
+		grouper.add(logs[i].Metadata, log)
+	}
+
+	return grouper.payload(), nil
+}
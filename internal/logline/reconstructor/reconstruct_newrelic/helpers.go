@@ -0,0 +1,73 @@
+package reconstruct_newrelic
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/tidwall/sjson"
+)
+
+// logGroup holds the logs that share their common attributes
+type logGroup struct {
+	common string
+	logs   []string
+}
+
+// logGrouper groups logs by their common attributes, keeping the order in which groups were first seen
+type logGrouper struct {
+	index  map[string]int
+	groups []*logGroup
+}
+
+func newLogGrouper() *logGrouper {
+	return &logGrouper{
+		index: map[string]int{},
+	}
+}
+
+// add adds a log to the group of its common metadata, the log's own metadata is added to its attributes
+func (g *logGrouper) add(metadata map[string]string, log string) {
+	common := `{}`
+	key := strings.Builder{}
+
+	for _, commonKey := range commonMetadataKeys {
+		if value, ok := metadata[commonKey]; ok {
+			common, _ = sjson.Set(common, commonKey, value)
+
+			key.WriteString(commonKey)
+			key.WriteByte(0)
+			key.WriteString(value)
+			key.WriteByte(0)
+		}
+	}
+
+	for metadataKey, value := range metadata {
+		if !slices.Contains(commonMetadataKeys, metadataKey) {
+			log, _ = sjson.Set(log, "attributes."+metadataKey, value)
+		}
+	}
+
+	i, ok := g.index[key.String()]
+	if !ok {
+		i = len(g.groups)
+		g.index[key.String()] = i
+		g.groups = append(g.groups, &logGroup{common: common})
+	}
+
+	g.groups[i].logs = append(g.groups[i].logs, log)
+}
+
+// payload returns the groups in the detailed json format, [{"common":{"attributes":{...}},"logs":[...]}]
+func (g *logGrouper) payload() []byte {
+	payload := `[]`
+
+	for _, group := range g.groups {
+		element := `{}`
+		element, _ = sjson.SetRaw(element, "common.attributes", group.common)
+		element, _ = sjson.SetRaw(element, "logs", "["+strings.Join(group.logs, ",")+"]")
+
+		payload, _ = sjson.SetRaw(payload, "-1", element)
+	}
+
+	return []byte(payload)
+}
@@ -0,0 +1,45 @@
+package reconstruct_newrelic
+
+import (
+	"fmt"
+
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// reconstruct multiple http logs into the detailed json format, grouped by their project, environment and service
+func HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	grouper := newLogGrouper()
+
+	for i := range logs {
+		fields := gjson.ParseBytes(logs[i].Log)
+
+		log := `{}`
+
+		log, _ = sjson.Set(log, "timestamp", logs[i].Timestamp.UnixMilli())
+		log, _ = sjson.Set(log, "message", fmt.Sprintf("%s %s %d", fields.Get("method").String(), logs[i].Path, logs[i].StatusCode))
+		log, _ = sjson.Set(log, "attributes.level", levelFromStatusCode(logs[i].StatusCode))
+
+		fields.ForEach(func(key, value gjson.Result) bool {
+			log, _ = sjson.SetRaw(log, "attributes."+key.String(), value.Raw)
+			return true
+		})
+
+		grouper.add(logs[i].Metadata, log)
+	}
+
+	return grouper.payload(), nil
+}
+
+func levelFromStatusCode(statusCode int64) string {
+	if statusCode >= 500 && statusCode <= 599 {
+		return "error"
+	}
+
+	if statusCode >= 400 && statusCode <= 499 {
+		return "warn"
+	}
+
+	return "info"
+}
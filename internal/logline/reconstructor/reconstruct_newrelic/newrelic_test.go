@@ -0,0 +1,128 @@
+package reconstruct_newrelic
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+func TestEnvironmentLogs(t *testing.T) {
+	timestamp := time.Date(2024, 1, 2, 3, 4, 5, 123_000_000, time.UTC)
+
+	// the metadata of a log of the given service, with its own deployment instance
+	metadata := func(service string, instance string) environment_logs.EnvironmentLogMetadata {
+		return environment_logs.EnvironmentLogMetadata{
+			"project_name":           "shop",
+			"environment_name":       "production",
+			"service_name":           service,
+			"deployment_instance_id": instance,
+		}
+	}
+
+	tests := []struct {
+		name string
+		logs []environment_logs.EnvironmentLogWithMetadata
+		want string
+	}{
+		{
+			name: "no logs",
+			logs: nil,
+			want: `[]`,
+		},
+		{
+			name: "a single log",
+			logs: []environment_logs.EnvironmentLogWithMetadata{{
+				Log: subscriptions.EnvironmentLog{
+					Timestamp: timestamp,
+					Message:   "\x1b[31mfailed\x1b[0m",
+					Severity:  "error",
+					Attributes: []subscriptions.EnvironmentLogAttributes{
+						{Key: "attempt", Value: "3"},
+						{Key: "request", Value: `{"method":"GET"}`},
+					},
+				},
+				Metadata: metadata("api", "i-1"),
+			}},
+			want: `[{"common":{"attributes":{"project_name":"shop","environment_name":"production","service_name":"api"}},` +
+				`"logs":[{"timestamp":1704164645123,"message":"failed","attributes":{"level":"error","attempt":3,"request":{"method":"GET"},"deployment_instance_id":"i-1"}}]}]`,
+		},
+		{
+			name: "grouped by service in the order first seen",
+			logs: []environment_logs.EnvironmentLogWithMetadata{
+				{Log: subscriptions.EnvironmentLog{Timestamp: timestamp, Message: "one", Severity: "info"}, Metadata: metadata("worker", "i-1")},
+				{Log: subscriptions.EnvironmentLog{Timestamp: timestamp, Message: "two", Severity: "info"}, Metadata: metadata("api", "i-2")},
+				{Log: subscriptions.EnvironmentLog{Timestamp: timestamp, Message: "three", Severity: "warn"}, Metadata: metadata("worker", "i-3")},
+			},
+			want: `[{"common":{"attributes":{"project_name":"shop","environment_name":"production","service_name":"worker"}},` +
+				`"logs":[{"timestamp":1704164645123,"message":"one","attributes":{"level":"info","deployment_instance_id":"i-1"}},` +
+				`{"timestamp":1704164645123,"message":"three","attributes":{"level":"warn","deployment_instance_id":"i-3"}}]},` +
+				`{"common":{"attributes":{"project_name":"shop","environment_name":"production","service_name":"api"}},` +
+				`"logs":[{"timestamp":1704164645123,"message":"two","attributes":{"level":"info","deployment_instance_id":"i-2"}}]}]`,
+		},
+		{
+			name: "a missing common key is its own group",
+			logs: []environment_logs.EnvironmentLogWithMetadata{
+				{Log: subscriptions.EnvironmentLog{Timestamp: timestamp, Message: "one", Severity: "info"}, Metadata: environment_logs.EnvironmentLogMetadata{"project_name": "shop"}},
+				{Log: subscriptions.EnvironmentLog{Timestamp: timestamp, Message: "two", Severity: "info"}, Metadata: environment_logs.EnvironmentLogMetadata{}},
+			},
+			want: `[{"common":{"attributes":{"project_name":"shop"}},"logs":[{"timestamp":1704164645123,"message":"one","attributes":{"level":"info"}}]},` +
+				`{"common":{"attributes":{}},"logs":[{"timestamp":1704164645123,"message":"two","attributes":{"level":"info"}}]}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EnvironmentLogs(tt.logs)
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("got\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHttpLogs(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int64
+		want       string
+	}{
+		{name: "success", statusCode: 200, want: "info"},
+		{name: "redirect", statusCode: 302, want: "info"},
+		{name: "client error", statusCode: 404, want: "warn"},
+		{name: "server error", statusCode: 503, want: "error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := []http_logs.DeploymentHttpLogWithMetadata{{
+				Timestamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Log:        json.RawMessage(`{"method":"GET","totalDuration":12}`),
+				Path:       "/orders",
+				StatusCode: tt.statusCode,
+				Metadata:   http_logs.DeploymentHttpLogMetadata{"service_name": "api"},
+			}}
+
+			got, err := HttpLogs(logs)
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			want := `[{"common":{"attributes":{"service_name":"api"}},"logs":[{"timestamp":1704164645000,` +
+				`"message":"GET /orders ` + strconv.FormatInt(tt.statusCode, 10) + `",` +
+				`"attributes":{"level":"` + tt.want + `","method":"GET","totalDuration":12}}]}]`
+
+			if string(got) != want {
+				t.Fatalf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package reconstruct_otlp
+
+// https://opentelemetry.io/docs/specs/otlp/
+
+const scopeName = "locomotive"
+
+// the module version stamped into the build, empty for development builds so the scope carries no version
+var scopeVersion = buildVersion()
+
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+const (
+	severityNumberDebug int32 = 5
+	severityNumberInfo  int32 = 9
+	severityNumberWarn  int32 = 13
+	severityNumberError int32 = 17
+	severityNumberFatal int32 = 21
+)
+
+// metadata keys mapped to resource attributes, following the semantic conventions where one exists
+//
+// https://opentelemetry.io/docs/specs/semconv/resource/
+var resourceAttributeKeys = []struct {
+	metadata  string
+	attribute string
+}{
+	{"service_name", "service.name"},
+	{"deployment_instance_id", "service.instance.id"},
+	{"environment_name", "deployment.environment"},
+	{"deployment_id", "deployment.id"},
+	{"project_name", "railway.project.name"},
+	{"project_id", "railway.project.id"},
+	{"environment_id", "railway.environment.id"},
+	{"service_id", "railway.service.id"},
+}
+
+// keys of a http log mapped to their semantic convention attribute names, the original keys are kept as well
+//
+// https://opentelemetry.io/docs/specs/semconv/http/http-spans/
+var httpAttributeKeys = map[string]string{
+	"method": "http.request.method",
+	"path":   "url.path",
+	"host":   "server.address",
+	"srcIp":  "client.address",
+}
@@ -0,0 +1,224 @@
+package reconstruct_otlp
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/logs/v1/logs.proto
+
+func encodeProtobuf(resources []*resourceLogs) []byte {
+	request := []byte{}
+
+	for _, resource := range resources {
+		// ExportLogsServiceRequest.resource_logs
+		request = appendMessage(request, 1, func(b []byte) []byte {
+			// ResourceLogs.resource
+			b = appendMessage(b, 1, func(b []byte) []byte {
+				return appendKeyValues(b, 1, resource.resource)
+			})
+
+			// ResourceLogs.scope_logs
+			return appendMessage(b, 2, func(b []byte) []byte {
+				b = appendMessage(b, 1, func(b []byte) []byte {
+					b = appendString(b, 1, scopeName)
+					return appendString(b, 2, scopeVersion)
+				})
+
+				for _, record := range resource.records {
+					b = appendMessage(b, 2, func(b []byte) []byte {
+						return appendLogRecord(b, record)
+					})
+				}
+
+				return b
+			})
+		})
+	}
+
+	return request
+}
+
+func appendLogRecord(b []byte, record logRecord) []byte {
+	b = protowire.AppendTag(b, 1, protowire.Fixed64Type)
+	b = protowire.AppendFixed64(b, record.timeUnixNano)
+
+	b = protowire.AppendTag(b, 2, protowire.VarintType)
+	b = protowire.AppendVarint(b, uint64(record.severityNumber))
+
+	b = appendString(b, 3, record.severityText)
+
+	b = appendMessage(b, 5, func(b []byte) []byte {
+		return appendAnyValue(b, record.body)
+	})
+
+	b = appendKeyValues(b, 6, record.attributes)
+
+	// observed_time_unix_nano, left out when unknown
+	if record.observedTimeUnixNano != 0 {
+		b = protowire.AppendTag(b, 11, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, record.observedTimeUnixNano)
+	}
+
+	return b
+}
+
+func appendKeyValues(b []byte, num protowire.Number, keyValues []keyValue) []byte {
+	for _, keyValue := range keyValues {
+		b = appendMessage(b, num, func(b []byte) []byte {
+			b = appendString(b, 1, keyValue.key)
+
+			return appendMessage(b, 2, func(b []byte) []byte {
+				return appendAnyValue(b, keyValue.value)
+			})
+		})
+	}
+
+	return b
+}
+
+func appendAnyValue(b []byte, value any) []byte {
+	switch value := value.(type) {
+	case string:
+		b = protowire.AppendTag(b, 1, protowire.BytesType)
+		b = protowire.AppendString(b, value)
+	case bool:
+		b = protowire.AppendTag(b, 2, protowire.VarintType)
+		b = protowire.AppendVarint(b, protowire.EncodeBool(value))
+	case int64:
+		b = protowire.AppendTag(b, 3, protowire.VarintType)
+		b = protowire.AppendVarint(b, uint64(value))
+	case float64:
+		b = protowire.AppendTag(b, 4, protowire.Fixed64Type)
+		b = protowire.AppendFixed64(b, math.Float64bits(value))
+	case []any:
+		b = appendMessage(b, 5, func(b []byte) []byte {
+			for _, v := range value {
+				b = appendMessage(b, 1, func(b []byte) []byte {
+					return appendAnyValue(b, v)
+				})
+			}
+
+			return b
+		})
+	case []keyValue:
+		b = appendMessage(b, 6, func(b []byte) []byte {
+			return appendKeyValues(b, 1, value)
+		})
+	}
+
+	return b
+}
+
+func appendString(b []byte, num protowire.Number, value string) []byte {
+	if value == "" {
+		return b
+	}
+
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendString(b, value)
+}
+
+// appendMessage appends an embedded message, encoded by the given function, as a length delimited field
+func appendMessage(b []byte, num protowire.Number, encode func(b []byte) []byte) []byte {
+	b = protowire.AppendTag(b, num, protowire.BytesType)
+
+	return protowire.AppendBytes(b, encode(nil))
+}
+
+// https://opentelemetry.io/docs/specs/otlp/#json-protobuf-encoding
+
+func encodeJson(resources []*resourceLogs) ([]byte, error) {
+	resourceLogs := make([]any, 0, len(resources))
+
+	for _, resource := range resources {
+		records := make([]any, 0, len(resource.records))
+
+		for _, record := range resource.records {
+			jsonRecord := map[string]any{
+				"timeUnixNano":   strconv.FormatUint(record.timeUnixNano, 10),
+				"severityNumber": record.severityNumber,
+				"severityText":   record.severityText,
+				"body":           jsonAnyValue(record.body),
+				"attributes":     jsonKeyValues(record.attributes),
+			}
+
+			if record.observedTimeUnixNano != 0 {
+				jsonRecord["observedTimeUnixNano"] = strconv.FormatUint(record.observedTimeUnixNano, 10)
+			}
+
+			records = append(records, jsonRecord)
+		}
+
+		resourceLogs = append(resourceLogs, map[string]any{
+			"resource": map[string]any{
+				"attributes": jsonKeyValues(resource.resource),
+			},
+			"scopeLogs": []any{
+				map[string]any{
+					"scope":      jsonScope(),
+					"logRecords": records,
+				},
+			},
+		})
+	}
+
+	return json.Marshal(map[string]any{
+		"resourceLogs": resourceLogs,
+	})
+}
+
+func jsonScope() map[string]any {
+	scope := map[string]any{
+		"name": scopeName,
+	}
+
+	if scopeVersion != "" {
+		scope["version"] = scopeVersion
+	}
+
+	return scope
+}
+
+func jsonKeyValues(keyValues []keyValue) []any {
+	values := make([]any, 0, len(keyValues))
+
+	for _, keyValue := range keyValues {
+		values = append(values, map[string]any{
+			"key":   keyValue.key,
+			"value": jsonAnyValue(keyValue.value),
+		})
+	}
+
+	return values
+}
+
+func jsonAnyValue(value any) map[string]any {
+	switch value := value.(type) {
+	case string:
+		return map[string]any{"stringValue": value}
+	case bool:
+		return map[string]any{"boolValue": value}
+	case int64:
+		// 64 bit integers are encoded as strings in otlp json
+		return map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		return map[string]any{"doubleValue": value}
+	case []any:
+		values := make([]any, 0, len(value))
+
+		for _, v := range value {
+			values = append(values, jsonAnyValue(v))
+		}
+
+		return map[string]any{"arrayValue": map[string]any{"values": values}}
+	case []keyValue:
+		return map[string]any{"kvlistValue": map[string]any{"values": jsonKeyValues(value)}}
+	default:
+		return map[string]any{}
+	}
+}
@@ -0,0 +1,238 @@
+package reconstruct_otlp
+
+import (
+	"math"
+	"testing"
+
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// field is a single decoded protobuf field, varint and fixed64 values are kept in number, length delimited values in bytes
+type field struct {
+	typ    protowire.Type
+	number uint64
+	bytes  []byte
+}
+
+// decode splits a protobuf message into its fields by field number, in the order they were encoded
+func decode(t *testing.T, b []byte) map[protowire.Number][]field {
+	t.Helper()
+
+	fields := map[protowire.Number][]field{}
+
+	for len(b) > 0 {
+		num, typ, n := protowire.ConsumeTag(b)
+		if n < 0 {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		f := field{typ: typ}
+
+		switch typ {
+		case protowire.VarintType:
+			f.number, n = protowire.ConsumeVarint(b)
+		case protowire.Fixed64Type:
+			f.number, n = protowire.ConsumeFixed64(b)
+		case protowire.BytesType:
+			f.bytes, n = protowire.ConsumeBytes(b)
+		default:
+			t.Fatalf("unexpected wire type %d for field %d", typ, num)
+		}
+
+		if n < 0 {
+			t.Fatalf("invalid value for field %d: %v", num, protowire.ParseError(n))
+		}
+
+		b = b[n:]
+
+		fields[num] = append(fields[num], f)
+	}
+
+	return fields
+}
+
+// only returns the single field with the given number, failing the test if there is not exactly one
+func only(t *testing.T, fields map[protowire.Number][]field, num protowire.Number) field {
+	t.Helper()
+
+	if len(fields[num]) != 1 {
+		t.Fatalf("got %d fields with number %d, want 1", len(fields[num]), num)
+	}
+
+	return fields[num][0]
+}
+
+func TestEncodeProtobufAnyValue(t *testing.T) {
+	tests := []struct {
+		name  string
+		value any
+		check func(t *testing.T, value map[protowire.Number][]field)
+	}{
+		{
+			name:  "string",
+			value: "hello",
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				if got := string(only(t, value, 1).bytes); got != "hello" {
+					t.Fatalf("got string value %q, want %q", got, "hello")
+				}
+			},
+		},
+		{
+			name:  "bool",
+			value: true,
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				if got := protowire.DecodeBool(only(t, value, 2).number); !got {
+					t.Fatalf("got bool value %t, want true", got)
+				}
+			},
+		},
+		{
+			name:  "negative int",
+			value: int64(-42),
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				if got := int64(only(t, value, 3).number); got != -42 {
+					t.Fatalf("got int value %d, want -42", got)
+				}
+			},
+		},
+		{
+			name:  "double",
+			value: 1.5,
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				f := only(t, value, 4)
+
+				if f.typ != protowire.Fixed64Type || math.Float64frombits(f.number) != 1.5 {
+					t.Fatalf("got double value %v of wire type %d, want 1.5 as fixed64", math.Float64frombits(f.number), f.typ)
+				}
+			},
+		},
+		{
+			name:  "array",
+			value: []any{"a", int64(1)},
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				values := decode(t, only(t, value, 5).bytes)[1]
+
+				if len(values) != 2 {
+					t.Fatalf("got %d array values, want 2", len(values))
+				}
+
+				if got := string(only(t, decode(t, values[0].bytes), 1).bytes); got != "a" {
+					t.Fatalf("got first array value %q, want %q", got, "a")
+				}
+
+				if got := only(t, decode(t, values[1].bytes), 3).number; got != 1 {
+					t.Fatalf("got second array value %d, want 1", got)
+				}
+			},
+		},
+		{
+			name:  "key value list",
+			value: []keyValue{{key: "k", value: "v"}},
+			check: func(t *testing.T, value map[protowire.Number][]field) {
+				kv := decode(t, only(t, decode(t, only(t, value, 6).bytes), 1).bytes)
+
+				if got := string(only(t, kv, 1).bytes); got != "k" {
+					t.Fatalf("got key %q, want %q", got, "k")
+				}
+
+				if got := string(only(t, decode(t, only(t, kv, 2).bytes), 1).bytes); got != "v" {
+					t.Fatalf("got value %q, want %q", got, "v")
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.check(t, decode(t, appendAnyValue(nil, tt.value)))
+		})
+	}
+}
+
+func TestEncodeProtobuf(t *testing.T) {
+	tests := []struct {
+		name   string
+		record logRecord
+	}{
+		{
+			name: "with observed time",
+			record: logRecord{
+				timeUnixNano:         1700000000123456789,
+				observedTimeUnixNano: 1700000000999999999,
+				severityNumber:       severityNumberWarn,
+				severityText:         "warn",
+				body:                 "something happened",
+				attributes:           []keyValue{{key: "attempt", value: int64(2)}},
+			},
+		},
+		{
+			name: "without observed time",
+			record: logRecord{
+				timeUnixNano:   1700000000123456789,
+				severityNumber: severityNumberError,
+				severityText:   "error",
+				body:           "something failed",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := encodeProtobuf([]*resourceLogs{{
+				resource: []keyValue{{key: "service.name", value: "api"}},
+				records:  []logRecord{tt.record},
+			}})
+
+			resourceLogs := decode(t, only(t, decode(t, payload), 1).bytes)
+
+			resource := decode(t, only(t, resourceLogs, 1).bytes)
+			attribute := decode(t, only(t, resource, 1).bytes)
+
+			if got := string(only(t, attribute, 1).bytes); got != "service.name" {
+				t.Fatalf("got resource attribute %q, want %q", got, "service.name")
+			}
+
+			scopeLogs := decode(t, only(t, resourceLogs, 2).bytes)
+
+			if got := string(only(t, decode(t, only(t, scopeLogs, 1).bytes), 1).bytes); got != scopeName {
+				t.Fatalf("got scope name %q, want %q", got, scopeName)
+			}
+
+			record := decode(t, only(t, scopeLogs, 2).bytes)
+
+			if f := only(t, record, 1); f.typ != protowire.Fixed64Type || f.number != tt.record.timeUnixNano {
+				t.Fatalf("got time %d of wire type %d, want %d as fixed64", f.number, f.typ, tt.record.timeUnixNano)
+			}
+
+			if got := int32(only(t, record, 2).number); got != tt.record.severityNumber {
+				t.Fatalf("got severity number %d, want %d", got, tt.record.severityNumber)
+			}
+
+			if got := string(only(t, record, 3).bytes); got != tt.record.severityText {
+				t.Fatalf("got severity text %q, want %q", got, tt.record.severityText)
+			}
+
+			if got := string(only(t, decode(t, only(t, record, 5).bytes), 1).bytes); got != tt.record.body {
+				t.Fatalf("got body %q, want %q", got, tt.record.body)
+			}
+
+			if got := len(record[6]); got != len(tt.record.attributes) {
+				t.Fatalf("got %d attributes, want %d", got, len(tt.record.attributes))
+			}
+
+			if tt.record.observedTimeUnixNano == 0 {
+				if len(record[11]) != 0 {
+					t.Fatal("got an observed time, want it left out")
+				}
+
+				return
+			}
+
+			if got := only(t, record, 11).number; got != tt.record.observedTimeUnixNano {
+				t.Fatalf("got observed time %d, want %d", got, tt.record.observedTimeUnixNano)
+			}
+		})
+	}
+}
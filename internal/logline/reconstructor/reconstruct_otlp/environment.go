@@ -0,0 +1,46 @@
+package reconstruct_otlp
+
+import (
+	"cmp"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/util"
+)
+
+// reconstruct multiple deployment logs into a protobuf encoded ExportLogsServiceRequest
+func EnvironmentLogsProtobuf(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	return encodeProtobuf(environmentLogResources(logs)), nil
+}
+
+// reconstruct multiple deployment logs into a json encoded ExportLogsServiceRequest
+func EnvironmentLogsJson(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	return encodeJson(environmentLogResources(logs))
+}
+
+func environmentLogResources(logs []environment_logs.EnvironmentLogWithMetadata) []*resourceLogs {
+	grouper := newResourceGrouper()
+
+	for i := range logs {
+		attributes := make([]keyValue, 0, len(logs[i].Log.Attributes)+1)
+
+		for _, attribute := range logs[i].Log.Attributes {
+			attributes = append(attributes, keyValue{key: attribute.Key, value: rawValue(attribute.Value)})
+		}
+
+		if logType := logs[i].Metadata["log_type"]; logType != "" {
+			attributes = append(attributes, keyValue{key: "railway.log.type", value: logType})
+		}
+
+		grouper.add(logs[i].Metadata, logRecord{
+			timeUnixNano:         uint64(cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp).UnixNano()),
+			observedTimeUnixNano: unixNano(logs[i].ReceivedAt),
+			severityNumber:       severityNumber(logs[i].Log.Severity),
+			severityText:         logs[i].Log.Severity,
+			body:                 util.StripAnsi(logs[i].Log.Message),
+			attributes:           attributes,
+		})
+	}
+
+	return grouper.resources
+}
@@ -0,0 +1,177 @@
+package reconstruct_otlp
+
+import (
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// the values of an AnyValue are held as string, bool, int64, float64, []any, []keyValue or nil for an empty value
+
+type keyValue struct {
+	key   string
+	value any
+}
+
+type logRecord struct {
+	timeUnixNano         uint64
+	observedTimeUnixNano uint64 // when locomotive received the log, zero if unknown
+	severityNumber       int32
+	severityText         string
+	body                 any
+	attributes           []keyValue
+}
+
+type resourceLogs struct {
+	resource []keyValue
+	records  []logRecord
+}
+
+// resourceGrouper groups log records by their resource attributes, keeping the order in which resources were first seen
+type resourceGrouper struct {
+	index     map[string]int
+	resources []*resourceLogs
+}
+
+func newResourceGrouper() *resourceGrouper {
+	return &resourceGrouper{
+		index: map[string]int{},
+	}
+}
+
+func (g *resourceGrouper) add(metadata map[string]string, record logRecord) {
+	resource := resourceAttributes(metadata)
+
+	key := strings.Builder{}
+
+	for _, attribute := range resource {
+		key.WriteString(attribute.key)
+		key.WriteByte(0)
+		key.WriteString(attribute.value.(string))
+		key.WriteByte(0)
+	}
+
+	i, ok := g.index[key.String()]
+	if !ok {
+		i = len(g.resources)
+		g.index[key.String()] = i
+		g.resources = append(g.resources, &resourceLogs{resource: resource})
+	}
+
+	g.resources[i].records = append(g.resources[i].records, record)
+}
+
+func resourceAttributes(metadata map[string]string) []keyValue {
+	attributes := []keyValue{}
+
+	for _, key := range resourceAttributeKeys {
+		value := metadata[key.metadata]
+
+		// service.name is required by most backends, fall back to the service id when the name is unknown
+		if key.attribute == "service.name" && value == "" {
+			value = metadata["service_id"]
+		}
+
+		if value == "" {
+			continue
+		}
+
+		attributes = append(attributes, keyValue{key: key.attribute, value: value})
+	}
+
+	return attributes
+}
+
+func severityNumber(severity string) int32 {
+	switch strings.ToLower(severity) {
+	case "debug":
+		return severityNumberDebug
+	case "warn", "warning":
+		return severityNumberWarn
+	case "error", "err":
+		return severityNumberError
+	case "fatal":
+		return severityNumberFatal
+	default:
+		return severityNumberInfo
+	}
+}
+
+func severityFromStatusCode(statusCode int64) (int32, string) {
+	switch {
+	case statusCode >= 500:
+		return severityNumberError, "error"
+	case statusCode >= 400:
+		return severityNumberWarn, "warn"
+	default:
+		return severityNumberInfo, "info"
+	}
+}
+
+// rawValue converts a raw json value into an AnyValue, values that are not valid json are kept as strings
+func rawValue(raw string) any {
+	if !gjson.Valid(raw) {
+		return raw
+	}
+
+	return jsonValue(gjson.Parse(raw))
+}
+
+func jsonValue(result gjson.Result) any {
+	switch result.Type {
+	case gjson.String:
+		return result.Str
+	case gjson.True:
+		return true
+	case gjson.False:
+		return false
+	case gjson.Number:
+		if i, err := strconv.ParseInt(result.Raw, 10, 64); err == nil {
+			return i
+		}
+
+		return result.Num
+	case gjson.JSON:
+		if result.IsArray() {
+			values := []any{}
+
+			for _, value := range result.Array() {
+				values = append(values, jsonValue(value))
+			}
+
+			return values
+		}
+
+		values := []keyValue{}
+
+		result.ForEach(func(key, value gjson.Result) bool {
+			values = append(values, keyValue{key: key.String(), value: jsonValue(value)})
+			return true
+		})
+
+		return values
+	default:
+		return nil
+	}
+}
+
+func buildVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "(devel)" {
+		return ""
+	}
+
+	return info.Main.Version
+}
+
+// unixNano returns zero for the zero time rather than a negative number of nanoseconds
+func unixNano(t time.Time) uint64 {
+	if t.IsZero() {
+		return 0
+	}
+
+	return uint64(t.UnixNano())
+}
@@ -0,0 +1,53 @@
+package reconstruct_otlp
+
+import (
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/tidwall/gjson"
+)
+
+// reconstruct multiple http logs into a protobuf encoded ExportLogsServiceRequest
+func HttpLogsProtobuf(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	return encodeProtobuf(httpLogResources(logs)), nil
+}
+
+// reconstruct multiple http logs into a json encoded ExportLogsServiceRequest
+func HttpLogsJson(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	return encodeJson(httpLogResources(logs))
+}
+
+func httpLogResources(logs []http_logs.DeploymentHttpLogWithMetadata) []*resourceLogs {
+	grouper := newResourceGrouper()
+
+	for i := range logs {
+		attributes := []keyValue{}
+
+		gjson.ParseBytes(logs[i].Log).ForEach(func(key, value gjson.Result) bool {
+			attributes = append(attributes, keyValue{key: key.String(), value: jsonValue(value)})
+
+			if attribute, ok := httpAttributeKeys[key.String()]; ok {
+				attributes = append(attributes, keyValue{key: attribute, value: jsonValue(value)})
+			}
+
+			return true
+		})
+
+		attributes = append(attributes, keyValue{key: "http.response.status_code", value: logs[i].StatusCode})
+
+		if logType := logs[i].Metadata["log_type"]; logType != "" {
+			attributes = append(attributes, keyValue{key: "railway.log.type", value: logType})
+		}
+
+		severityNumber, severityText := severityFromStatusCode(logs[i].StatusCode)
+
+		grouper.add(logs[i].Metadata, logRecord{
+			timeUnixNano:         uint64(logs[i].Timestamp.UnixNano()),
+			observedTimeUnixNano: unixNano(logs[i].ReceivedAt),
+			severityNumber:       severityNumber,
+			severityText:         severityText,
+			body:                 logs[i].Path,
+			attributes:           attributes,
+		})
+	}
+
+	return grouper.resources
+}
@@ -0,0 +1,13 @@
+package reconstruct_splunk
+
+// https://docs.splunk.com/Documentation/Splunk/latest/Data/FormateventsforHTTPEventCollector
+
+// HTTP Event Collector endpoints, relative to the collector's base url
+const (
+	EventPath = "/services/collector/event"
+	RawPath   = "/services/collector/raw"
+	AckPath   = "/services/collector/ack"
+)
+
+// the raw endpoint has no envelope to carry the log time in, so it is added to the json object for splunk's timestamp extraction
+const rawTimestampAttribute = "timestamp"
@@ -0,0 +1,120 @@
+package reconstruct_splunk
+
+import (
+	"bytes"
+	"cmp"
+	"strconv"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_json"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/tidwall/sjson"
+)
+
+// HEC reconstructs logs into http event collector requests, either concatenated event objects or json lines for the raw endpoint
+type HEC struct {
+	host       *reconstructor.Pattern
+	source     *reconstructor.Pattern
+	sourcetype *reconstructor.Pattern
+	index      *reconstructor.Pattern
+
+	raw bool
+}
+
+// New parses the event metadata patterns, an empty pattern leaves the field to the defaults of the collector's token
+func New(host, source, sourcetype, index string, raw bool) (*HEC, error) {
+	h := &HEC{
+		raw: raw,
+	}
+
+	for _, field := range []struct {
+		pattern string
+		target  **reconstructor.Pattern
+	}{
+		{host, &h.host},
+		{source, &h.source},
+		{sourcetype, &h.sourcetype},
+		{index, &h.index},
+	} {
+		pattern, err := reconstructor.ParsePattern(field.pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		*field.target = pattern
+	}
+
+	return h, nil
+}
+
+// reconstruct multiple deployment logs into a collector request
+func (h *HEC) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	if h.raw {
+		return reconstruct_json.EnvironmentLogsJsonLinesWithConfig(logs, reconstruct_json.Config{
+			TimestampAttribute: rawTimestampAttribute,
+		})
+	}
+
+	request := bytes.Buffer{}
+
+	for i := range logs {
+		event, err := reconstruct_json.EnvironmentLogsJsonLines(logs[i : i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		timestamp := cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp)
+
+		request.Write(h.event(logs[i].Metadata, timestamp, event))
+	}
+
+	return request.Bytes(), nil
+}
+
+// reconstruct multiple http logs into a collector request
+func (h *HEC) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	if h.raw {
+		return reconstruct_json.HttpLogsJsonLinesWithConfig(logs, reconstruct_json.Config{
+			TimestampAttribute: rawTimestampAttribute,
+		})
+	}
+
+	request := bytes.Buffer{}
+
+	for i := range logs {
+		event, err := reconstruct_json.HttpLogsJsonLines(logs[i : i+1])
+		if err != nil {
+			return nil, err
+		}
+
+		request.Write(h.event(logs[i].Metadata, logs[i].Timestamp, event))
+	}
+
+	return request.Bytes(), nil
+}
+
+// event wraps a json object in the collector's event envelope, the collector accepts any number of envelopes concatenated in one request
+func (h *HEC) event(metadata map[string]string, timestamp time.Time, object []byte) []byte {
+	// epoch seconds with millisecond precision
+	event, _ := sjson.SetRaw(`{}`, "time", strconv.FormatFloat(float64(timestamp.UnixMilli())/1000, 'f', 3, 64))
+
+	for _, field := range []struct {
+		key     string
+		pattern *reconstructor.Pattern
+	}{
+		{"host", h.host},
+		{"source", h.source},
+		{"sourcetype", h.sourcetype},
+		{"index", h.index},
+	} {
+		if value := field.pattern.Render(metadata, timestamp, nil); value != "" {
+			event, _ = sjson.Set(event, field.key, value)
+		}
+	}
+
+	event, _ = sjson.SetRaw(event, "event", string(object))
+
+	return []byte(event)
+}
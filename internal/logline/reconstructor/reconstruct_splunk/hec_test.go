@@ -0,0 +1,194 @@
+package reconstruct_splunk
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/tidwall/gjson"
+)
+
+// objects decodes every json object in a request, whether concatenated or on their own lines
+func objects(t *testing.T, request []byte) []gjson.Result {
+	t.Helper()
+
+	decoder := json.NewDecoder(bytes.NewReader(request))
+
+	results := []gjson.Result{}
+
+	for {
+		object := json.RawMessage{}
+
+		if err := decoder.Decode(&object); errors.Is(err, io.EOF) {
+			return results
+		} else if err != nil {
+			t.Fatalf("decode %s: %v", request, err)
+		}
+
+		results = append(results, gjson.ParseBytes(object))
+	}
+}
+
+func TestEnvironmentLogs(t *testing.T) {
+	logs := []environment_logs.EnvironmentLogWithMetadata{
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123_000_000, time.UTC),
+				Message:   "first",
+				Severity:  "info",
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{"service_name": "api", "project_name": "shop", "log_type": "deployment"},
+		},
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+				Message:   "second",
+				Severity:  "error",
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{"service_name": "worker", "project_name": "shop", "log_type": "deployment"},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		host       string
+		source     string
+		sourcetype string
+		index      string
+		raw        bool
+
+		// the fields wanted in every object, an empty value means the field is wanted absent
+		want [][]field
+	}{
+		{
+			name:       "event envelopes",
+			host:       "{project_name}",
+			source:     "{service_name}",
+			sourcetype: "railway:{log_type}",
+			index:      "main",
+			want: [][]field{
+				{{"time", "1704164645.123"}, {"host", "shop"}, {"source", "api"}, {"sourcetype", "railway:deployment"}, {"index", "main"}, {"event.message", "first"}},
+				{{"time", "1704164646.000"}, {"host", "shop"}, {"source", "worker"}, {"sourcetype", "railway:deployment"}, {"index", "main"}, {"event.message", "second"}},
+			},
+		},
+		{
+			name:   "empty patterns are left to the token defaults",
+			source: "{service_name}",
+			want: [][]field{
+				{{"host", ""}, {"source", "api"}, {"sourcetype", ""}, {"index", ""}, {"event.severity", "info"}},
+				{{"host", ""}, {"source", "worker"}, {"sourcetype", ""}, {"index", ""}, {"event.severity", "error"}},
+			},
+		},
+		{
+			name:   "a placeholder rendering empty omits the field",
+			source: "{missing}",
+			want: [][]field{
+				{{"source", ""}, {"event.message", "first"}},
+				{{"source", ""}, {"event.message", "second"}},
+			},
+		},
+		{
+			name:  "raw json lines",
+			index: "main",
+			raw:   true,
+			want: [][]field{
+				{{"message", "first"}, {"timestamp", "2024-01-02T03:04:05.123Z"}, {"event", ""}, {"index", ""}},
+				{{"message", "second"}, {"timestamp", "2024-01-02T03:04:06Z"}, {"event", ""}, {"index", ""}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hec, err := New(tt.host, tt.source, tt.sourcetype, tt.index, tt.raw)
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+
+			request, err := hec.EnvironmentLogs(logs)
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			if tt.raw && bytes.Count(request, []byte("\n")) != len(logs)-1 {
+				t.Fatalf("got %s, want one line per log", request)
+			}
+
+			checkObjects(t, objects(t, request), tt.want)
+		})
+	}
+}
+
+func TestHttpLogs(t *testing.T) {
+	logs := []http_logs.DeploymentHttpLogWithMetadata{{
+		Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Log:       json.RawMessage(`{"method":"GET","path":"/health","httpStatus":200}`),
+		Metadata:  http_logs.DeploymentHttpLogMetadata{"service_name": "api", "log_type": "http"},
+	}}
+
+	hec, err := New("", "{service_name}", "railway:{log_type}", "", false)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	request, err := hec.HttpLogs(logs)
+	if err != nil {
+		t.Fatalf("reconstruct: %v", err)
+	}
+
+	checkObjects(t, objects(t, request), [][]field{
+		{{"time", "1704164645.000"}, {"source", "api"}, {"sourcetype", "railway:http"}, {"host", ""}, {"event.path", "/health"}},
+	})
+}
+
+func TestNew(t *testing.T) {
+	if _, err := New("{project_name", "", "", "", false); err == nil || !strings.Contains(err.Error(), "unclosed placeholder") {
+		t.Fatalf("got error %v, want the unclosed placeholder rejected", err)
+	}
+}
+
+// field is a gjson path and the value wanted at it
+type field struct {
+	path  string
+	value string
+}
+
+func checkObjects(t *testing.T, got []gjson.Result, want [][]field) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d objects, want %d", len(got), len(want))
+	}
+
+	for i := range want {
+		for _, f := range want[i] {
+			result := got[i].Get(f.path)
+
+			if f.value == "" {
+				if result.Exists() {
+					t.Fatalf("got %s = %s in object %d, want it absent", f.path, result.Raw, i)
+				}
+
+				continue
+			}
+
+			// numbers are compared as written, to check the precision of the time
+			value := result.String()
+
+			if result.Type == gjson.Number {
+				value = result.Raw
+			}
+
+			if value != f.value {
+				t.Fatalf("got %s = %q in object %d, want %q", f.path, value, i, f.value)
+			}
+		}
+	}
+}
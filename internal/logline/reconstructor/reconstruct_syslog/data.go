@@ -0,0 +1,31 @@
+package reconstruct_syslog
+
+// https://datatracker.ietf.org/doc/html/rfc5424
+// https://datatracker.ietf.org/doc/html/rfc3164
+
+// sd-ids with an @ need an enterprise number, railway has none registered so the example number reserved by RFC 5612 is used
+const (
+	metadataSdId   = "metadata@32473"
+	attributesSdId = "attributes@32473"
+	httpSdId       = "http@32473"
+)
+
+// field length limits of RFC 5424 section 6
+const (
+	maxHostnameLength = 255
+	maxAppNameLength  = 48
+	maxProcIdLength   = 128
+	maxMsgIdLength    = 32
+	maxSdNameLength   = 32
+
+	// RFC 3164 section 4.1.3
+	maxTagLength = 32
+)
+
+const nilValue = "-"
+
+// RFC 5424 allows at most 6 digits of fractional seconds
+const rfc5424TimeLayout = "2006-01-02T15:04:05.000000Z07:00"
+
+// RFC 3164 timestamps carry neither a year nor a zone, they are always written in UTC
+const rfc3164TimeLayout = "Jan _2 15:04:05"
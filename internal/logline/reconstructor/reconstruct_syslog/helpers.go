@@ -0,0 +1,110 @@
+package reconstruct_syslog
+
+import (
+	"strings"
+)
+
+func severityFromLevel(severity string) int {
+	switch strings.ToLower(severity) {
+	case "debug":
+		return 7
+	case "warn", "warning":
+		return 4
+	case "error", "err":
+		return 3
+	case "fatal", "critical":
+		return 2
+	case "alert":
+		return 1
+	case "emergency":
+		return 0
+	default:
+		return 6 // info
+	}
+}
+
+func severityFromStatusCode(statusCode int64) int {
+	if statusCode >= 500 && statusCode <= 599 {
+		return 3 // error
+	}
+
+	if statusCode >= 400 && statusCode <= 499 {
+		return 4 // warning
+	}
+
+	return 6 // info
+}
+
+// header returns a header field with everything outside of printable us-ascii removed and cut to the max length, or the nil value if empty
+func header(value string, maxLength int) string {
+	value = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 {
+			return -1
+		}
+
+		return r
+	}, value)
+
+	if len(value) > maxLength {
+		value = value[:maxLength]
+	}
+
+	if value == "" {
+		return nilValue
+	}
+
+	return value
+}
+
+// sdName returns a structured data param name without the characters RFC 5424 forbids in it
+func sdName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if r < 33 || r > 126 || r == '=' || r == ']' || r == '"' {
+			return -1
+		}
+
+		return r
+	}, name)
+
+	if len(name) > maxSdNameLength {
+		name = name[:maxSdNameLength]
+	}
+
+	return name
+}
+
+var sdValueEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`)
+
+// sdParam is a single name and value pair of a structured data element
+type sdParam struct {
+	name  string
+	value string
+}
+
+// writeSdElement writes a structured data element, elements without params are left out
+func writeSdElement(b *strings.Builder, id string, params []sdParam) {
+	written := false
+
+	for _, param := range params {
+		name := sdName(param.name)
+		if name == "" {
+			continue
+		}
+
+		if !written {
+			b.WriteByte('[')
+			b.WriteString(id)
+			written = true
+		}
+
+		b.WriteByte(' ')
+		b.WriteString(name)
+		b.WriteString(`="`)
+		b.WriteString(sdValueEscaper.Replace(param.value))
+		b.WriteByte('"')
+	}
+
+	if written {
+		b.WriteByte(']')
+	}
+}
@@ -0,0 +1,205 @@
+package reconstruct_syslog
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+// Syslog reconstructs logs into syslog messages, framed with octet counting as described in RFC 6587 section 3.4.1
+type Syslog struct {
+	rfc3164  bool
+	facility int
+
+	hostname *reconstructor.Pattern
+	appName  *reconstructor.Pattern
+}
+
+// message is a single log before it is formatted
+type message struct {
+	severity  int
+	timestamp time.Time
+	metadata  map[string]string
+	msg       string
+
+	// structured data elements besides the metadata, keyed by their sd-id
+	elements map[string][]sdParam
+}
+
+// New parses the hostname and app-name patterns, messages follow RFC 5424 unless rfc3164 is set
+func New(rfc3164 bool, facility int, hostname string, appName string) (*Syslog, error) {
+	s := &Syslog{
+		rfc3164:  rfc3164,
+		facility: facility,
+	}
+
+	var err error
+
+	if s.hostname, err = reconstructor.ParsePattern(hostname); err != nil {
+		return nil, err
+	}
+
+	if s.appName, err = reconstructor.ParsePattern(appName); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// reconstruct multiple deployment logs into octet counted syslog messages
+func (s *Syslog) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	frames := bytes.Buffer{}
+
+	for i := range logs {
+		attributes := make([]sdParam, 0, len(logs[i].Log.Attributes))
+
+		for _, attribute := range logs[i].Log.Attributes {
+			attributes = append(attributes, sdParam{name: attribute.Key, value: gjson.Parse(attribute.Value).String()})
+		}
+
+		s.writeFrame(&frames, message{
+			severity:  severityFromLevel(logs[i].Log.Severity),
+			timestamp: cmp.Or(reconstructor.TryExtractTimestamp(logs[i]), logs[i].Log.Timestamp),
+			metadata:  logs[i].Metadata,
+			msg:       util.StripAnsi(logs[i].Log.Message),
+			elements: map[string][]sdParam{
+				attributesSdId: attributes,
+			},
+		})
+	}
+
+	return frames.Bytes(), nil
+}
+
+// reconstruct multiple http logs into octet counted syslog messages
+func (s *Syslog) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	frames := bytes.Buffer{}
+
+	for i := range logs {
+		log := gjson.ParseBytes(logs[i].Log)
+
+		fields := []sdParam{}
+
+		log.ForEach(func(key, value gjson.Result) bool {
+			fields = append(fields, sdParam{name: key.String(), value: value.String()})
+			return true
+		})
+
+		s.writeFrame(&frames, message{
+			severity:  severityFromStatusCode(logs[i].StatusCode),
+			timestamp: logs[i].Timestamp,
+			metadata:  logs[i].Metadata,
+			msg:       fmt.Sprintf("%s %s %d", log.Get("method").String(), logs[i].Path, logs[i].StatusCode),
+			elements: map[string][]sdParam{
+				httpSdId: fields,
+			},
+		})
+	}
+
+	return frames.Bytes(), nil
+}
+
+func (s *Syslog) writeFrame(frames *bytes.Buffer, m message) {
+	var formatted string
+
+	if s.rfc3164 {
+		formatted = s.formatRFC3164(m)
+	} else {
+		formatted = s.formatRFC5424(m)
+	}
+
+	frames.WriteString(strconv.Itoa(len(formatted)))
+	frames.WriteByte(' ')
+	frames.WriteString(formatted)
+}
+
+// <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID STRUCTURED-DATA MSG
+func (s *Syslog) formatRFC5424(m message) string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s %s ",
+		s.facility*8+m.severity,
+		m.timestamp.UTC().Format(rfc5424TimeLayout),
+		header(s.hostname.Render(m.metadata, m.timestamp, util.SanitizeString), maxHostnameLength),
+		header(s.appName.Render(m.metadata, m.timestamp, util.SanitizeString), maxAppNameLength),
+		header(m.metadata["deployment_instance_id"], maxProcIdLength),
+		header(m.metadata["log_type"], maxMsgIdLength),
+	)
+
+	metadata := make([]sdParam, 0, len(m.metadata))
+
+	for _, key := range slices.Sorted(maps.Keys(m.metadata)) {
+		metadata = append(metadata, sdParam{name: key, value: m.metadata[key]})
+	}
+
+	sd := strings.Builder{}
+
+	writeSdElement(&sd, metadataSdId, metadata)
+
+	for _, id := range slices.Sorted(maps.Keys(m.elements)) {
+		writeSdElement(&sd, id, m.elements[id])
+	}
+
+	if sd.Len() == 0 {
+		b.WriteString(nilValue)
+	} else {
+		b.WriteString(sd.String())
+	}
+
+	if m.msg != "" {
+		b.WriteByte(' ')
+		b.WriteString(m.msg)
+	}
+
+	return b.String()
+}
+
+// <PRI>TIMESTAMP HOSTNAME TAG: MSG, RFC 3164 has no structured data so only the message is sent
+func (s *Syslog) formatRFC3164(m message) string {
+	tag := s.appName.Render(m.metadata, m.timestamp, util.SanitizeString)
+
+	if len(tag) > maxTagLength {
+		tag = tag[:maxTagLength]
+	}
+
+	return fmt.Sprintf("<%d>%s %s %s: %s",
+		s.facility*8+m.severity,
+		m.timestamp.UTC().Format(rfc3164TimeLayout),
+		header(s.hostname.Render(m.metadata, m.timestamp, util.SanitizeString), maxHostnameLength),
+		cmp.Or(tag, "railway"),
+		m.msg,
+	)
+}
+
+// Frames splits octet counted messages into the messages themselves, such as for sending every message in its own datagram
+func Frames(payload []byte) ([][]byte, error) {
+	messages := [][]byte{}
+
+	for len(payload) > 0 {
+		length, rest, ok := bytes.Cut(payload, []byte{' '})
+		if !ok {
+			return nil, fmt.Errorf("malformed syslog frame, missing message length")
+		}
+
+		n, err := strconv.Atoi(string(length))
+		if err != nil || n < 0 || n > len(rest) {
+			return nil, fmt.Errorf("malformed syslog frame, invalid message length: %q", length)
+		}
+
+		messages = append(messages, rest[:n])
+		payload = rest[n:]
+	}
+
+	return messages, nil
+}
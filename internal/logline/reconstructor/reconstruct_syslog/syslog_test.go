@@ -0,0 +1,245 @@
+package reconstruct_syslog
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+// frame octet counts a message the way the reconstructor is expected to
+func frame(message string) string {
+	return strconv.Itoa(len(message)) + " " + message
+}
+
+func TestEnvironmentLogs(t *testing.T) {
+	logs := []environment_logs.EnvironmentLogWithMetadata{
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 123456789, time.UTC),
+				Message:   "\x1b[31mfailed\x1b[0m",
+				Severity:  "error",
+				Attributes: []subscriptions.EnvironmentLogAttributes{
+					{Key: "path", Value: `"/a\"b]"`},
+				},
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{
+				"project_name":           "shop",
+				"service_name":           "api server",
+				"deployment_instance_id": "instance-1",
+				"log_type":               "deployment",
+			},
+		},
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 12, 13, 4, 5, 0, time.FixedZone("", 3600)),
+				Message:   "",
+				Severity:  "debug",
+			},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		rfc3164  bool
+		facility int
+		hostname string
+		appName  string
+
+		want []string
+	}{
+		{
+			name:     "rfc 5424",
+			facility: 1,
+			hostname: "{project_name}",
+			appName:  "{service_name}",
+			want: []string{
+				`<11>1 2024-01-02T03:04:05.123456Z shop api-server instance-1 deployment ` +
+					`[metadata@32473 deployment_instance_id="instance-1" log_type="deployment" project_name="shop" service_name="api server"]` +
+					`[attributes@32473 path="/a\"b\]"] failed`,
+				`<15>1 2024-01-12T12:04:05.000000Z - - - - -`,
+			},
+		},
+		{
+			name:     "rfc 5424 with literal header fields",
+			facility: 16,
+			hostname: "railway app",
+			appName:  strings.Repeat("a", 60),
+			want: []string{
+				`<131>1 2024-01-02T03:04:05.123456Z railwayapp ` + strings.Repeat("a", maxAppNameLength) + ` instance-1 deployment ` +
+					`[metadata@32473 deployment_instance_id="instance-1" log_type="deployment" project_name="shop" service_name="api server"]` +
+					`[attributes@32473 path="/a\"b\]"] failed`,
+				`<135>1 2024-01-12T12:04:05.000000Z railwayapp ` + strings.Repeat("a", maxAppNameLength) + ` - - -`,
+			},
+		},
+		{
+			name:     "rfc 3164",
+			rfc3164:  true,
+			facility: 1,
+			hostname: "{project_name}",
+			appName:  "{service_name}",
+			want: []string{
+				`<11>Jan  2 03:04:05 shop api-server: failed`,
+				`<15>Jan 12 12:04:05 - railway: `,
+			},
+		},
+		{
+			name:     "rfc 3164 with a long tag",
+			rfc3164:  true,
+			facility: 1,
+			hostname: "host",
+			appName:  strings.Repeat("t", 40),
+			want: []string{
+				`<11>Jan  2 03:04:05 host ` + strings.Repeat("t", maxTagLength) + `: failed`,
+				`<15>Jan 12 12:04:05 host ` + strings.Repeat("t", maxTagLength) + `: `,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(tt.rfc3164, tt.facility, tt.hostname, tt.appName)
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+
+			got, err := s.EnvironmentLogs(logs)
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			want := ""
+
+			for _, message := range tt.want {
+				want += frame(message)
+			}
+
+			if string(got) != want {
+				t.Fatalf("got\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestHttpLogs(t *testing.T) {
+	logs := []http_logs.DeploymentHttpLogWithMetadata{
+		{
+			Timestamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Log:        json.RawMessage(`{"method":"POST","totalDuration":12}`),
+			Path:       "/orders",
+			StatusCode: 503,
+			Metadata:   http_logs.DeploymentHttpLogMetadata{"service_name": "api", "log_type": "http"},
+		},
+		{
+			Timestamp:  time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+			Log:        json.RawMessage(`{"method":"GET"}`),
+			Path:       "/missing",
+			StatusCode: 404,
+		},
+	}
+
+	s, err := New(false, 1, "host", "{service_name}")
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	got, err := s.HttpLogs(logs)
+	if err != nil {
+		t.Fatalf("reconstruct: %v", err)
+	}
+
+	want := frame(`<11>1 2024-01-02T03:04:05.000000Z host api - http [metadata@32473 log_type="http" service_name="api"][http@32473 method="POST" totalDuration="12"] POST /orders 503`) +
+		frame(`<12>1 2024-01-02T03:04:06.000000Z host - - - [http@32473 method="GET"] GET /missing 404`)
+
+	if string(got) != want {
+		t.Fatalf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	levels := []struct {
+		level string
+		want  int
+	}{
+		{"debug", 7},
+		{"INFO", 6},
+		{"", 6},
+		{"warn", 4},
+		{"warning", 4},
+		{"error", 3},
+		{"fatal", 2},
+		{"alert", 1},
+		{"emergency", 0},
+	}
+
+	for _, tt := range levels {
+		if got := severityFromLevel(tt.level); got != tt.want {
+			t.Fatalf("got severity %d for level %q, want %d", got, tt.level, tt.want)
+		}
+	}
+
+	statusCodes := []struct {
+		statusCode int64
+		want       int
+	}{
+		{200, 6},
+		{302, 6},
+		{404, 4},
+		{500, 3},
+		{599, 3},
+	}
+
+	for _, tt := range statusCodes {
+		if got := severityFromStatusCode(tt.statusCode); got != tt.want {
+			t.Fatalf("got severity %d for status code %d, want %d", got, tt.statusCode, tt.want)
+		}
+	}
+}
+
+func TestFrames(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload string
+
+		want    []string
+		wantErr bool
+	}{
+		{name: "empty", payload: "", want: []string{}},
+		{name: "single message", payload: frame("<14>1 - - - - - - one"), want: []string{"<14>1 - - - - - - one"}},
+		{name: "messages with spaces and newlines", payload: frame("a b") + frame("c\nd"), want: []string{"a b", "c\nd"}},
+		{name: "empty message", payload: "0 " + frame("a"), want: []string{"", "a"}},
+		{name: "missing length", payload: "abc", wantErr: true},
+		{name: "length not a number", payload: "x abc", wantErr: true},
+		{name: "length past the end", payload: "10 abc", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, err := Frames([]byte(tt.payload))
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			got := []string{}
+
+			for _, message := range messages {
+				got = append(got, string(message))
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package reconstruct_template
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+// sample logs shaped like the ones received from railway, used to validate templates at startup
+
+func sampleEnvironmentLogs() []environment_logs.EnvironmentLogWithMetadata {
+	log := environment_logs.EnvironmentLogWithMetadata{
+		Log: subscriptions.EnvironmentLog{
+			Timestamp: time.Now(),
+			Message:   "sample log message",
+			Severity:  "info",
+			Attributes: []subscriptions.EnvironmentLogAttributes{
+				{Key: "level", Value: `"info"`},
+			},
+		},
+		Metadata: environment_logs.EnvironmentLogMetadata{
+			"project_id":             "",
+			"project_name":           "",
+			"environment_id":         "",
+			"environment_name":       "",
+			"service_id":             "",
+			"service_name":           "",
+			"deployment_id":          "",
+			"deployment_instance_id": "",
+			"log_type":               "environment",
+		},
+	}
+
+	return []environment_logs.EnvironmentLogWithMetadata{log, log}
+}
+
+func sampleHttpLogs() []http_logs.DeploymentHttpLogWithMetadata {
+	log := http_logs.DeploymentHttpLogWithMetadata{
+		Timestamp:  time.Now(),
+		Log:        json.RawMessage(`{"requestId":"","timestamp":"","method":"GET","path":"/","host":"","httpStatus":200,"totalDuration":0}`),
+		Path:       "/",
+		StatusCode: 200,
+		Metadata: http_logs.DeploymentHttpLogMetadata{
+			"project_id":       "",
+			"project_name":     "",
+			"environment_id":   "",
+			"environment_name": "",
+			"service_id":       "",
+			"service_name":     "",
+			"deployment_id":    "",
+			"log_type":         "http",
+		},
+	}
+
+	return []http_logs.DeploymentHttpLogWithMetadata{log, log}
+}
@@ -0,0 +1,166 @@
+package reconstruct_template
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor"
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/tidwall/gjson"
+)
+
+// Template renders logs with user provided text/template files, either template may be nil if that log type is not sent
+type Template struct {
+	deployLogs *template.Template
+	httpLogs   *template.Template
+}
+
+// data is what a template is executed with, the logs are available as .Logs
+type data[T any] struct {
+	Logs []T
+}
+
+var funcs = template.FuncMap{
+	// json encodes a value, use it to safely embed strings such as {{ json .Log.Message }}
+	"json": func(v any) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+	// formatTime formats a time with a Go layout or one of the names RFC3339, RFC3339Nano, unix, unixMilli and unixNano
+	"formatTime": formatTime,
+	// timestamp returns the timestamp of a deploy log, preferring a timestamp attribute set by the application
+	"timestamp": func(log environment_logs.EnvironmentLogWithMetadata) time.Time {
+		return cmp.Or(reconstructor.TryExtractTimestamp(log), log.Log.Timestamp)
+	},
+	// meta returns a metadata value such as service_name, or an empty string if it is not set
+	"meta": func(metadata map[string]string, key string) string {
+		return metadata[key]
+	},
+	// attr returns the decoded value of a deploy log attribute, or nil if it is not set
+	"attr": func(attributes []subscriptions.EnvironmentLogAttributes, key string) any {
+		for _, attribute := range attributes {
+			if attribute.Key == key {
+				return gjson.Parse(attribute.Value).Value()
+			}
+		}
+
+		return nil
+	},
+	// get returns the value at a gjson path of a http log, such as {{ get .Log "httpStatus" }}
+	"get": func(raw json.RawMessage, path string) any {
+		return gjson.GetBytes(raw, path).Value()
+	},
+	"stripAnsi": util.StripAnsi,
+	"lower":     strings.ToLower,
+	"upper":     strings.ToUpper,
+	// default returns the fallback if the value is empty, such as {{ default "unknown" (meta .Metadata "service_name") }}
+	"default": func(fallback any, value any) any {
+		if value == nil || value == "" {
+			return fallback
+		}
+
+		return value
+	},
+}
+
+// New parses the template files and renders them with sample logs so mistakes surface at startup rather than on the first batch
+func New(deployLogsPath string, httpLogsPath string) (*Template, error) {
+	t := &Template{}
+
+	if deployLogsPath != "" {
+		deployLogs, err := parse(deployLogsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		t.deployLogs = deployLogs
+
+		if _, err := t.EnvironmentLogs(sampleEnvironmentLogs()); err != nil {
+			return nil, fmt.Errorf("failed to render deploy logs template with sample logs: %w", err)
+		}
+	}
+
+	if httpLogsPath != "" {
+		httpLogs, err := parse(httpLogsPath)
+		if err != nil {
+			return nil, err
+		}
+
+		t.httpLogs = httpLogs
+
+		if _, err := t.HttpLogs(sampleHttpLogs()); err != nil {
+			return nil, fmt.Errorf("failed to render http logs template with sample logs: %w", err)
+		}
+	}
+
+	return t, nil
+}
+
+func parse(path string) (*template.Template, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template: %w", err)
+	}
+
+	t, err := template.New(filepath.Base(path)).Option("missingkey=error").Funcs(funcs).Parse(string(contents))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %w", err)
+	}
+
+	return t, nil
+}
+
+// render multiple deployment logs with the deploy logs template
+func (t *Template) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	if t.deployLogs == nil {
+		return nil, fmt.Errorf("no deploy logs template configured")
+	}
+
+	return execute(t.deployLogs, data[environment_logs.EnvironmentLogWithMetadata]{Logs: logs})
+}
+
+// render multiple http logs with the http logs template
+func (t *Template) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	if t.httpLogs == nil {
+		return nil, fmt.Errorf("no http logs template configured")
+	}
+
+	return execute(t.httpLogs, data[http_logs.DeploymentHttpLogWithMetadata]{Logs: logs})
+}
+
+func execute(t *template.Template, data any) ([]byte, error) {
+	buf := bytes.Buffer{}
+
+	if err := t.Execute(&buf, data); err != nil {
+		return nil, fmt.Errorf("failed to render template: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func formatTime(layout string, t time.Time) string {
+	switch layout {
+	case "RFC3339":
+		return t.Format(time.RFC3339)
+	case "RFC3339Nano":
+		return t.Format(time.RFC3339Nano)
+	case "unix":
+		return fmt.Sprint(t.Unix())
+	case "unixMilli":
+		return fmt.Sprint(t.UnixMilli())
+	case "unixNano":
+		return fmt.Sprint(t.UnixNano())
+	default:
+		return t.Format(layout)
+	}
+}
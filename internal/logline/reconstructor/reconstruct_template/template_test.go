@@ -0,0 +1,176 @@
+package reconstruct_template
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+// writeTemplate writes the template to a file and returns its path, an empty template is not written and returns an empty path
+func writeTemplate(t *testing.T, name string, contents string) string {
+	t.Helper()
+
+	if contents == "" {
+		return ""
+	}
+
+	path := filepath.Join(t.TempDir(), name)
+
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestEnvironmentLogs(t *testing.T) {
+	logs := []environment_logs.EnvironmentLogWithMetadata{
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				Message:   "\x1b[31mquoted \"failure\"\x1b[0m",
+				Severity:  "error",
+				Attributes: []subscriptions.EnvironmentLogAttributes{
+					{Key: "attempt", Value: "3"},
+					{Key: "timestamp", Value: `"2024-01-02T03:04:06Z"`},
+				},
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{"service_name": "api"},
+		},
+		{
+			Log: subscriptions.EnvironmentLog{
+				Timestamp: time.Date(2024, 1, 2, 3, 4, 7, 0, time.UTC),
+				Message:   "second",
+				Severity:  "info",
+			},
+			Metadata: environment_logs.EnvironmentLogMetadata{"service_name": "worker"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		template string
+
+		want    string
+		wantErr string
+	}{
+		{
+			name:     "messages",
+			template: `{{ range .Logs }}{{ .Log.Message | stripAnsi }};{{ end }}`,
+			want:     `quoted "failure";second;`,
+		},
+		{
+			name:     "json escaped",
+			template: `{{ range .Logs }}{{ json (stripAnsi .Log.Message) }}{{ end }}`,
+			want:     `"quoted \"failure\"""second"`,
+		},
+		{
+			name:     "metadata and attributes",
+			template: `{{ range .Logs }}{{ upper (meta .Metadata "service_name") }}={{ default "none" (attr .Log.Attributes "attempt") }} {{ end }}`,
+			want:     `API=3 WORKER=none `,
+		},
+		{
+			name:     "timestamps",
+			template: `{{ range .Logs }}{{ formatTime "unix" (timestamp .) }} {{ formatTime "2006-01-02" .Log.Timestamp }} {{ end }}`,
+			want:     `1704164646 2024-01-02 1704164647 2024-01-02 `,
+		},
+		{
+			name:     "missing field",
+			template: `{{ range .Logs }}{{ .Log.Missing }}{{ end }}`,
+			wantErr:  "failed to render deploy logs template with sample logs",
+		},
+		{
+			name:     "syntax error",
+			template: `{{ range .Logs }}`,
+			wantErr:  "failed to parse template",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := New(writeTemplate(t, "deploy.tmpl", tt.template), "")
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+
+			got, err := template.EnvironmentLogs(logs)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+
+			if _, err := template.HttpLogs(nil); err == nil {
+				t.Fatal("got no error rendering http logs without a template, want one")
+			}
+		})
+	}
+}
+
+func TestHttpLogs(t *testing.T) {
+	logs := []http_logs.DeploymentHttpLogWithMetadata{{
+		Timestamp:  time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Log:        json.RawMessage(`{"method":"POST","path":"/orders","httpStatus":201,"totalDuration":12}`),
+		Path:       "/orders",
+		StatusCode: 201,
+		Metadata:   http_logs.DeploymentHttpLogMetadata{"service_name": "api"},
+	}}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name:     "fields",
+			template: `{{ range .Logs }}{{ .StatusCode }} {{ .Path }}{{ end }}`,
+			want:     `201 /orders`,
+		},
+		{
+			name:     "raw log values",
+			template: `{{ range .Logs }}{{ get .Log "method" | lower }} {{ get .Log "totalDuration" }} {{ default "-" (get .Log "missing") }}{{ end }}`,
+			want:     `post 12 -`,
+		},
+		{
+			name:     "time formats",
+			template: `{{ range .Logs }}{{ formatTime "RFC3339" .Timestamp }} {{ formatTime "unixMilli" .Timestamp }}{{ end }}`,
+			want:     `2024-01-02T03:04:05Z 1704164645000`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := New("", writeTemplate(t, "http.tmpl", tt.template))
+			if err != nil {
+				t.Fatalf("new: %v", err)
+			}
+
+			got, err := template.HttpLogs(logs)
+			if err != nil {
+				t.Fatalf("render: %v", err)
+			}
+
+			if string(got) != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
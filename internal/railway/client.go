@@ -17,13 +17,23 @@ func NewClient(gqlConfig *GraphQLClient) (*GraphQLClient, error) {
 		return nil, errors.New("auth token must not be empty")
 	}
 
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if gqlConfig.ProxyURL != nil {
+		transport.Proxy = http.ProxyURL(gqlConfig.ProxyURL)
+	}
+
 	httpClient := &http.Client{
 		Transport: &authedTransport{
 			token:   gqlConfig.AuthToken,
-			wrapped: http.DefaultTransport,
+			wrapped: transport,
 		},
 	}
 
+	gqlConfig.wsClient = &http.Client{
+		Transport: transport,
+	}
+
 	if gqlConfig.BaseURL != "" {
 		gqlConfig.Client = graphql.NewClient(gqlConfig.BaseURL, httpClient)
 	}
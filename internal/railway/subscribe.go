@@ -31,6 +31,7 @@ func (g *GraphQLClient) CreateWebSocketSubscription(ctx context.Context, payload
 			"Content-Type":  []string{"application/json"},
 		},
 		Subprotocols: []string{"graphql-transport-ws"},
+		HTTPClient:   g.wsClient,
 	}
 
 	ctxTimeout, cancel := context.WithTimeout(ctx, (10 * time.Second))
@@ -120,6 +120,8 @@ func SubscribeToServiceLogs(ctx context.Context, g *railway.GraphQLClient, logTr
 
 		filteredLogs := []EnvironmentLogWithMetadata{}
 
+		receivedAt := time.Now()
+
 		for i := range logs.Payload.Data.EnvironmentLogs {
 			// skip logs with empty messages and no attributes
 			// we check for 1 attribute because empty logs will always have at least one attribute, the level
@@ -176,6 +178,7 @@ func SubscribeToServiceLogs(ctx context.Context, g *railway.GraphQLClient, logTr
 
 					"log_type": "environment",
 				},
+				ReceivedAt: receivedAt,
 			})
 		}
 
@@ -1,6 +1,8 @@
 package environment_logs
 
 import (
+	"time"
+
 	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
 )
 
@@ -8,4 +10,7 @@ type EnvironmentLogMetadata map[string]string
 type EnvironmentLogWithMetadata struct {
 	Log      subscriptions.EnvironmentLog
 	Metadata EnvironmentLogMetadata
+
+	// when the log was received from Railway
+	ReceivedAt time.Time
 }
@@ -307,6 +307,8 @@ func getHttpLogs(ctx context.Context, g *railway.GraphQLClient, initialDeploymen
 
 			filteredHttpLogs := []DeploymentHttpLogWithMetadata{}
 
+			receivedAt := time.Now()
+
 			for i := range logs.Payload.Data.HTTPLogs {
 				logTimestamp, err := getTimeStampAttributeFromHttpLog(logs.Payload.Data.HTTPLogs[i])
 				if err != nil {
@@ -351,6 +353,8 @@ func getHttpLogs(ctx context.Context, g *railway.GraphQLClient, initialDeploymen
 					StatusCode: statusCode,
 
 					Metadata: metadata,
+
+					ReceivedAt: receivedAt,
 				})
 
 				logTimes = logTimestamp
@@ -15,4 +15,7 @@ type DeploymentHttpLogWithMetadata struct {
 	StatusCode int64
 
 	Metadata DeploymentHttpLogMetadata
+
+	// when the log was received from Railway
+	ReceivedAt time.Time
 }
@@ -1,6 +1,9 @@
 package railway
 
 import (
+	"net/http"
+	"net/url"
+
 	"github.com/flexstack/uuid"
 	"github.com/hasura/go-graphql-client"
 )
@@ -10,4 +13,10 @@ type GraphQLClient struct {
 	BaseSubscriptionURL string
 	BaseURL             string
 	Client              *graphql.Client
+
+	// routes both the graphql requests and the subscription websocket through a proxy, the standard proxy environment variables are used when nil
+	ProxyURL *url.URL
+
+	// used to dial the subscription websocket
+	wsClient *http.Client
 }
@@ -0,0 +1,115 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Bucket is a token bucket refilled at a constant rate, it is safe for concurrent use.
+//
+// Taking more tokens than the bucket holds is allowed once the bucket is full, the bucket then goes into debt
+// so the average rate is still respected. A nil bucket never limits.
+type Bucket struct {
+	rate  float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// New returns a bucket refilled with rate tokens per second holding at most burst tokens, it returns nil if rate is not positive
+func New(rate float64, burst int) *Bucket {
+	if rate <= 0 {
+		return nil
+	}
+
+	if burst < 1 {
+		burst = int(math.Ceil(rate))
+	}
+
+	return &Bucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// TryTake takes n tokens if they are available right away and reports whether it did
+func (b *Bucket) TryTake(n int) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.refillLocked()
+
+	if b.tokens < b.neededLocked(n) {
+		return false
+	}
+
+	b.tokens -= float64(n)
+
+	return true
+}
+
+// Wait takes n tokens, blocking until they are available or the context is done
+func (b *Bucket) Wait(ctx context.Context, n int) error {
+	if b == nil {
+		return nil
+	}
+
+	b.mu.Lock()
+
+	b.refillLocked()
+
+	// the tokens are reserved right away so waiters are served in order
+	deficit := b.neededLocked(n) - b.tokens
+	b.tokens -= float64(n)
+
+	b.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	t := time.NewTimer(time.Duration(deficit / b.rate * float64(time.Second)))
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		b.Return(n)
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// Return gives back n tokens that were taken but not used
+func (b *Bucket) Return(n int) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens = min(b.tokens+float64(n), b.burst)
+}
+
+func (b *Bucket) refillLocked() {
+	now := time.Now()
+
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, b.burst)
+	b.last = now
+}
+
+// neededLocked returns how many tokens must be available before n tokens can be taken
+func (b *Bucket) neededLocked(n int) float64 {
+	return min(float64(n), b.burst)
+}
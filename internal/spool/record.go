@@ -0,0 +1,81 @@
+package spool
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// every record is framed as [uint32 length][uint32 crc32c][data]
+const headerSize = 8
+
+// guards against allocating a huge buffer when reading a corrupt length
+const maxRecordLength = 1 << 30
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+var errCorruptRecord = errors.New("corrupt spool record")
+
+func encodeRecord(data []byte) []byte {
+	buf := make([]byte, headerSize+len(data))
+
+	binary.LittleEndian.PutUint32(buf[0:4], uint32(len(data)))
+	binary.LittleEndian.PutUint32(buf[4:8], crc32.Checksum(data, crcTable))
+	copy(buf[headerSize:], data)
+
+	return buf
+}
+
+// reads the record at the given offset and returns its data along with the offset of the following record
+func readRecord(r io.ReaderAt, offset int64) ([]byte, int64, error) {
+	header := make([]byte, headerSize)
+
+	if _, err := r.ReadAt(header, offset); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", errCorruptRecord, err)
+	}
+
+	length := binary.LittleEndian.Uint32(header[0:4])
+	checksum := binary.LittleEndian.Uint32(header[4:8])
+
+	if length > maxRecordLength {
+		return nil, 0, errCorruptRecord
+	}
+
+	data := make([]byte, length)
+
+	if _, err := r.ReadAt(data, offset+headerSize); err != nil {
+		return nil, 0, fmt.Errorf("%w: %w", errCorruptRecord, err)
+	}
+
+	if crc32.Checksum(data, crcTable) != checksum {
+		return nil, 0, errCorruptRecord
+	}
+
+	return data, offset + headerSize + int64(length), nil
+}
+
+// scanSegment walks the records of a segment starting at offset,
+// returning the offset just past the last valid record and the number of valid records found
+func scanSegment(path string, offset int64) (int64, int64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	defer f.Close()
+
+	var count int64
+
+	for {
+		_, next, err := readRecord(f, offset)
+		if err != nil {
+			return offset, count, nil
+		}
+
+		offset = next
+		count++
+	}
+}
@@ -0,0 +1,576 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	segmentExtension = ".seg"
+	cursorFileName   = "cursor"
+)
+
+type FsyncPolicy string
+
+const (
+	// fsync after every appended record
+	FsyncAlways FsyncPolicy = "always"
+	// fsync on a fixed interval, a crash can lose at most one interval worth of records
+	FsyncInterval FsyncPolicy = "interval"
+	// never fsync, leave flushing up to the operating system
+	FsyncNever FsyncPolicy = "never"
+)
+
+func (p FsyncPolicy) IsValid() bool {
+	return p == FsyncAlways || p == FsyncInterval || p == FsyncNever
+}
+
+var ErrRecordTooLarge = errors.New("record is larger than the maximum spool size")
+
+type Options struct {
+	Dir string
+
+	// a segment is sealed and a new one started once it would grow past this size
+	MaxSegmentSize int64
+	// once the spool would grow past this size the oldest segments are evicted
+	MaxTotalSize int64
+
+	FsyncPolicy   FsyncPolicy
+	FsyncInterval time.Duration
+}
+
+type Record struct {
+	Data []byte
+
+	segment uint64
+	next    int64
+}
+
+type segment struct {
+	id   uint64
+	size int64
+}
+
+// Spool is a disk backed write-ahead queue made up of size capped segment files.
+//
+// Records are appended to the newest segment and read back in order from a persisted cursor,
+// it supports a single consumer that calls Ack after it is done with a record.
+// Calling Next again without an Ack returns the same record.
+type Spool struct {
+	opts Options
+
+	mu       sync.Mutex
+	segments []segment
+	active   *os.File
+	dirty    bool
+
+	reader   *os.File
+	readerID uint64
+
+	cursorSegment uint64
+	cursorOffset  int64
+
+	notify chan struct{}
+	done   chan struct{}
+
+	evicted atomic.Int64
+}
+
+func Open(opts Options) (*Spool, error) {
+	if opts.Dir == "" {
+		return nil, errors.New("spool directory must not be empty")
+	}
+
+	if opts.MaxSegmentSize <= 0 || opts.MaxTotalSize <= 0 {
+		return nil, errors.New("spool sizes must be greater than zero")
+	}
+
+	if opts.MaxSegmentSize > opts.MaxTotalSize {
+		return nil, errors.New("spool segment size must not be larger than the maximum spool size")
+	}
+
+	if !opts.FsyncPolicy.IsValid() {
+		return nil, fmt.Errorf("invalid fsync policy: %s", opts.FsyncPolicy)
+	}
+
+	if err := os.MkdirAll(opts.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	s := &Spool{
+		opts:   opts,
+		notify: make(chan struct{}, 1),
+		done:   make(chan struct{}),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+
+	if opts.FsyncPolicy == FsyncInterval && opts.FsyncInterval > 0 {
+		go s.syncEvery(opts.FsyncInterval)
+	}
+
+	return s, nil
+}
+
+// load discovers existing segments, repairs a torn tail write and restores the read cursor
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.opts.Dir)
+	if err != nil {
+		return fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), segmentExtension) {
+			continue
+		}
+
+		id, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), segmentExtension), 10, 64)
+		if err != nil {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return fmt.Errorf("failed to stat spool segment: %w", err)
+		}
+
+		s.segments = append(s.segments, segment{id: id, size: info.Size()})
+	}
+
+	slices.SortFunc(s.segments, func(a, b segment) int {
+		return compareUint64(a.id, b.id)
+	})
+
+	if len(s.segments) == 0 {
+		s.segments = append(s.segments, segment{id: 1})
+	}
+
+	last := &s.segments[len(s.segments)-1]
+
+	validSize, _, err := scanSegment(s.segmentPath(last.id), 0)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	active, err := os.OpenFile(s.segmentPath(last.id), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open active spool segment: %w", err)
+	}
+
+	// drop a partially written record left behind by a crash
+	if validSize != last.size {
+		if err := active.Truncate(validSize); err != nil {
+			active.Close()
+			return fmt.Errorf("failed to truncate active spool segment: %w", err)
+		}
+
+		last.size = validSize
+	}
+
+	s.active = active
+
+	s.cursorSegment, s.cursorOffset = s.readCursor()
+
+	if s.cursorSegment < s.segments[0].id {
+		s.cursorSegment, s.cursorOffset = s.segments[0].id, 0
+	}
+
+	// the cursor and the segment are not synced together, so after a crash the cursor can point past the truncated tail,
+	// where new records would be appended below it or read from their middle
+	if s.cursorSegment == last.id {
+		s.cursorOffset = min(s.cursorOffset, last.size)
+	}
+
+	return nil
+}
+
+// Append durably queues a record, evicting the oldest segments if the spool is full
+func (s *Spool) Append(data []byte) error {
+	recordSize := int64(headerSize + len(data))
+
+	if recordSize > s.opts.MaxTotalSize {
+		return ErrRecordTooLarge
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := &s.segments[len(s.segments)-1]
+
+	if last.size > 0 && last.size+recordSize > s.opts.MaxSegmentSize {
+		if err := s.rollLocked(); err != nil {
+			return err
+		}
+	}
+
+	for s.totalSizeLocked()+recordSize > s.opts.MaxTotalSize && len(s.segments) > 1 {
+		if err := s.evictOldestLocked(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.active.Write(encodeRecord(data)); err != nil {
+		return fmt.Errorf("failed to write spool record: %w", err)
+	}
+
+	s.segments[len(s.segments)-1].size += recordSize
+
+	if s.opts.FsyncPolicy == FsyncAlways {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("failed to sync spool segment: %w", err)
+		}
+	} else {
+		s.dirty = true
+	}
+
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// Next blocks until a record is available and returns the oldest record that has not been acknowledged yet
+func (s *Spool) Next(ctx context.Context) (Record, error) {
+	for {
+		s.mu.Lock()
+		record, ok, err := s.readLocked()
+		s.mu.Unlock()
+
+		if err != nil {
+			return Record{}, err
+		}
+
+		if ok {
+			return record, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return Record{}, ctx.Err()
+		case <-s.done:
+			return Record{}, errors.New("spool is closed")
+		case <-s.notify:
+		}
+	}
+}
+
+// Ack marks the record as delivered and persists the read cursor past it
+func (s *Spool) Ack(record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// the segment holding this record was evicted while it was being delivered
+	if record.segment < s.cursorSegment {
+		return nil
+	}
+
+	s.cursorSegment, s.cursorOffset = record.segment, record.next
+
+	return s.writeCursorLocked()
+}
+
+// Evicted returns the number of records that were discarded to stay under the maximum spool size
+func (s *Spool) Evicted() int64 {
+	return s.evicted.Load()
+}
+
+// Size returns the number of bytes past the read cursor, records that were acknowledged but not removed from disk yet are not counted
+func (s *Spool) Size() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pendingSizeLocked()
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		return nil
+	default:
+		close(s.done)
+	}
+
+	if s.reader != nil {
+		s.reader.Close()
+	}
+
+	if s.opts.FsyncPolicy != FsyncNever {
+		s.active.Sync()
+	}
+
+	return s.active.Close()
+}
+
+func (s *Spool) readLocked() (Record, bool, error) {
+	for {
+		index := slices.IndexFunc(s.segments, func(seg segment) bool {
+			return seg.id == s.cursorSegment
+		})
+
+		if index == -1 {
+			s.cursorSegment, s.cursorOffset = s.segments[0].id, 0
+			continue
+		}
+
+		seg := s.segments[index]
+		isActive := index == len(s.segments)-1
+
+		if s.cursorOffset >= seg.size {
+			if isActive {
+				return Record{}, false, nil
+			}
+
+			// the sealed segment has been fully consumed
+			if err := s.removeSegmentLocked(index); err != nil {
+				return Record{}, false, err
+			}
+
+			s.cursorSegment, s.cursorOffset = s.segments[index].id, 0
+
+			if err := s.writeCursorLocked(); err != nil {
+				return Record{}, false, err
+			}
+
+			continue
+		}
+
+		reader, err := s.readerLocked(seg.id)
+		if err != nil {
+			return Record{}, false, err
+		}
+
+		data, next, err := readRecord(reader, s.cursorOffset)
+		if err != nil {
+			if isActive {
+				return Record{}, false, err
+			}
+
+			// a corrupt record in a sealed segment makes the rest of it unreadable, skip ahead to the next segment
+			s.cursorOffset = seg.size
+			continue
+		}
+
+		return Record{Data: data, segment: seg.id, next: next}, true, nil
+	}
+}
+
+func (s *Spool) readerLocked(id uint64) (*os.File, error) {
+	if s.reader != nil && s.readerID == id {
+		return s.reader, nil
+	}
+
+	if s.reader != nil {
+		s.reader.Close()
+		s.reader = nil
+	}
+
+	reader, err := os.Open(s.segmentPath(id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool segment for reading: %w", err)
+	}
+
+	s.reader, s.readerID = reader, id
+
+	return reader, nil
+}
+
+func (s *Spool) rollLocked() error {
+	if s.opts.FsyncPolicy != FsyncNever {
+		if err := s.active.Sync(); err != nil {
+			return fmt.Errorf("failed to sync spool segment: %w", err)
+		}
+	}
+
+	if err := s.active.Close(); err != nil {
+		return fmt.Errorf("failed to close spool segment: %w", err)
+	}
+
+	id := s.segments[len(s.segments)-1].id + 1
+
+	active, err := os.OpenFile(s.segmentPath(id), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create spool segment: %w", err)
+	}
+
+	s.active = active
+	s.dirty = false
+	s.segments = append(s.segments, segment{id: id})
+
+	return nil
+}
+
+func (s *Spool) evictOldestLocked() error {
+	oldest := s.segments[0]
+
+	var offset int64
+	if s.cursorSegment == oldest.id {
+		offset = s.cursorOffset
+	}
+
+	if _, count, err := scanSegment(s.segmentPath(oldest.id), offset); err == nil {
+		s.evicted.Add(count)
+	}
+
+	if err := s.removeSegmentLocked(0); err != nil {
+		return err
+	}
+
+	if s.cursorSegment <= oldest.id {
+		s.cursorSegment, s.cursorOffset = s.segments[0].id, 0
+
+		return s.writeCursorLocked()
+	}
+
+	return nil
+}
+
+func (s *Spool) removeSegmentLocked(index int) error {
+	id := s.segments[index].id
+
+	if s.reader != nil && s.readerID == id {
+		s.reader.Close()
+		s.reader = nil
+	}
+
+	if err := os.Remove(s.segmentPath(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove spool segment: %w", err)
+	}
+
+	s.segments = slices.Delete(s.segments, index, index+1)
+
+	return nil
+}
+
+func (s *Spool) totalSizeLocked() int64 {
+	var total int64
+
+	for _, seg := range s.segments {
+		total += seg.size
+	}
+
+	return total
+}
+
+func (s *Spool) pendingSizeLocked() int64 {
+	var pending int64
+
+	for _, seg := range s.segments {
+		switch {
+		case seg.id > s.cursorSegment:
+			pending += seg.size
+		case seg.id == s.cursorSegment:
+			pending += max(seg.size-s.cursorOffset, 0)
+		}
+	}
+
+	return pending
+}
+
+func (s *Spool) syncEvery(interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-t.C:
+			s.mu.Lock()
+
+			if s.dirty {
+				s.active.Sync()
+				s.dirty = false
+			}
+
+			s.mu.Unlock()
+		}
+	}
+}
+
+func (s *Spool) segmentPath(id uint64) string {
+	return filepath.Join(s.opts.Dir, fmt.Sprintf("%020d%s", id, segmentExtension))
+}
+
+func (s *Spool) readCursor() (uint64, int64) {
+	b, err := os.ReadFile(filepath.Join(s.opts.Dir, cursorFileName))
+	if err != nil {
+		return 0, 0
+	}
+
+	fields := strings.Fields(string(b))
+	if len(fields) != 2 {
+		return 0, 0
+	}
+
+	id, err := strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	offset, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return 0, 0
+	}
+
+	return id, offset
+}
+
+// the cursor is written to a temporary file first so a crash never leaves a half written cursor behind
+func (s *Spool) writeCursorLocked() error {
+	path := filepath.Join(s.opts.Dir, cursorFileName)
+	tmpPath := path + ".tmp"
+
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+
+	if _, err := fmt.Fprintf(f, "%d %d\n", s.cursorSegment, s.cursorOffset); err != nil {
+		f.Close()
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+
+	if s.opts.FsyncPolicy == FsyncAlways {
+		if err := f.Sync(); err != nil {
+			f.Close()
+			return fmt.Errorf("failed to sync spool cursor: %w", err)
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write spool cursor: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace spool cursor: %w", err)
+	}
+
+	return nil
+}
+
+func compareUint64(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
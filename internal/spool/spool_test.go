@@ -0,0 +1,319 @@
+package spool
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func openTestSpool(t *testing.T, dir string) *Spool {
+	t.Helper()
+
+	s, err := Open(Options{
+		Dir:            dir,
+		MaxSegmentSize: 1 << 20,
+		MaxTotalSize:   4 << 20,
+		FsyncPolicy:    FsyncNever,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+// readAll returns the data of every pending record, acknowledging each one
+func readAll(t *testing.T, s *Spool) []string {
+	t.Helper()
+
+	records := []string{}
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		record, err := s.Next(ctx)
+		cancel()
+
+		if err != nil {
+			return records
+		}
+
+		records = append(records, string(record.Data))
+
+		if err := s.Ack(record); err != nil {
+			t.Fatalf("ack: %v", err)
+		}
+	}
+}
+
+func recordSize(data string) int64 {
+	return int64(headerSize + len(data))
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name string
+		// records appended before the simulated crash
+		before []string
+		// records acknowledged before the simulated crash
+		acked int
+		// changes the segment file the way a crash could leave it behind
+		crash func(t *testing.T, path string)
+		// records appended after reopening
+		after []string
+		want  []string
+	}{
+		{
+			name:   "clean reopen",
+			before: []string{"a", "b"},
+			crash:  func(t *testing.T, path string) {},
+			after:  []string{"c"},
+			want:   []string{"a", "b", "c"},
+		},
+		{
+			name:   "partial header at the tail",
+			before: []string{"a", "b"},
+			crash: func(t *testing.T, path string) {
+				appendBytes(t, path, []byte{5, 0, 0})
+			},
+			after: []string{"c"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:   "partial data at the tail",
+			before: []string{"a", "b"},
+			crash: func(t *testing.T, path string) {
+				appendBytes(t, path, encodeRecord([]byte("lost"))[:headerSize+2])
+			},
+			after: []string{"c"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:   "corrupt checksum at the tail",
+			before: []string{"a", "b"},
+			crash: func(t *testing.T, path string) {
+				record := encodeRecord([]byte("lost"))
+				record[len(record)-1] ^= 0xff
+				appendBytes(t, path, record)
+			},
+			after: []string{"c"},
+			want:  []string{"a", "b", "c"},
+		},
+		{
+			name:   "cursor past the truncated tail",
+			before: []string{"a", "bb"},
+			acked:  2,
+			crash: func(t *testing.T, path string) {
+				// the cursor was persisted but the last record never made it to disk in full
+				if err := os.Truncate(path, recordSize("a")+headerSize+1); err != nil {
+					t.Fatal(err)
+				}
+			},
+			after: []string{"c", "d"},
+			want:  []string{"c", "d"},
+		},
+		{
+			name:   "cursor inside the truncated tail",
+			before: []string{"a", "bb", "ccc"},
+			acked:  2,
+			crash: func(t *testing.T, path string) {
+				if err := os.Truncate(path, recordSize("a")+recordSize("bb")+headerSize); err != nil {
+					t.Fatal(err)
+				}
+			},
+			after: []string{"d"},
+			want:  []string{"d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			s := openTestSpool(t, dir)
+
+			for _, data := range tt.before {
+				if err := s.Append([]byte(data)); err != nil {
+					t.Fatalf("append: %v", err)
+				}
+			}
+
+			for range tt.acked {
+				record, err := s.Next(context.Background())
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+
+				if err := s.Ack(record); err != nil {
+					t.Fatalf("ack: %v", err)
+				}
+			}
+
+			s.Close()
+
+			tt.crash(t, s.segmentPath(s.segments[len(s.segments)-1].id))
+
+			s = openTestSpool(t, dir)
+
+			for _, data := range tt.after {
+				if err := s.Append([]byte(data)); err != nil {
+					t.Fatalf("append: %v", err)
+				}
+			}
+
+			got := readAll(t, s)
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got records %q, want %q", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got records %q, want %q", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		records []string
+		acked   int
+		want    int64
+	}{
+		{
+			name: "empty",
+			want: 0,
+		},
+		{
+			name:    "nothing acknowledged",
+			records: []string{"a", "bb", "ccc"},
+			want:    recordSize("a") + recordSize("bb") + recordSize("ccc"),
+		},
+		{
+			name:    "some acknowledged",
+			records: []string{"a", "bb", "ccc"},
+			acked:   2,
+			want:    recordSize("ccc"),
+		},
+		{
+			name:    "all acknowledged",
+			records: []string{"a", "bb", "ccc"},
+			acked:   3,
+			want:    0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := openTestSpool(t, t.TempDir())
+
+			for _, data := range tt.records {
+				if err := s.Append([]byte(data)); err != nil {
+					t.Fatalf("append: %v", err)
+				}
+			}
+
+			for range tt.acked {
+				record, err := s.Next(context.Background())
+				if err != nil {
+					t.Fatalf("next: %v", err)
+				}
+
+				if err := s.Ack(record); err != nil {
+					t.Fatalf("ack: %v", err)
+				}
+			}
+
+			if got := s.Size(); got != tt.want {
+				t.Fatalf("got size %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextWithoutAck(t *testing.T) {
+	s := openTestSpool(t, t.TempDir())
+
+	for _, data := range []string{"a", "b"} {
+		if err := s.Append([]byte(data)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	for range 2 {
+		record, err := s.Next(context.Background())
+		if err != nil {
+			t.Fatalf("next: %v", err)
+		}
+
+		if string(record.Data) != "a" {
+			t.Fatalf("got record %q, want %q", record.Data, "a")
+		}
+	}
+}
+
+func TestEviction(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(Options{
+		Dir:            dir,
+		MaxSegmentSize: recordSize("aaaa") * 2,
+		MaxTotalSize:   recordSize("aaaa") * 4,
+		FsyncPolicy:    FsyncNever,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+
+	defer s.Close()
+
+	for _, data := range []string{"0000", "1111", "2222", "3333", "4444", "5555"} {
+		if err := s.Append([]byte(data)); err != nil {
+			t.Fatalf("append: %v", err)
+		}
+	}
+
+	if got := s.Evicted(); got != 2 {
+		t.Fatalf("got %d evicted records, want 2", got)
+	}
+
+	got := readAll(t, s)
+	want := []string{"2222", "3333", "4444", "5555"}
+
+	if len(got) != len(want) {
+		t.Fatalf("got records %q, want %q", got, want)
+	}
+
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got records %q, want %q", got, want)
+		}
+	}
+
+	segments, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+
+	if len(segments) > 3 {
+		t.Fatalf("got %d segment files, want at most 3", len(segments))
+	}
+}
+
+func appendBytes(t *testing.T, path string, b []byte) {
+	t.Helper()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer f.Close()
+
+	if _, err := f.Write(b); err != nil {
+		t.Fatal(err)
+	}
+}
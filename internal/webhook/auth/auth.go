@@ -0,0 +1,15 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+)
+
+// Provider adds credentials to outgoing webhook requests
+type Provider interface {
+	// Authorize sets the credentials on the request, fetching them first if needed
+	Authorize(ctx context.Context, req *http.Request) error
+
+	// Invalidate drops any cached credentials after the destination rejected them, the next request fetches new ones
+	Invalidate()
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+// tokens are refreshed this long before they expire at most, shorter lived tokens are refreshed after 80% of their lifetime
+const maxRefreshBefore = time.Minute
+
+// TokenError is returned when the token endpoint responds with a non success status code
+type TokenError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *TokenError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("token endpoint responded with non success status code: %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("token endpoint responded with non success status code: %d; with body: %s", e.StatusCode, e.Body)
+}
+
+// ClientCredentials authorizes requests with bearer tokens fetched using the OAuth2 client credentials grant
+type ClientCredentials struct {
+	settings config.OAuth2
+	client   *http.Client
+
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+func NewClientCredentials(settings config.OAuth2, client *http.Client) *ClientCredentials {
+	return &ClientCredentials{
+		settings: settings,
+		client:   client,
+	}
+}
+
+func (c *ClientCredentials) Authorize(ctx context.Context, req *http.Request) error {
+	token, err := c.Token(ctx)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	return nil
+}
+
+func (c *ClientCredentials) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.token = ""
+}
+
+// Token returns the cached access token, fetching a new one if there is none or it is about to expire.
+//
+// Concurrent callers wait for a single fetch rather than each fetching their own token.
+func (c *ClientCredentials) Token(ctx context.Context) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.token != "" && (c.refreshAt.IsZero() || time.Now().Before(c.refreshAt)) {
+		return c.token, nil
+	}
+
+	token, expiresIn, err := c.fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	c.token = token
+	c.refreshAt = time.Time{}
+
+	// tokens without an expiry are used until the destination rejects them
+	if expiresIn > 0 {
+		c.refreshAt = time.Now().Add(expiresIn - min(expiresIn/5, maxRefreshBefore))
+	}
+
+	return c.token, nil
+}
+
+func (c *ClientCredentials) fetch(ctx context.Context) (string, time.Duration, error) {
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+
+	if len(c.settings.Scopes) > 0 {
+		form.Set("scope", strings.Join(c.settings.Scopes, " "))
+	}
+
+	if c.settings.Audience != "" {
+		form.Set("audience", c.settings.Audience)
+	}
+
+	if c.settings.ClientAuth == config.OAuth2ClientAuthBody {
+		form.Set("client_id", c.settings.ClientId)
+		form.Set("client_secret", c.settings.ClientSecret)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.settings.TokenUrl.String(), strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create token request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if c.settings.ClientAuth != config.OAuth2ClientAuthBody {
+		req.SetBasicAuth(url.QueryEscape(c.settings.ClientId), url.QueryEscape(c.settings.ClientSecret))
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to fetch token: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to read token response: %w", err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", 0, &TokenError{
+			StatusCode: res.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+		}
+	}
+
+	tokenResponse := struct {
+		AccessToken string `json:"access_token"`
+		TokenType   string `json:"token_type"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}{}
+
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", 0, fmt.Errorf("failed to parse token response: %w", err)
+	}
+
+	if tokenResponse.AccessToken == "" {
+		return "", 0, fmt.Errorf("token response did not contain an access token")
+	}
+
+	if tokenResponse.TokenType != "" && !strings.EqualFold(tokenResponse.TokenType, "bearer") {
+		return "", 0, fmt.Errorf("unsupported token type: %s", tokenResponse.TokenType)
+	}
+
+	return tokenResponse.AccessToken, time.Duration(tokenResponse.ExpiresIn) * time.Second, nil
+}
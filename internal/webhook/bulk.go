@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_elasticsearch"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+)
+
+// partialError is returned by send when a bulk request was delivered in part, it holds the items that were not delivered
+type partialError struct {
+	payload []byte
+	count   int
+	err     error
+}
+
+func (e *partialError) Error() string {
+	return fmt.Sprintf("%d bulk items were not delivered: %s", e.count, e.err)
+}
+
+func (e *partialError) Unwrap() error {
+	return e.err
+}
+
+// undelivered narrows a payload that failed to send, along with its log count, to the part of it that was not delivered
+func undelivered(payload []byte, count int, err error) ([]byte, int) {
+	var partialErr *partialError
+	if errors.As(err, &partialErr) {
+		return partialErr.payload, partialErr.count
+	}
+
+	return payload, count
+}
+
+// narrowBulk returns the bulk request holding only the items that failed, along with the error to retry or give up with.
+//
+// Items are retried for as long as any of them failed with a retryable status, once only rejected items are left they are given up on as a status error.
+func narrowBulk(payload []byte, bulkErr *generic.BulkError) ([]byte, error) {
+	payload = reconstruct_elasticsearch.Items(payload, bulkErr.Items)
+
+	if !isRetryable(bulkErr) {
+		return payload, bulkErr.StatusError()
+	}
+
+	return payload, bulkErr
+}
@@ -0,0 +1,130 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/brody192/locomotive/internal/circuit"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/webhook/socket"
+)
+
+const (
+	endpointPrimary  = "primary"
+	endpointFailover = "failover"
+)
+
+// endpoint is a url a destination can deliver to, every endpoint tracks its health with its own circuit breaker
+type endpoint struct {
+	role    string
+	url     url.URL
+	breaker *circuit.Breaker
+
+	// the connection used instead of http when the url has a socket scheme, such as tcp:// in the syslog webhook mode
+	socket *socket.Conn
+}
+
+// newEndpoints returns the primary endpoint of the destination followed by its failover endpoint if one is configured
+func (d *Destination) newEndpoints() []*endpoint {
+	endpoints := []*endpoint{d.newEndpoint(endpointPrimary, d.Config.WebhookUrl)}
+
+	if d.Config.FailoverWebhookUrl.Host != "" {
+		endpoints = append(endpoints, d.newEndpoint(endpointFailover, d.Config.FailoverWebhookUrl))
+	}
+
+	return endpoints
+}
+
+func (d *Destination) newEndpoint(role string, u url.URL) *endpoint {
+	e := &endpoint{
+		role:   role,
+		url:    u,
+		socket: d.newSocket(u),
+	}
+
+	e.breaker = circuit.New(circuit.Options{
+		FailureThreshold: config.Global.CircuitBreakerFailureThreshold,
+		SuccessThreshold: config.Global.CircuitBreakerSuccessThreshold,
+		ProbeInterval:    config.Global.CircuitBreakerProbeInterval,
+		OnStateChange: func(from, to circuit.State) {
+			d.logCircuitStateChange(e, from, to)
+		},
+	})
+
+	return e
+}
+
+// pickEndpoint returns the first endpoint whose circuit breaker lets a request through, so the primary endpoint is used
+// whenever it is healthy or due for a probe. It returns circuit.ErrOpen if every endpoint is unavailable.
+func (d *Destination) pickEndpoint() (*endpoint, error) {
+	for _, e := range d.endpoints {
+		if err := e.breaker.Allow(); err == nil {
+			if previous := d.activeEndpoint.Swap(e); previous != e && previous != nil {
+				d.logger().Warn("switching webhook endpoint",
+					slog.String("from", previous.role),
+					slog.String("to", e.role),
+					slog.String("webhook_url_host", e.url.Host),
+				)
+			}
+
+			return e, nil
+		}
+	}
+
+	return nil, circuit.ErrOpen
+}
+
+// recordOutcome feeds the result of a single request to the endpoint's circuit breaker, only failures that point to an unavailable endpoint count against it
+func (e *endpoint) recordOutcome(err error) {
+	if errors.Is(err, context.Canceled) {
+		e.breaker.Abort()
+		return
+	}
+
+	e.breaker.Done(isRetryable(err))
+}
+
+// CircuitState returns the state of the circuit breaker of the destination's primary endpoint
+func (d *Destination) CircuitState() circuit.State {
+	return d.endpoints[0].breaker.State()
+}
+
+// ActiveEndpoint returns whether the primary or failover endpoint received the latest request
+func (d *Destination) ActiveEndpoint() string {
+	if e := d.activeEndpoint.Load(); e != nil {
+		return e.role
+	}
+
+	return endpointPrimary
+}
+
+// probeIn returns how long until any endpoint lets a request through again
+func (d *Destination) probeIn() time.Duration {
+	wait := d.endpoints[0].breaker.ProbeIn()
+
+	for _, e := range d.endpoints[1:] {
+		wait = min(wait, e.breaker.ProbeIn())
+	}
+
+	return wait
+}
+
+func (d *Destination) logCircuitStateChange(e *endpoint, from, to circuit.State) {
+	attrs := []any{
+		slog.String("endpoint", e.role),
+		slog.String("from", from.String()),
+		slog.String("to", to.String()),
+	}
+
+	switch to {
+	case circuit.StateOpen:
+		d.logger().Warn("webhook endpoint is unavailable, opening circuit breaker", attrs...)
+	case circuit.StateHalfOpen:
+		d.logger().Info("probing webhook endpoint", attrs...)
+	case circuit.StateClosed:
+		d.logger().Info("webhook endpoint recovered, closing circuit breaker", attrs...)
+	}
+}
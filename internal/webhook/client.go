@@ -4,31 +4,52 @@ import (
 	"crypto/tls"
 	"net"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/brody192/locomotive/internal/config"
 )
 
 var client *http.Client
 
 func init() {
-	client = &http.Client{
-		Timeout: 20 * time.Second,
-		Transport: &http.Transport{
-			DialContext: (&net.Dialer{
-				Timeout:   5 * time.Second,
-				KeepAlive: 5 * time.Minute,
-			}).DialContext,
-			MaxConnsPerHost:       100,
-			MaxIdleConns:          100,
-			IdleConnTimeout:       5 * time.Minute,
-			TLSHandshakeTimeout:   20 * time.Second,
-			ResponseHeaderTimeout: 20 * time.Second,
-			ExpectContinueTimeout: 20 * time.Second,
-			MaxIdleConnsPerHost:   100,
-			DisableKeepAlives:     false,
-			TLSClientConfig:       &tls.Config{},
-		},
+	client = newClient(newTransport(&tls.Config{}))
+}
+
+func newClient(transport http.RoundTripper) *http.Client {
+	return &http.Client{
+		Timeout:   20 * time.Second,
+		Transport: transport,
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
 }
+
+// proxy returns the proxy for a webhook request, LOCOMOTIVE_WEBHOOK_PROXY_URL takes precedence over the standard proxy environment variables
+func proxy(req *http.Request) (*url.URL, error) {
+	if config.Global.WebhookProxyUrl.Host != "" {
+		return &config.Global.WebhookProxyUrl, nil
+	}
+
+	return http.ProxyFromEnvironment(req)
+}
+
+func newTransport(tlsConfig *tls.Config) *http.Transport {
+	return &http.Transport{
+		Proxy: proxy,
+		DialContext: (&net.Dialer{
+			Timeout:   5 * time.Second,
+			KeepAlive: 5 * time.Minute,
+		}).DialContext,
+		MaxConnsPerHost:       100,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       5 * time.Minute,
+		TLSHandshakeTimeout:   20 * time.Second,
+		ResponseHeaderTimeout: 20 * time.Second,
+		ExpectContinueTimeout: 20 * time.Second,
+		MaxIdleConnsPerHost:   100,
+		DisableKeepAlives:     false,
+		TLSClientConfig:       tlsConfig,
+	}
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/deadletter"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+)
+
+var ErrDeadLettered = errors.New("payload was written to the dead letter sink")
+
+var (
+	// writes rejected payloads to LOCOMOTIVE_DEAD_LETTER_DIR when set
+	deadLetterWriter *deadletter.Writer
+
+	// forwards rejected payloads to LOCOMOTIVE_DEAD_LETTER_WEBHOOK_URL when set
+	deadLetterWebhook *Destination
+)
+
+// OpenDeadLetters sets up the dead letter sinks configured by LOCOMOTIVE_DEAD_LETTER_DIR and LOCOMOTIVE_DEAD_LETTER_WEBHOOK_URL
+func OpenDeadLetters() error {
+	if config.Global.DeadLetterDir != "" {
+		w, err := deadletter.NewWriter(config.Global.DeadLetterDir, int64(config.Global.DeadLetterMaxFileSize), config.Global.DeadLetterMaxFiles)
+		if err != nil {
+			return err
+		}
+
+		deadLetterWriter = w
+	}
+
+	if config.Global.DeadLetterWebhookUrl.Host != "" {
+		w, err := newDestination(config.NamedDestination{
+			Name: "dead-letter",
+			Destination: config.Destination{
+				WebhookUrl:        config.Global.DeadLetterWebhookUrl,
+				WebhookMode:       config.WebhookModeJson,
+				AdditionalHeaders: config.Global.DeadLetterAdditionalHeaders,
+				Compression:       config.CompressionNone,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		deadLetterWebhook = w
+	}
+
+	return nil
+}
+
+// reject accounts for a batch that could not be delivered, storing it in the dead letter sinks
+// when the destination permanently rejected it, and returns the error to report for it
+func (d *Destination) reject(ctx context.Context, kind logKind, count int, payload []byte, sendErr error) error {
+	_, dropped, deadLettered := d.Stats.forKind(kind)
+
+	var statusErr *generic.StatusError
+	if !errors.As(sendErr, &statusErr) || isRetryable(sendErr) || !d.deadLetter(ctx, kind, count, payload, statusErr) {
+		dropped.Add(int64(count))
+
+		return sendErr
+	}
+
+	deadLettered.Add(int64(count))
+
+	return fmt.Errorf("%w: %w", ErrDeadLettered, sendErr)
+}
+
+// deadLetter writes the rejected payload to every configured dead letter sink, it returns true if at least one sink stored it
+func (d *Destination) deadLetter(ctx context.Context, kind logKind, count int, payload []byte, statusErr *generic.StatusError) bool {
+	if deadLetterWriter == nil && deadLetterWebhook == nil {
+		return false
+	}
+
+	entry := deadletter.Entry{
+		Time:         time.Now().UTC(),
+		Destination:  d.Name,
+		Mode:         string(d.Config.WebhookMode),
+		LogKind:      kind.String(),
+		LogCount:     count,
+		StatusCode:   statusErr.StatusCode,
+		ResponseBody: statusErr.Body,
+		Error:        statusErr.Error(),
+		Payload:      payload,
+	}
+
+	stored := false
+
+	if deadLetterWriter != nil {
+		if err := deadLetterWriter.Write(entry); err != nil {
+			d.logger().Error("error writing payload to the dead letter directory", logger.ErrAttr(err))
+		} else {
+			stored = true
+		}
+	}
+
+	if deadLetterWebhook != nil {
+		body, err := json.Marshal(entry)
+		if err == nil {
+			err = deadLetterWebhook.send(ctx, count, body)
+		}
+
+		if err != nil {
+			d.logger().Error("error sending payload to the dead letter webhook", logger.ErrAttr(err))
+		} else {
+			stored = true
+		}
+	}
+
+	if stored {
+		d.logger().Warn("payload rejected by the webhook endpoint, written to the dead letter sink",
+			slog.String("log_kind", kind.String()),
+			slog.Int("log_count", count),
+			slog.Int("status_code", statusErr.StatusCode),
+		)
+	}
+
+	return stored
+}
@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+	"sync/atomic"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/ratelimit"
+	"github.com/brody192/locomotive/internal/spool"
+	"github.com/brody192/locomotive/internal/util"
+	"github.com/brody192/locomotive/internal/webhook/auth"
+)
+
+// Destination delivers logs to a single configured endpoint, every destination keeps its own spool and stats so it is isolated from the others
+type Destination struct {
+	Name   string
+	Config config.Destination
+
+	Stats stats
+
+	// the request body compression resolved from the configuration and the webhook mode
+	compression config.Compression
+
+	// reconstructs payloads in the webhook mode of the destination
+	reconstructor reconstructor
+
+	// the shared client, or a client of its own when the destination has tls settings
+	client *http.Client
+
+	// adds credentials to requests, nil when the destination only uses static headers
+	auth auth.Provider
+
+	// the primary endpoint followed by the failover endpoint if configured
+	endpoints []*endpoint
+
+	// the endpoint that received the latest request
+	activeEndpoint atomic.Pointer[endpoint]
+
+	// limit the requests and logs sent per second, nil when not configured
+	requestLimiter *ratelimit.Bucket
+	eventLimiter   *ratelimit.Bucket
+
+	// when set, serialized batches are queued on disk and delivered by drainSpool instead of being sent inline
+	spool *spool.Spool
+}
+
+// NewDestination creates a destination from its configuration, opening its spool if LOCOMOTIVE_SPOOL_DIR is set.
+//
+// The context controls the lifetime of the spool delivery goroutine.
+func NewDestination(ctx context.Context, namedDestination config.NamedDestination) (*Destination, error) {
+	d, err := newDestination(namedDestination)
+	if err != nil {
+		return nil, err
+	}
+
+	if config.Global.SpoolDir != "" {
+		spoolDir := filepath.Join(config.Global.SpoolDir, util.SanitizeString(d.Name))
+
+		if err := d.openSpool(ctx, spoolDir); err != nil {
+			return nil, fmt.Errorf("failed to open spool for destination %s: %w", d.Name, err)
+		}
+	}
+
+	return d, nil
+}
+
+// newDestination creates a destination without a spool
+func newDestination(namedDestination config.NamedDestination) (*Destination, error) {
+	d := &Destination{
+		Name:        namedDestination.Name,
+		Config:      namedDestination.Destination,
+		compression: resolveCompression(namedDestination.Destination),
+		client:      client,
+	}
+
+	reconstructor, err := newReconstructor(d.Config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure destination %s: %w", d.Name, err)
+	}
+
+	d.reconstructor = reconstructor
+
+	if d.Config.WebhookMode == config.WebhookModeTemplate {
+		d.setDefaultHeader("Content-Type", d.Config.TemplateContentType)
+	}
+
+	if d.Config.WebhookMode == config.WebhookModeLoki {
+		if d.Config.Loki.Encoding == config.LokiEncodingJson {
+			d.setDefaultHeader("Content-Type", "application/json")
+		}
+
+		if d.Config.Loki.TenantId != "" {
+			d.setDefaultHeader("X-Scope-OrgID", d.Config.Loki.TenantId)
+		}
+	}
+
+	if d.otlpJson() {
+		d.setDefaultHeader("Content-Type", "application/json")
+	}
+
+	if d.Config.TLS.IsSet() {
+		transport, err := newReloadingTransport(d.Config.TLS, d.logger())
+		if err != nil {
+			return nil, fmt.Errorf("failed to configure tls for destination %s: %w", d.Name, err)
+		}
+
+		d.client = newClient(transport)
+	}
+
+	if d.Config.OAuth2.TokenUrl.Host != "" {
+		d.auth = auth.NewClientCredentials(d.Config.OAuth2, d.client)
+	}
+
+	d.requestLimiter, d.eventLimiter = newRateLimiters(namedDestination.Destination)
+
+	d.endpoints = d.newEndpoints()
+
+	return d, nil
+}
+
+// NewDestinations creates every configured destination
+func NewDestinations(ctx context.Context) ([]*Destination, error) {
+	destinations := make([]*Destination, 0, len(config.Global.Destinations))
+
+	for _, namedDestination := range config.Global.Destinations {
+		d, err := NewDestination(ctx, namedDestination)
+		if err != nil {
+			return nil, err
+		}
+
+		destinations = append(destinations, d)
+	}
+
+	return destinations, nil
+}
+
+func (d *Destination) logger() *slog.Logger {
+	return logger.Stderr.With(slog.String("destination", d.Name))
+}
+
+// resolveCompression picks the compression preferred by the webhook mode unless one was configured explicitly
+func resolveCompression(destination config.Destination) config.Compression {
+	// payloads sent over sockets are never compressed as a whole
+	if config.IsSocketUrl(destination.WebhookMode, destination.WebhookUrl) {
+		return config.CompressionNone
+	}
+
+	// already compressed with snappy
+	if destination.WebhookMode == config.WebhookModeLoki && destination.Loki.Encoding == config.LokiEncodingProtobuf {
+		return config.CompressionNone
+	}
+
+	if destination.Compression != config.CompressionAuto {
+		return destination.Compression
+	}
+
+	if supported := config.WebhookModeToConfig[destination.WebhookMode].SupportedCompressions; len(supported) > 0 {
+		return supported[0]
+	}
+
+	return config.CompressionNone
+}
@@ -0,0 +1,55 @@
+package webhook
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+func TestResolveCompression(t *testing.T) {
+	tests := []struct {
+		name        string
+		destination config.Destination
+		want        config.Compression
+	}{
+		{
+			name:        "newrelic prefers gzip",
+			destination: config.Destination{WebhookMode: config.WebhookModeNewRelic, WebhookUrl: url.URL{Scheme: "https", Host: "log-api.newrelic.com"}},
+			want:        config.CompressionGzip,
+		},
+		{
+			name:        "newrelic with compression turned off",
+			destination: config.Destination{WebhookMode: config.WebhookModeNewRelic, WebhookUrl: url.URL{Scheme: "https", Host: "log-api.newrelic.com"}, Compression: config.CompressionNone},
+			want:        config.CompressionNone,
+		},
+		{
+			name:        "explicit compression",
+			destination: config.Destination{WebhookMode: config.WebhookModeJson, WebhookUrl: url.URL{Scheme: "https", Host: "logs.example.com"}, Compression: config.CompressionZstd},
+			want:        config.CompressionZstd,
+		},
+		{
+			name:        "mode without a preference",
+			destination: config.Destination{WebhookMode: config.WebhookModeJson, WebhookUrl: url.URL{Scheme: "https", Host: "logs.example.com"}},
+			want:        config.CompressionNone,
+		},
+		{
+			name:        "socket",
+			destination: config.Destination{WebhookMode: config.WebhookModeGelf, WebhookUrl: url.URL{Scheme: "udp", Host: "graylog:12201"}, Compression: config.CompressionGzip},
+			want:        config.CompressionNone,
+		},
+		{
+			name:        "loki protobuf is already compressed",
+			destination: config.Destination{WebhookMode: config.WebhookModeLoki, WebhookUrl: url.URL{Scheme: "https", Host: "loki.example.com"}, Loki: config.Loki{Encoding: config.LokiEncodingProtobuf}, Compression: config.CompressionGzip},
+			want:        config.CompressionNone,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := resolveCompression(tt.destination); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package generic
+
+import (
+	"strings"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/tidwall/gjson"
+)
+
+// the most of a response body read to check assertions and bulk responses against
+const maxResponseBodySize = 10 << 20
+
+// assertResponse checks the response body against every assertion, returning an AssertionError for the first one that does not hold
+func assertResponse(body []byte, assertions config.ResponseAssertions) error {
+	for _, assertion := range assertions {
+		actual := gjson.GetBytes(body, assertion.Path).String()
+
+		if (actual == assertion.Value) != assertion.Negate {
+			continue
+		}
+
+		return &AssertionError{
+			Assertion: assertion,
+			Actual:    actual,
+			Body:      strings.TrimSpace(string(body)),
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,40 @@
+package generic
+
+import "github.com/tidwall/gjson"
+
+// checkBulkResponse returns a BulkError if any item of a bulk api request failed, the request itself is answered with a success status code
+//
+// https://www.elastic.co/guide/en/elasticsearch/reference/current/docs-bulk.html#bulk-api-response-body
+func checkBulkResponse(body []byte) error {
+	response := gjson.ParseBytes(body)
+
+	if !response.Get("errors").Bool() {
+		return nil
+	}
+
+	bulkErr := &BulkError{}
+
+	for i, item := range response.Get("items").Array() {
+		// every item is an object keyed by its action, such as index or create
+		item.ForEach(func(_, result gjson.Result) bool {
+			status := int(result.Get("status").Int())
+
+			if status >= 300 || result.Get("error").Exists() {
+				bulkErr.Items = append(bulkErr.Items, i)
+				bulkErr.StatusCodes = append(bulkErr.StatusCodes, status)
+
+				if bulkErr.Body == "" {
+					bulkErr.Body = result.Get("error").Raw
+				}
+			}
+
+			return false
+		})
+	}
+
+	if len(bulkErr.Items) == 0 {
+		return nil
+	}
+
+	return bulkErr
+}
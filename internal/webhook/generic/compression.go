@@ -0,0 +1,81 @@
+package generic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"sync"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+var (
+	gzipWriters = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(nil)
+		},
+	}
+
+	zlibWriters = sync.Pool{
+		New: func() any {
+			return zlib.NewWriter(nil)
+		},
+	}
+
+	// EncodeAll is safe for concurrent use, so a single encoder is shared, it is created on first use so a failure to create it is returned by Compress
+	zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+		return zstd.NewWriter(nil)
+	})
+)
+
+// Compress encodes the payload with the given compression, the compression name doubles as the Content-Encoding header value
+func Compress(compression config.Compression, payload []byte) ([]byte, error) {
+	switch compression {
+	case config.CompressionGzip:
+		buf := bytes.Buffer{}
+
+		w := gzipWriters.Get().(*gzip.Writer)
+		defer gzipWriters.Put(w)
+
+		w.Reset(&buf)
+
+		if _, err := w.Write(payload); err != nil {
+			return nil, fmt.Errorf("failed to gzip payload: %w", err)
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to gzip payload: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	case config.CompressionDeflate:
+		// the deflate content coding is the zlib format, see RFC 9110 section 8.4.1.2
+		buf := bytes.Buffer{}
+
+		w := zlibWriters.Get().(*zlib.Writer)
+		defer zlibWriters.Put(w)
+
+		w.Reset(&buf)
+
+		if _, err := w.Write(payload); err != nil {
+			return nil, fmt.Errorf("failed to deflate payload: %w", err)
+		}
+
+		if err := w.Close(); err != nil {
+			return nil, fmt.Errorf("failed to deflate payload: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	case config.CompressionZstd:
+		encoder, err := zstdEncoder()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create zstd encoder: %w", err)
+		}
+
+		return encoder.EncodeAll(payload, make([]byte, 0, len(payload)/2)), nil
+	default:
+		return payload, nil
+	}
+}
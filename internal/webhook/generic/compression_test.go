@@ -0,0 +1,76 @@
+package generic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"testing"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestCompress(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"message":"hello from the api"}`+"\n"), 100)
+
+	tests := []struct {
+		name        string
+		compression config.Compression
+		decompress  func(r io.Reader) (io.Reader, error)
+	}{
+		{
+			name:        "none",
+			compression: config.CompressionNone,
+			decompress:  func(r io.Reader) (io.Reader, error) { return r, nil },
+		},
+		{
+			name:        "gzip",
+			compression: config.CompressionGzip,
+			decompress:  func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		},
+		{
+			name:        "deflate",
+			compression: config.CompressionDeflate,
+			decompress:  func(r io.Reader) (io.Reader, error) { return zlib.NewReader(r) },
+		},
+		{
+			name:        "zstd",
+			compression: config.CompressionZstd,
+			decompress: func(r io.Reader) (io.Reader, error) {
+				d, err := zstd.NewReader(r)
+				if err != nil {
+					return nil, err
+				}
+
+				return d.IOReadCloser(), nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// twice, so a pooled writer is reused
+			for range 2 {
+				body, err := Compress(tt.compression, payload)
+				if err != nil {
+					t.Fatalf("compress: %v", err)
+				}
+
+				r, err := tt.decompress(bytes.NewReader(body))
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+
+				got, err := io.ReadAll(r)
+				if err != nil {
+					t.Fatalf("decompress: %v", err)
+				}
+
+				if !bytes.Equal(got, payload) {
+					t.Fatalf("got %d bytes back, want the %d bytes of the payload", len(got), len(payload))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+package generic
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+// StatusError is returned when the webhook endpoint responds with a non success status code
+type StatusError struct {
+	StatusCode int
+	Body       string
+
+	// the duration the endpoint asked us to wait via the Retry-After header, zero if not present
+	RetryAfter time.Duration
+}
+
+func (e *StatusError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("non success status code: %d", e.StatusCode)
+	}
+
+	return fmt.Sprintf("non success status code: %d; with body: %s", e.StatusCode, e.Body)
+}
+
+// AssertionError is returned when the webhook endpoint responds with a success status code but the response body does not match a configured assertion
+type AssertionError struct {
+	Assertion config.ResponseAssertion
+	Actual    string
+	Body      string
+}
+
+func (e *AssertionError) Error() string {
+	return fmt.Sprintf("response assertion %s failed, got %q; with body: %s", e.Assertion, e.Actual, e.Body)
+}
+
+// BulkError is returned when a bulk api request is accepted but some of its items failed
+type BulkError struct {
+	// the positions of the failed items in the request, along with their status codes
+	Items       []int
+	StatusCodes []int
+	// the error of the first failed item
+	Body string
+}
+
+func (e *BulkError) Error() string {
+	return fmt.Sprintf("%d bulk items failed, first with status code: %d; with error: %s", len(e.Items), e.StatusCodes[0], e.Body)
+}
+
+// StatusError describes the failed items as a status error, using the status code of the first failed item
+func (e *BulkError) StatusError() *StatusError {
+	return &StatusError{
+		StatusCode: e.StatusCodes[0],
+		Body:       e.Body,
+	}
+}
+
+// AckError is returned when the splunk http event collector accepted a request but did not acknowledge it as indexed in time,
+// so whether the request was indexed is unknown
+type AckError struct {
+	AckId   int64
+	Timeout time.Duration
+
+	// whether the request should be sent again, which can index its events twice
+	Retry bool
+}
+
+func (e *AckError) Error() string {
+	return fmt.Sprintf("collector did not acknowledge ackId %d within %s", e.AckId, e.Timeout)
+}
+
+// parse a Retry-After header value, which can either be a number of seconds or a http date
+func parseRetryAfter(value string) time.Duration {
+	value = strings.TrimSpace(value)
+
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0)
+	}
+
+	return 0
+}
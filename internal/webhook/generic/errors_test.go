@@ -0,0 +1,37 @@
+package generic
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRetryAfter(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+
+		// the result of a http date is compared with some slack, as time passes between formatting and parsing it
+		wantMin time.Duration
+		wantMax time.Duration
+	}{
+		{name: "empty", value: "", wantMin: 0, wantMax: 0},
+		{name: "blank", value: "  ", wantMin: 0, wantMax: 0},
+		{name: "seconds", value: "120", wantMin: 2 * time.Minute, wantMax: 2 * time.Minute},
+		{name: "seconds with spaces", value: " 5 ", wantMin: 5 * time.Second, wantMax: 5 * time.Second},
+		{name: "zero seconds", value: "0", wantMin: 0, wantMax: 0},
+		{name: "negative seconds", value: "-5", wantMin: 0, wantMax: 0},
+		{name: "fractional seconds", value: "1.5", wantMin: 0, wantMax: 0},
+		{name: "garbage", value: "soon", wantMin: 0, wantMax: 0},
+		{name: "future date", value: time.Now().Add(time.Minute).UTC().Format(http.TimeFormat), wantMin: 58 * time.Second, wantMax: time.Minute},
+		{name: "past date", value: time.Now().Add(-time.Minute).UTC().Format(http.TimeFormat), wantMin: 0, wantMax: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseRetryAfter(tt.value); got < tt.wantMin || got > tt.wantMax {
+				t.Fatalf("got %s, want between %s and %s", got, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
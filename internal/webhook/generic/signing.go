@@ -0,0 +1,38 @@
+package generic
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+// signRequest sets the timestamp and signature headers when a signing secret is configured.
+//
+// The signature is the hex encoded HMAC-SHA256 of "<timestamp>.<body>" using the secret, sent as "sha256=<signature>".
+// The body is signed as sent, so receivers must verify it before decompressing it.
+func signRequest(req *http.Request, body []byte, signing config.Signing) {
+	if signing.Secret == "" {
+		return
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+
+	req.Header.Set(signing.TimestampHeader, timestamp)
+	req.Header.Set(signing.SignatureHeader, "sha256="+Signature(signing.Secret, timestamp, body))
+}
+
+// Signature returns the hex encoded HMAC-SHA256 of "<timestamp>.<body>"
+func Signature(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,107 @@
+package generic
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+func TestSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		body      string
+		want      string
+	}{
+		{
+			name:      "json body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      `{"message":"hello"}`,
+			want:      "b0455bec1c3de5765fb35f322efcc57ea51df2b13deea7d6dd7a0b8f9588fc05",
+		},
+		{
+			name:      "empty body",
+			secret:    "secret",
+			timestamp: "1700000000",
+			body:      "",
+			want:      "4bc5f74d868b97888288889c5d9d65df02526f94c1592a79fdf4fe8b26e311e5",
+		},
+		{
+			name:      "another secret",
+			secret:    "other",
+			timestamp: "1700000000",
+			body:      `{"message":"hello"}`,
+			want:      "fca44275714a98d17cb68118d2805fdc0e8e72681ea480625ba45eaff0f0228d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Signature(tt.secret, tt.timestamp, []byte(tt.body)); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSignRequest(t *testing.T) {
+	body := []byte(`{"message":"hello"}`)
+
+	tests := []struct {
+		name    string
+		signing config.Signing
+
+		wantSigned bool
+	}{
+		{
+			name:    "disabled without a secret",
+			signing: config.Signing{SignatureHeader: "X-Locomotive-Signature", TimestampHeader: "X-Locomotive-Timestamp"},
+		},
+		{
+			name:       "default headers",
+			signing:    config.Signing{Secret: "secret", SignatureHeader: "X-Locomotive-Signature", TimestampHeader: "X-Locomotive-Timestamp"},
+			wantSigned: true,
+		},
+		{
+			name:       "custom headers",
+			signing:    config.Signing{Secret: "secret", SignatureHeader: "X-Signature", TimestampHeader: "X-Timestamp"},
+			wantSigned: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(http.MethodPost, "https://logs.example.com", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signRequest(req, body, tt.signing)
+
+			timestamp := req.Header.Get(tt.signing.TimestampHeader)
+			signature := req.Header.Get(tt.signing.SignatureHeader)
+
+			if !tt.wantSigned {
+				if timestamp != "" || signature != "" {
+					t.Fatalf("got timestamp %q and signature %q, want the request left unsigned", timestamp, signature)
+				}
+
+				return
+			}
+
+			seconds, err := strconv.ParseInt(timestamp, 10, 64)
+			if err != nil || time.Since(time.Unix(seconds, 0)).Abs() > time.Minute {
+				t.Fatalf("got timestamp %q, want the current unix time", timestamp)
+			}
+
+			if want := "sha256=" + Signature(tt.signing.Secret, timestamp, body); signature != want {
+				t.Fatalf("got signature %q, want %q", signature, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,132 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_splunk"
+	"github.com/brody192/locomotive/internal/webhook/auth"
+	"github.com/tidwall/gjson"
+)
+
+// the http event collector ties requests and acknowledgements to the channel sent in this header
+const splunkChannelHeader = "X-Splunk-Request-Channel"
+
+const (
+	ackInitialInterval = 500 * time.Millisecond
+	ackMaxInterval     = 5 * time.Second
+)
+
+// waitForAck polls the collector until the request with the ackId in the given response body has been indexed, or the ack timeout passes
+//
+// https://docs.splunk.com/Documentation/Splunk/latest/Data/AboutHECIDXAck
+func waitForAck(ctx context.Context, body []byte, destination config.Destination, authProvider auth.Provider, client *http.Client) error {
+	ackId := gjson.GetBytes(body, "ackId")
+	if !ackId.Exists() {
+		return fmt.Errorf("collector response has no ackId, indexer acknowledgement must be enabled on the token: %s", strings.TrimSpace(string(body)))
+	}
+
+	ackUrl := url.URL{
+		Scheme:   destination.WebhookUrl.Scheme,
+		User:     destination.WebhookUrl.User,
+		Host:     destination.WebhookUrl.Host,
+		Path:     splunkAckPath(destination.WebhookUrl.Path),
+		RawQuery: url.Values{"channel": {destination.Splunk.Channel}}.Encode(),
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, destination.Splunk.AckTimeout)
+	defer cancel()
+
+	interval := ackInitialInterval
+
+	for {
+		select {
+		case <-ctx.Done():
+			if ctx.Err() == context.DeadlineExceeded {
+				return &AckError{AckId: ackId.Int(), Timeout: destination.Splunk.AckTimeout, Retry: destination.Splunk.AckRetry}
+			}
+
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		acked, err := pollAck(ctx, ackUrl, ackId.Int(), destination, authProvider, client)
+		if err != nil {
+			return err
+		}
+
+		if acked {
+			return nil
+		}
+
+		interval = min(interval*2, ackMaxInterval)
+	}
+}
+
+// splunkAckPath returns the path of the ack endpoint next to the configured collector path, keeping any prefix in front of it,
+// such as /splunk/services/collector/event behind a reverse proxy
+func splunkAckPath(collectorPath string) string {
+	if i := strings.LastIndex(collectorPath, "/services/collector"); i >= 0 {
+		return collectorPath[:i] + reconstruct_splunk.AckPath
+	}
+
+	// a custom path, the ack endpoint is expected to be its sibling
+	return path.Join(path.Dir(strings.TrimSuffix(collectorPath, "/")), "ack")
+}
+
+// pollAck asks the collector whether a single ackId has been indexed
+func pollAck(ctx context.Context, ackUrl url.URL, ackId int64, destination config.Destination, authProvider auth.Provider, client *http.Client) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, ackUrl.String(), strings.NewReader(fmt.Sprintf(`{"acks":[%d]}`, ackId)))
+	if err != nil {
+		return false, fmt.Errorf("failed to create ack request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	for key, value := range destination.AdditionalHeaders {
+		req.Header.Set(key, value)
+	}
+
+	req.Header.Set(splunkChannelHeader, destination.Splunk.Channel)
+
+	if authProvider != nil {
+		if err := authProvider.Authorize(ctx, req); err != nil {
+			return false, fmt.Errorf("failed to authorize ack request: %w", err)
+		}
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		// a deadline hit mid request is reported as an ack timeout by the caller
+		if ctx.Err() == context.DeadlineExceeded {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to send ack request: %w", err)
+	}
+
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBodySize))
+	if err != nil {
+		return false, fmt.Errorf("failed to read ack response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK {
+		return false, &StatusError{
+			StatusCode: res.StatusCode,
+			Body:       strings.TrimSpace(string(body)),
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+
+	return gjson.GetBytes(body, "acks."+strconv.FormatInt(ackId, 10)).Bool(), nil
+}
@@ -0,0 +1,179 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+func TestSplunkAckPath(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+		want string
+	}{
+		{name: "event endpoint", path: "/services/collector/event", want: "/services/collector/ack"},
+		{name: "raw endpoint", path: "/services/collector/raw", want: "/services/collector/ack"},
+		{name: "event endpoint with version", path: "/services/collector/event/1.0", want: "/services/collector/ack"},
+		{name: "behind a prefix", path: "/splunk/services/collector/event", want: "/splunk/services/collector/ack"},
+		{name: "custom path", path: "/custom/hec", want: "/custom/ack"},
+		{name: "custom path with trailing slash", path: "/custom/hec/", want: "/custom/ack"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splunkAckPath(tt.path); got != tt.want {
+				t.Fatalf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWaitForAck(t *testing.T) {
+	tests := []struct {
+		name string
+		// the response of the collector to the request being acknowledged
+		body       string
+		ackTimeout time.Duration
+		ackRetry   bool
+		// the poll the ackId is first reported as indexed on, zero means never
+		ackedOn int32
+		// the status of every ack response
+		status int
+
+		wantPolls int32
+		wantErr   func(error) bool
+	}{
+		{
+			name:       "acknowledged on the first poll",
+			body:       `{"text":"Success","code":0,"ackId":7}`,
+			ackTimeout: 5 * time.Second,
+			ackedOn:    1,
+			status:     http.StatusOK,
+			wantPolls:  1,
+		},
+		{
+			name:       "acknowledged on a later poll",
+			body:       `{"text":"Success","code":0,"ackId":7}`,
+			ackTimeout: 5 * time.Second,
+			ackedOn:    2,
+			status:     http.StatusOK,
+			wantPolls:  2,
+		},
+		{
+			name:       "no ackId in the response",
+			body:       `{"text":"Success","code":0}`,
+			ackTimeout: 5 * time.Second,
+			status:     http.StatusOK,
+			wantErr: func(err error) bool {
+				return err != nil && strings.Contains(err.Error(), "indexer acknowledgement must be enabled")
+			},
+		},
+		{
+			name:       "not acknowledged in time",
+			body:       `{"text":"Success","code":0,"ackId":7}`,
+			ackTimeout: 100 * time.Millisecond,
+			status:     http.StatusOK,
+			wantErr: func(err error) bool {
+				ackErr := &AckError{}
+				return errors.As(err, &ackErr) && ackErr.AckId == 7 && !ackErr.Retry
+			},
+		},
+		{
+			name:       "not acknowledged in time with retries",
+			body:       `{"text":"Success","code":0,"ackId":7}`,
+			ackTimeout: 100 * time.Millisecond,
+			ackRetry:   true,
+			status:     http.StatusOK,
+			wantErr: func(err error) bool {
+				ackErr := &AckError{}
+				return errors.As(err, &ackErr) && ackErr.Retry
+			},
+		},
+		{
+			name:       "ack endpoint failing",
+			body:       `{"text":"Success","code":0,"ackId":7}`,
+			ackTimeout: 5 * time.Second,
+			status:     http.StatusServiceUnavailable,
+			wantPolls:  1,
+			wantErr: func(err error) bool {
+				statusErr := &StatusError{}
+				return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusServiceUnavailable
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			polls := atomic.Int32{}
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				poll := polls.Add(1)
+
+				body, _ := io.ReadAll(r.Body)
+
+				if r.URL.Path != "/splunk/services/collector/ack" || r.URL.Query().Get("channel") != "channel-1" ||
+					r.Header.Get(splunkChannelHeader) != "channel-1" || string(body) != `{"acks":[7]}` {
+					t.Errorf("got ack request %s %s with channel header %q and body %s", r.Method, r.URL, r.Header.Get(splunkChannelHeader), body)
+				}
+
+				w.WriteHeader(tt.status)
+				fmt.Fprintf(w, `{"acks":{"7":%t}}`, tt.ackedOn != 0 && poll >= tt.ackedOn)
+			}))
+			t.Cleanup(server.Close)
+
+			webhookUrl, err := url.Parse(server.URL + "/splunk/services/collector/event")
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			destination := config.Destination{
+				WebhookUrl: *webhookUrl,
+				Splunk: config.Splunk{
+					Channel:    "channel-1",
+					Ack:        true,
+					AckTimeout: tt.ackTimeout,
+					AckRetry:   tt.ackRetry,
+				},
+			}
+
+			err = waitForAck(context.Background(), []byte(tt.body), destination, nil, server.Client())
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("got error %v, want none", err)
+			}
+
+			if tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("got error %v, want another", err)
+			}
+
+			if tt.wantPolls != 0 && polls.Load() != tt.wantPolls {
+				t.Fatalf("got %d polls, want %d", polls.Load(), tt.wantPolls)
+			}
+		})
+	}
+}
+
+func TestWaitForAckCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	destination := config.Destination{Splunk: config.Splunk{AckTimeout: time.Minute}}
+
+	err := waitForAck(ctx, []byte(`{"ackId":7}`), destination, nil, http.DefaultClient)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want %v", err, context.Canceled)
+	}
+}
@@ -2,16 +2,16 @@ package generic
 
 import (
 	"bytes"
+	"cmp"
+	"context"
 	"fmt"
 	"io"
 	"net/http"
-	"net/url"
 	"slices"
 	"strings"
 
 	"github.com/brody192/locomotive/internal/config"
-	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
-	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/webhook/auth"
 )
 
 var acceptedStatusCodes = []int{
@@ -21,29 +21,15 @@ var acceptedStatusCodes = []int{
 	http.StatusCreated,
 }
 
-func SendWebhookForDeployLogs(logs []environment_logs.EnvironmentLogWithMetadata, client *http.Client) (serializedLogs []byte, err error) {
-	payload, err := config.WebhookModeToConfig[config.Global.WebhookMode].EnvironmentLogReconstructorFunc(logs)
-
-	// fmt.Printf("Payload: %s\n", payload)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to reconstruct deploy log lines: %w", err)
-	}
-
-	return payload, sendRawWebhook(payload, config.Global.WebhookUrl, config.Global.AdditionalHeaders, client)
-}
-
-func SendWebhookForHttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata, client *http.Client) (serializedLogs []byte, err error) {
-	payload, err := config.WebhookModeToConfig[config.Global.WebhookMode].HTTPLogReconstructorFunc(logs)
-	if err != nil {
-		return nil, fmt.Errorf("failed to reconstruct http log lines: %w", err)
-	}
-
-	return payload, sendRawWebhook(payload, config.Global.WebhookUrl, config.Global.AdditionalHeaders, client)
+// SendRawWebhook sends an already serialized payload to the destination, contentEncoding is set as the Content-Encoding header if not empty.
+//
+// If authProvider is not nil it adds its credentials to the request.
+func SendRawWebhook(ctx context.Context, payload []byte, contentEncoding string, destination config.Destination, authProvider auth.Provider, client *http.Client) error {
+	return sendRawWebhook(ctx, payload, contentEncoding, destination, authProvider, client)
 }
 
-func sendRawWebhook(logs []byte, url url.URL, additionalHeaders config.AdditionalHeaders, client *http.Client) error {
-	req, err := http.NewRequest(http.MethodPost, url.String(), bytes.NewReader(logs))
+func sendRawWebhook(ctx context.Context, logs []byte, contentEncoding string, destination config.Destination, authProvider auth.Provider, client *http.Client) error {
+	req, err := http.NewRequestWithContext(ctx, cmp.Or(destination.HttpMethod, http.MethodPost), destination.WebhookUrl.String(), bytes.NewReader(logs))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
@@ -52,14 +38,31 @@ func sendRawWebhook(logs []byte, url url.URL, additionalHeaders config.Additiona
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Keep-Alive", "timeout=5, max=1000")
 
-	for key, value := range config.WebhookModeToConfig[config.Global.WebhookMode].Headers {
+	if contentEncoding != "" {
+		req.Header.Set("Content-Encoding", contentEncoding)
+	}
+
+	for key, value := range config.WebhookModeToConfig[destination.WebhookMode].Headers {
 		req.Header.Set(key, value)
 	}
 
-	for key, value := range additionalHeaders {
+	for key, value := range destination.AdditionalHeaders {
 		req.Header.Set(key, value)
 	}
 
+	if destination.WebhookMode == config.WebhookModeSplunk {
+		req.Header.Set(splunkChannelHeader, destination.Splunk.Channel)
+	}
+
+	if authProvider != nil {
+		if err := authProvider.Authorize(ctx, req); err != nil {
+			return fmt.Errorf("failed to authorize request: %w", err)
+		}
+	}
+
+	// signed on every attempt so retries carry a fresh timestamp
+	signRequest(req, logs, destination.Signing)
+
 	res, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send webhook request: %w", err)
@@ -67,15 +70,53 @@ func sendRawWebhook(logs []byte, url url.URL, additionalHeaders config.Additiona
 
 	defer res.Body.Close()
 
-	if !slices.Contains(acceptedStatusCodes, res.StatusCode) {
-		body, err := io.ReadAll(res.Body)
-		bodyStr := strings.TrimSpace(string(body))
-		if err != nil || len(bodyStr) == 0 {
-			return fmt.Errorf("non success status code: %d", res.StatusCode)
+	if !isAccepted(res.StatusCode, destination.AcceptedStatusCodes) {
+		statusErr := &StatusError{
+			StatusCode: res.StatusCode,
+			RetryAfter: parseRetryAfter(res.Header.Get("Retry-After")),
 		}
 
-		return fmt.Errorf("non success status code: %d; with body: %s", res.StatusCode, bodyStr)
+		if body, err := io.ReadAll(res.Body); err == nil {
+			statusErr.Body = strings.TrimSpace(string(body))
+		}
+
+		return statusErr
 	}
 
+	waitsForAck := destination.WebhookMode == config.WebhookModeSplunk && destination.Splunk.Ack
+
+	if len(destination.ResponseAssertions) > 0 || destination.WebhookMode == config.WebhookModeElasticsearch || waitsForAck {
+		body, err := io.ReadAll(io.LimitReader(res.Body, maxResponseBodySize))
+		if err != nil {
+			return fmt.Errorf("failed to read webhook response: %w", err)
+		}
+
+		if err := assertResponse(body, destination.ResponseAssertions); err != nil {
+			return err
+		}
+
+		if destination.WebhookMode == config.WebhookModeElasticsearch {
+			if err := checkBulkResponse(body); err != nil {
+				return err
+			}
+		}
+
+		if waitsForAck {
+			return waitForAck(ctx, body, destination, authProvider, client)
+		}
+	}
+
+	// drain the body so the connection can be reused
+	io.Copy(io.Discard, res.Body)
+
 	return nil
 }
+
+// isAccepted reports whether the status code counts as a success, the default accepted status codes are used if none are configured
+func isAccepted(statusCode int, accepted config.StatusCodes) bool {
+	if len(accepted) == 0 {
+		return slices.Contains(acceptedStatusCodes, statusCode)
+	}
+
+	return slices.Contains(accepted, statusCode)
+}
@@ -0,0 +1,45 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/ratelimit"
+)
+
+var ErrRateLimited = errors.New("rate limit exceeded, logs dropped")
+
+// newRateLimiters creates the request and event buckets for a destination, either is nil when its limit is not configured
+func newRateLimiters(destination config.Destination) (requests *ratelimit.Bucket, events *ratelimit.Bucket) {
+	return ratelimit.New(destination.RateLimitRequestsPerSecond, 0), ratelimit.New(destination.RateLimitEventsPerSecond, 0)
+}
+
+// acquire takes the rate limit budget for a single request carrying count logs.
+//
+// When block is false it returns ErrRateLimited right away if the budget is exhausted instead of waiting for it.
+func (d *Destination) acquire(ctx context.Context, count int, block bool) error {
+	if !block {
+		if !d.eventLimiter.TryTake(count) {
+			return ErrRateLimited
+		}
+
+		if !d.requestLimiter.TryTake(1) {
+			d.eventLimiter.Return(count)
+			return ErrRateLimited
+		}
+
+		return nil
+	}
+
+	if err := d.eventLimiter.Wait(ctx, count); err != nil {
+		return err
+	}
+
+	if err := d.requestLimiter.Wait(ctx, 1); err != nil {
+		d.eventLimiter.Return(count)
+		return err
+	}
+
+	return nil
+}
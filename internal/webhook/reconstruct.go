@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"fmt"
+	"maps"
+	"strings"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_elasticsearch"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_gelf"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_loki"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_otlp"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_splunk"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_syslog"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_template"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+)
+
+// setDefaultHeader adds a header to the destination's additional headers, unless the header is already set explicitly
+func (d *Destination) setDefaultHeader(key string, value string) {
+	for existing := range d.Config.AdditionalHeaders {
+		if strings.EqualFold(existing, key) {
+			return
+		}
+	}
+
+	headers := config.AdditionalHeaders{}
+	maps.Copy(headers, d.Config.AdditionalHeaders)
+	headers[key] = value
+
+	d.Config.AdditionalHeaders = headers
+}
+
+// the otlp webhook mode encodes with protobuf by default, json is picked per destination
+func (d *Destination) otlpJson() bool {
+	return d.Config.WebhookMode == config.WebhookModeOtlp && d.Config.OtlpEncoding == config.OtlpEncodingJson
+}
+
+// reconstructor turns logs into the payload of a request to a destination
+type reconstructor interface {
+	EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error)
+	HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error)
+}
+
+// modeReconstructor reconstructs payloads with fixed functions, for webhook modes that have no settings of their own
+type modeReconstructor struct {
+	environmentLogs func([]environment_logs.EnvironmentLogWithMetadata) ([]byte, error)
+	httpLogs        func([]http_logs.DeploymentHttpLogWithMetadata) ([]byte, error)
+}
+
+func (m modeReconstructor) EnvironmentLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	return m.environmentLogs(logs)
+}
+
+func (m modeReconstructor) HttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	return m.httpLogs(logs)
+}
+
+// newReconstructor builds the reconstructor for the webhook mode of the destination from its settings
+func newReconstructor(destination config.Destination) (reconstructor, error) {
+	switch destination.WebhookMode {
+	case config.WebhookModeTemplate:
+		template, err := reconstruct_template.New(destination.TemplateDeployLogs, destination.TemplateHttpLogs)
+		if err != nil {
+			return nil, fmt.Errorf("invalid template: %w", err)
+		}
+
+		return template, nil
+	case config.WebhookModeElasticsearch:
+		bulk, err := reconstruct_elasticsearch.New(destination.ElasticsearchIndex, destination.ElasticsearchDataStream)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index pattern: %w", err)
+		}
+
+		return bulk, nil
+	case config.WebhookModeSplunk:
+		splunk := destination.Splunk
+
+		hec, err := reconstruct_splunk.New(splunk.Host, splunk.Source, splunk.Sourcetype, splunk.Index, splunk.Endpoint == config.SplunkEndpointRaw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid splunk metadata pattern: %w", err)
+		}
+
+		return hec, nil
+	case config.WebhookModeSyslog:
+		s := destination.Syslog
+
+		syslog, err := reconstruct_syslog.New(s.Format == config.SyslogFormatRFC3164, int(s.Facility), s.Hostname, s.AppName)
+		if err != nil {
+			return nil, fmt.Errorf("invalid syslog pattern: %w", err)
+		}
+
+		return syslog, nil
+	case config.WebhookModeGelf:
+		// messages are framed on stream sockets, one message is sent per datagram or http request
+		return reconstruct_gelf.New(config.IsSocketUrl(destination.WebhookMode, destination.WebhookUrl)), nil
+	case config.WebhookModeLoki:
+		return reconstruct_loki.New(destination.Loki.Labels, destination.Loki.Encoding == config.LokiEncodingProtobuf), nil
+	case config.WebhookModeOtlp:
+		if destination.OtlpEncoding == config.OtlpEncodingJson {
+			return modeReconstructor{
+				environmentLogs: reconstruct_otlp.EnvironmentLogsJson,
+				httpLogs:        reconstruct_otlp.HttpLogsJson,
+			}, nil
+		}
+	}
+
+	mode := config.WebhookModeToConfig[destination.WebhookMode]
+
+	return modeReconstructor{
+		environmentLogs: mode.EnvironmentLogReconstructorFunc,
+		httpLogs:        mode.HTTPLogReconstructorFunc,
+	}, nil
+}
+
+func (d *Destination) reconstructDeployLogs(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+	payload, err := d.reconstructor.EnvironmentLogs(logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct deploy log lines: %w", err)
+	}
+
+	return payload, nil
+}
+
+func (d *Destination) reconstructHttpLogs(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+	payload, err := d.reconstructor.HttpLogs(logs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reconstruct http log lines: %w", err)
+	}
+
+	return payload, nil
+}
@@ -0,0 +1,142 @@
+package webhook
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+)
+
+func testEnvironmentLogs() []environment_logs.EnvironmentLogWithMetadata {
+	return []environment_logs.EnvironmentLogWithMetadata{{
+		Log: subscriptions.EnvironmentLog{
+			Timestamp: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			Message:   "hello from the api",
+			Severity:  "info",
+		},
+		Metadata: environment_logs.EnvironmentLogMetadata{
+			"project_name":     "shop",
+			"environment_name": "production",
+			"service_name":     "api",
+			"log_type":         "deploy",
+		},
+	}}
+}
+
+func TestNewReconstructor(t *testing.T) {
+	templatePath := filepath.Join(t.TempDir(), "deploy.tmpl")
+
+	if err := os.WriteFile(templatePath, []byte(`{{ range .Logs }}{{ .Log.Message }}{{ end }}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		destination config.Destination
+
+		wantErr string
+		// a part of the payload reconstructed from testEnvironmentLogs
+		wantPayload string
+	}{
+		{
+			name:        "json",
+			destination: config.Destination{WebhookMode: config.WebhookModeJson},
+			wantPayload: `"hello from the api"`,
+		},
+		{
+			name:        "template",
+			destination: config.Destination{WebhookMode: config.WebhookModeTemplate, TemplateDeployLogs: templatePath},
+			wantPayload: "hello from the api",
+		},
+		{
+			name:        "template that does not exist",
+			destination: config.Destination{WebhookMode: config.WebhookModeTemplate, TemplateDeployLogs: filepath.Join(t.TempDir(), "missing.tmpl")},
+			wantErr:     "invalid template",
+		},
+		{
+			name:        "elasticsearch",
+			destination: config.Destination{WebhookMode: config.WebhookModeElasticsearch, ElasticsearchIndex: "logs-{service_name}"},
+			wantPayload: `"logs-api"`,
+		},
+		{
+			name:        "elasticsearch with an invalid index pattern",
+			destination: config.Destination{WebhookMode: config.WebhookModeElasticsearch, ElasticsearchIndex: "logs-{service_name"},
+			wantErr:     "invalid index pattern",
+		},
+		{
+			name: "splunk",
+			destination: config.Destination{WebhookMode: config.WebhookModeSplunk, Splunk: config.Splunk{
+				Host:     "{project_name}-{environment_name}",
+				Endpoint: config.SplunkEndpointEvent,
+			}},
+			wantPayload: `"shop-production"`,
+		},
+		{
+			name: "syslog",
+			destination: config.Destination{WebhookMode: config.WebhookModeSyslog, Syslog: config.Syslog{
+				Format:   config.SyslogFormatRFC5424,
+				Facility: 1,
+				Hostname: "{project_name}",
+				AppName:  "{service_name}",
+			}},
+			wantPayload: "shop api",
+		},
+		{
+			name:        "gelf",
+			destination: config.Destination{WebhookMode: config.WebhookModeGelf, WebhookUrl: url.URL{Scheme: "udp", Host: "graylog:12201"}},
+			wantPayload: `"hello from the api"`,
+		},
+		{
+			name:        "loki",
+			destination: config.Destination{WebhookMode: config.WebhookModeLoki, Loki: config.Loki{Labels: []string{"service_name"}, Encoding: config.LokiEncodingJson}},
+			wantPayload: `"service_name":"api"`,
+		},
+		{
+			name:        "otlp json",
+			destination: config.Destination{WebhookMode: config.WebhookModeOtlp, OtlpEncoding: config.OtlpEncodingJson},
+			wantPayload: `"resourceLogs"`,
+		},
+		{
+			name:        "otlp protobuf",
+			destination: config.Destination{WebhookMode: config.WebhookModeOtlp, OtlpEncoding: config.OtlpEncodingProtobuf},
+			wantPayload: "hello from the api",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := newReconstructor(tt.destination)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+
+				if r != nil {
+					t.Fatalf("got a reconstructor along with an error")
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("new reconstructor: %v", err)
+			}
+
+			payload, err := r.EnvironmentLogs(testEnvironmentLogs())
+			if err != nil {
+				t.Fatalf("reconstruct: %v", err)
+			}
+
+			if !strings.Contains(string(payload), tt.wantPayload) {
+				t.Fatalf("got payload %q, want it to contain %q", payload, tt.wantPayload)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/deadletter"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+)
+
+type claimedFile struct {
+	path    string
+	entries []deadletter.Entry
+}
+
+// RedriveResult counts what happened to the dead lettered entries during a re-drive
+type RedriveResult struct {
+	Delivered int
+	Failed    int
+	Skipped   int
+}
+
+// RedriveDeadLetters re-sends every entry in LOCOMOTIVE_DEAD_LETTER_DIR to its destination using the current configuration,
+// entries that fail again, or whose destination is filtered out or no longer configured, are written back to the directory.
+//
+// If destinationFilter is empty entries for every destination are re-driven.
+func RedriveDeadLetters(ctx context.Context, destinationFilter string) (RedriveResult, error) {
+	result := RedriveResult{}
+
+	if config.Global.DeadLetterDir == "" {
+		return result, errors.New("LOCOMOTIVE_DEAD_LETTER_DIR must be set to re-drive dead lettered payloads")
+	}
+
+	recovered, err := deadletter.Recover(config.Global.DeadLetterDir)
+	if err != nil {
+		return result, err
+	}
+
+	if recovered > 0 {
+		logger.Stderr.Warn("recovered dead letter files left claimed by an interrupted re-drive", slog.Int("file_count", recovered))
+	}
+
+	files, err := deadletter.Files(config.Global.DeadLetterDir)
+	if err != nil {
+		return result, err
+	}
+
+	// failed entries are written back to the directory so they can be re-driven again later, without a file limit,
+	// since rotating out the oldest files could remove entries that were just written back
+	writer, err := deadletter.NewWriter(config.Global.DeadLetterDir, int64(config.Global.DeadLetterMaxFileSize), 0)
+	if err != nil {
+		return result, err
+	}
+
+	// spools are not opened, entries are sent inline so the outcome is known before the file is released
+	destinations := map[string]*Destination{}
+
+	for _, namedDestination := range config.Global.Destinations {
+		d, err := newDestination(namedDestination)
+		if err != nil {
+			return result, err
+		}
+
+		destinations[namedDestination.Name] = d
+	}
+
+	// every file is claimed before any entry is written back, claimed files are out of reach of the rotation of any writer
+	claimed := make([]claimedFile, 0, len(files))
+
+	// files not released by the time of an error are handed back, the entries of the file being re-driven may then be written twice
+	defer func() {
+		unclaim(claimed)
+	}()
+
+	for _, file := range files {
+		entries, claimedPath, err := deadletter.Claim(file)
+		if err != nil {
+			return result, err
+		}
+
+		claimed = append(claimed, claimedFile{path: claimedPath, entries: entries})
+	}
+
+	for len(claimed) > 0 {
+		entries, claimedPath := claimed[0].entries, claimed[0].path
+
+		for _, entry := range entries {
+			if ctx.Err() != nil {
+				result.Skipped++
+
+				if err := writer.Write(entry); err != nil {
+					return result, fmt.Errorf("failed to write back dead letter entry: %w", err)
+				}
+
+				continue
+			}
+
+			d, ok := destinations[entry.Destination]
+			if !ok || (destinationFilter != "" && entry.Destination != destinationFilter) {
+				if !ok {
+					logger.Stderr.Warn("dead lettered entry belongs to a destination that is no longer configured, keeping it",
+						slog.String("destination", entry.Destination),
+					)
+				}
+
+				result.Skipped++
+
+				if err := writer.Write(entry); err != nil {
+					return result, fmt.Errorf("failed to write back dead letter entry: %w", err)
+				}
+
+				continue
+			}
+
+			if entry.Mode != string(d.Config.WebhookMode) {
+				d.logger().Warn("dead lettered entry was serialized for a different webhook mode than the one configured",
+					slog.String("entry_mode", entry.Mode),
+					slog.String("webhook_mode", string(d.Config.WebhookMode)),
+				)
+			}
+
+			err := d.acquire(ctx, entry.LogCount, true)
+			if err == nil {
+				err = d.send(ctx, entry.LogCount, entry.Payload)
+			}
+
+			if err != nil {
+				d.logger().Error("error re-driving dead lettered entry",
+					slog.String("log_kind", entry.LogKind),
+					slog.Int("log_count", entry.LogCount),
+					logger.ErrAttr(err),
+				)
+
+				result.Failed++
+
+				entry.Payload, entry.LogCount = undelivered(entry.Payload, entry.LogCount, err)
+				entry.Error = err.Error()
+				entry.StatusCode = 0
+				entry.ResponseBody = ""
+
+				var statusErr *generic.StatusError
+				if errors.As(err, &statusErr) {
+					entry.StatusCode = statusErr.StatusCode
+					entry.ResponseBody = statusErr.Body
+				}
+
+				if err := writer.Write(entry); err != nil {
+					return result, fmt.Errorf("failed to write back dead letter entry: %w", err)
+				}
+
+				continue
+			}
+
+			result.Delivered++
+		}
+
+		if err := deadletter.Release(claimedPath); err != nil {
+			return result, fmt.Errorf("failed to remove re-driven dead letter file: %w", err)
+		}
+
+		claimed = claimed[1:]
+	}
+
+	return result, nil
+}
+
+func unclaim(claimed []claimedFile) {
+	for _, c := range claimed {
+		if err := deadletter.Unclaim(c.path); err != nil {
+			logger.Stderr.Error("error handing back claimed dead letter file", slog.String("path", c.path), logger.ErrAttr(err))
+		}
+	}
+}
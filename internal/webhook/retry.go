@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/webhook/auth"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+	"github.com/brody192/locomotive/internal/webhook/socket"
+	"github.com/sethvargo/go-retry"
+)
+
+var retryableStatusCodes = []int{
+	http.StatusRequestTimeout,
+	http.StatusTooManyRequests,
+}
+
+// isRetryable reports whether a failed send should be attempted again.
+//
+// 408, 429 and 5xx responses from the destination or its token endpoint, failed response assertions, network errors
+// and, when SPLUNK_ACK_RETRY is set, unacknowledged splunk requests are retryable, while any other status code is treated as permanent.
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *generic.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= 500 || slices.Contains(retryableStatusCodes, statusErr.StatusCode)
+	}
+
+	// a bulk request is retried while any of its failed items can succeed on a later attempt
+	var bulkErr *generic.BulkError
+	if errors.As(err, &bulkErr) {
+		return slices.ContainsFunc(bulkErr.StatusCodes, func(statusCode int) bool {
+			return statusCode >= 500 || slices.Contains(retryableStatusCodes, statusCode)
+		})
+	}
+
+	// the endpoint accepted the request but reported a failure in its response, which is usually transient
+	var assertionErr *generic.AssertionError
+	if errors.As(err, &assertionErr) {
+		return true
+	}
+
+	// the request may still be indexed, it is only sent again when configured to trade a possible duplicate for guaranteed delivery
+	var ackErr *generic.AckError
+	if errors.As(err, &ackErr) {
+		return ackErr.Retry
+	}
+
+	var tokenErr *auth.TokenError
+	if errors.As(err, &tokenErr) {
+		return tokenErr.StatusCode >= 500 || slices.Contains(retryableStatusCodes, tokenErr.StatusCode)
+	}
+
+	var socketErr *socket.Error
+	if errors.As(err, &socketErr) {
+		return true
+	}
+
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
+
+// builds a new backoff from the configured retry settings, the returned duration pointer can be set to honor a Retry-After header for the next attempt
+func newBackoff() (retry.Backoff, *time.Duration) {
+	retryAfter := new(time.Duration)
+
+	b := retry.NewExponential(config.Global.RetryInitialInterval)
+
+	// the jitter backoff panics on a zero percentage
+	if config.Global.RetryJitterPercent > 0 {
+		b = retry.WithJitterPercent(config.Global.RetryJitterPercent, b)
+	}
+
+	b = retry.WithCappedDuration(config.Global.RetryMaxInterval, b)
+
+	next := b
+	b = retry.BackoffFunc(func() (time.Duration, bool) {
+		val, stop := next.Next()
+		if stop {
+			return 0, true
+		}
+
+		if *retryAfter > val {
+			val = *retryAfter
+		}
+
+		*retryAfter = 0
+
+		return val, false
+	})
+
+	b = retry.WithMaxRetries(config.Global.RetryMaxAttempts-1, b)
+	b = retry.WithMaxDuration(config.Global.RetryMaxElapsedTime, b)
+
+	return b, retryAfter
+}
+
+// sendWithRetry calls send until it succeeds, returns a permanent error, or the retry budget is exhausted
+func (d *Destination) sendWithRetry(ctx context.Context, send func(ctx context.Context) error) error {
+	b, retryAfter := newBackoff()
+
+	attempt := 0
+
+	return retry.Do(ctx, b, func(ctx context.Context) error {
+		attempt++
+
+		if attempt > 1 {
+			d.Stats.Retries.Add(1)
+		}
+
+		err := send(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if !isRetryable(err) {
+			return err
+		}
+
+		var statusErr *generic.StatusError
+		if errors.As(err, &statusErr) {
+			*retryAfter = statusErr.RetryAfter
+		}
+
+		logger.Stdout.Debug("webhook request failed, retrying",
+			slog.String("destination", d.Name),
+			slog.Int("attempt", attempt),
+			logger.ErrAttr(err),
+		)
+
+		return retry.RetryableError(err)
+	})
+}
+
+// isUnauthorized reports whether the destination rejected the credentials of a request
+func isUnauthorized(err error) bool {
+	var statusErr *generic.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnauthorized
+}
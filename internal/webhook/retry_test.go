@@ -0,0 +1,208 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/webhook/auth"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+	"github.com/brody192/locomotive/internal/webhook/socket"
+)
+
+// setRetryConfig sets the global retry settings for the duration of the test
+func setRetryConfig(t *testing.T, maxAttempts uint64, initialInterval, maxInterval, maxElapsedTime time.Duration) {
+	t.Helper()
+
+	previous := config.Global
+
+	config.Global.RetryMaxAttempts = maxAttempts
+	config.Global.RetryInitialInterval = initialInterval
+	config.Global.RetryMaxInterval = maxInterval
+	config.Global.RetryMaxElapsedTime = maxElapsedTime
+	config.Global.RetryJitterPercent = 0
+
+	t.Cleanup(func() { config.Global = previous })
+}
+
+func TestIsRetryable(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "cancelled", err: context.Canceled, want: false},
+		{name: "wrapped cancellation", err: fmt.Errorf("send: %w", context.Canceled), want: false},
+		{name: "request timeout", err: &generic.StatusError{StatusCode: 408}, want: true},
+		{name: "too many requests", err: &generic.StatusError{StatusCode: 429}, want: true},
+		{name: "internal server error", err: &generic.StatusError{StatusCode: 500}, want: true},
+		{name: "service unavailable", err: &generic.StatusError{StatusCode: 503}, want: true},
+		{name: "bad request", err: &generic.StatusError{StatusCode: 400}, want: false},
+		{name: "unauthorized", err: &generic.StatusError{StatusCode: 401}, want: false},
+		{name: "not found", err: &generic.StatusError{StatusCode: 404}, want: false},
+		{name: "wrapped status", err: fmt.Errorf("send: %w", &generic.StatusError{StatusCode: 502}), want: true},
+		{name: "bulk items with a retryable status", err: &generic.BulkError{Items: []int{0, 1}, StatusCodes: []int{400, 429}}, want: true},
+		{name: "bulk items only rejected", err: &generic.BulkError{Items: []int{0, 1}, StatusCodes: []int{400, 409}}, want: false},
+		{name: "failed assertion", err: &generic.AssertionError{}, want: true},
+		{name: "unacknowledged", err: &generic.AckError{}, want: false},
+		{name: "unacknowledged with retry", err: &generic.AckError{Retry: true}, want: true},
+		{name: "token endpoint unavailable", err: &auth.TokenError{StatusCode: 503}, want: true},
+		{name: "token endpoint rejected", err: &auth.TokenError{StatusCode: 400}, want: false},
+		{name: "socket", err: &socket.Error{Op: "dial", Err: errors.New("connection refused")}, want: true},
+		{name: "network", err: &url.Error{Op: "Post", URL: "https://example.com", Err: errors.New("connection reset")}, want: true},
+		{name: "anything else", err: errors.New("failed to encode payload"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Fatalf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewBackoff(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxAttempts uint64
+		// set as the Retry-After of the attempt before each wait, zero leaves it unset
+		retryAfter []time.Duration
+
+		want []time.Duration
+	}{
+		{
+			name:        "single attempt never waits",
+			maxAttempts: 1,
+			want:        []time.Duration{},
+		},
+		{
+			name:        "doubles up to the maximum interval",
+			maxAttempts: 6,
+			want:        []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 400 * time.Millisecond, 500 * time.Millisecond, 500 * time.Millisecond},
+		},
+		{
+			name:        "a longer Retry-After replaces one wait",
+			maxAttempts: 4,
+			retryAfter:  []time.Duration{0, 2 * time.Second, 0},
+			want:        []time.Duration{100 * time.Millisecond, 2 * time.Second, 400 * time.Millisecond},
+		},
+		{
+			name:        "a shorter Retry-After is ignored",
+			maxAttempts: 3,
+			retryAfter:  []time.Duration{10 * time.Millisecond, 0},
+			want:        []time.Duration{100 * time.Millisecond, 200 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRetryConfig(t, tt.maxAttempts, 100*time.Millisecond, 500*time.Millisecond, time.Hour)
+
+			b, retryAfter := newBackoff()
+
+			got := []time.Duration{}
+
+			for {
+				if len(got) < len(tt.retryAfter) {
+					*retryAfter = tt.retryAfter[len(got)]
+				}
+
+				wait, stop := b.Next()
+				if stop {
+					break
+				}
+
+				got = append(got, wait)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("got waits %v, want %v", got, tt.want)
+			}
+
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got waits %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestSendWithRetry(t *testing.T) {
+	errRejected := &generic.StatusError{StatusCode: 400}
+	errUnavailable := &generic.StatusError{StatusCode: 503}
+
+	tests := []struct {
+		name string
+		// the error returned by each attempt, attempts past the end succeed
+		errs []error
+
+		wantErr      error
+		wantAttempts int
+	}{
+		{
+			name:         "first attempt succeeds",
+			wantAttempts: 1,
+		},
+		{
+			name:         "succeeds after retryable failures",
+			errs:         []error{errUnavailable, errUnavailable},
+			wantAttempts: 3,
+		},
+		{
+			name:         "permanent failure is not retried",
+			errs:         []error{errRejected},
+			wantErr:      errRejected,
+			wantAttempts: 1,
+		},
+		{
+			name:         "permanent failure after a retryable one",
+			errs:         []error{errUnavailable, errRejected},
+			wantErr:      errRejected,
+			wantAttempts: 2,
+		},
+		{
+			name:         "gives up after the maximum attempts",
+			errs:         []error{errUnavailable, errUnavailable, errUnavailable, errUnavailable},
+			wantErr:      errUnavailable,
+			wantAttempts: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setRetryConfig(t, 3, time.Millisecond, time.Millisecond, time.Hour)
+
+			d := &Destination{Name: "test"}
+
+			attempts := 0
+
+			err := d.sendWithRetry(context.Background(), func(ctx context.Context) error {
+				attempts++
+
+				if attempts <= len(tt.errs) {
+					return tt.errs[attempts-1]
+				}
+
+				return nil
+			})
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if attempts != tt.wantAttempts {
+				t.Fatalf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+
+			if got := d.Stats.Retries.Load(); got != int64(tt.wantAttempts-1) {
+				t.Fatalf("got %d retries counted, want %d", got, tt.wantAttempts-1)
+			}
+		})
+	}
+}
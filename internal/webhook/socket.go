@@ -0,0 +1,59 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"net/url"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_gelf"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_syslog"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+	"github.com/brody192/locomotive/internal/webhook/socket"
+)
+
+// newSocket returns a connection for endpoints whose url has a socket scheme of the webhook mode, nil for http endpoints
+func (d *Destination) newSocket(u url.URL) *socket.Conn {
+	if !config.IsSocketUrl(d.Config.WebhookMode, u) {
+		return nil
+	}
+
+	var proxy *url.URL
+
+	// sockets only go through a socks5 proxy, config validation rejects any other proxy for them
+	if config.Global.WebhookProxyUrl.Host != "" {
+		proxy = &config.Global.WebhookProxyUrl
+	}
+
+	return socket.New(u, proxy, func() (*tls.Config, error) {
+		return newTLSConfig(d.Config.TLS)
+	}, d.datagrams)
+}
+
+// datagrams splits a payload into the datagrams it is sent as over udp
+func (d *Destination) datagrams(payload []byte) ([][]byte, error) {
+	switch d.Config.WebhookMode {
+	case config.WebhookModeSyslog:
+		// RFC 5426 sends every message in its own datagram, without octet counting
+		return reconstruct_syslog.Frames(payload)
+	case config.WebhookModeGelf:
+		datagrams := [][]byte{}
+
+		for _, message := range reconstruct_gelf.Messages(payload) {
+			compressed, err := generic.Compress(config.CompressionGzip, message)
+			if err != nil {
+				return nil, err
+			}
+
+			chunks, err := reconstruct_gelf.Chunks(compressed)
+			if err != nil {
+				return nil, err
+			}
+
+			datagrams = append(datagrams, chunks...)
+		}
+
+		return datagrams, nil
+	default:
+		return [][]byte{payload}, nil
+	}
+}
@@ -0,0 +1,227 @@
+// Package socket sends payloads over plain tcp, tls and udp connections for destinations that do not speak http
+package socket
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	NetworkTCP = "tcp"
+	NetworkTLS = "tls"
+	NetworkUDP = "udp"
+)
+
+const (
+	dialTimeout  = 5 * time.Second
+	writeTimeout = 20 * time.Second
+)
+
+// Error wraps every failure to dial or write, all of which point to an unavailable endpoint
+type Error struct {
+	Op  string
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("failed to %s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Conn is a connection to a single endpoint that is dialed on first use and dialed again after any failure
+type Conn struct {
+	network string
+	address string
+
+	// socks5 or socks5h proxy that stream connections are dialed through, nil to dial directly
+	proxy *url.URL
+
+	// called on every dial so that changed certificate files are picked up on reconnect
+	tlsConfig func() (*tls.Config, error)
+
+	// splits a payload into its datagrams, stream networks write the payload as is
+	datagrams func(payload []byte) ([][]byte, error)
+
+	mu   sync.Mutex
+	conn net.Conn
+
+	// closed by the reader of a stream connection once the other end closed it, nil for datagram networks
+	peerClosed chan struct{}
+}
+
+// New creates a connection to the host and port of the url, whose scheme must be one of tcp, tls or udp.
+// A non nil proxy must have a socks5 or socks5h scheme and is only used by tcp and tls, udp is always sent directly.
+func New(u url.URL, proxy *url.URL, tlsConfig func() (*tls.Config, error), datagrams func(payload []byte) ([][]byte, error)) *Conn {
+	return &Conn{
+		network:   u.Scheme,
+		address:   u.Host,
+		proxy:     proxy,
+		tlsConfig: tlsConfig,
+		datagrams: datagrams,
+	}
+}
+
+// Send writes the payload, on a datagram network every datagram of the payload is written on its own
+func (c *Conn) Send(ctx context.Context, payload []byte) error {
+	frames := [][]byte{payload}
+
+	if c.network == NetworkUDP && c.datagrams != nil {
+		datagrams, err := c.datagrams(payload)
+		if err != nil {
+			// not a socket error, the payload can not be sent on any attempt
+			return err
+		}
+
+		frames = datagrams
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// a write to a connection the other end already closed can succeed while the data is lost, so it is replaced up front
+	select {
+	case <-c.peerClosed:
+		c.closeLocked()
+	default:
+	}
+
+	if c.conn == nil {
+		conn, err := c.dial(ctx)
+		if err != nil {
+			return &Error{Op: "dial " + c.address, Err: err}
+		}
+
+		c.conn = conn
+
+		if c.network != NetworkUDP {
+			c.peerClosed = make(chan struct{})
+
+			go watchPeer(conn, c.peerClosed)
+		}
+	}
+
+	deadline := time.Now().Add(writeTimeout)
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	c.conn.SetWriteDeadline(deadline)
+
+	// a cancelled context interrupts a blocked write
+	stop := context.AfterFunc(ctx, func() {
+		c.conn.SetWriteDeadline(time.Now())
+	})
+	defer stop()
+
+	for _, frame := range frames {
+		if _, err := c.conn.Write(frame); err != nil {
+			// the connection is in an unknown state after a failed write, so the next send starts over on a new one
+			c.closeLocked()
+
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			return &Error{Op: "write to " + c.address, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func (c *Conn) dial(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: 5 * time.Minute,
+	}
+
+	if c.proxy == nil || c.network == NetworkUDP {
+		if c.network != NetworkTLS {
+			return dialer.DialContext(ctx, c.network, c.address)
+		}
+
+		tlsConfig, err := c.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+
+		return (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", c.address)
+	}
+
+	conn, err := dialSocks5(ctx, dialer, c.proxy, c.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if c.network != NetworkTLS {
+		return conn, nil
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the tls dialer fills in the server name from the address, a connection from the proxy has to be told
+	if tlsConfig.ServerName == "" {
+		host, _, err := net.SplitHostPort(c.address)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		tlsConfig = tlsConfig.Clone()
+		tlsConfig.ServerName = host
+	}
+
+	handshakeCtx, cancel := context.WithTimeout(ctx, dialTimeout)
+	defer cancel()
+
+	tlsConn := tls.Client(conn, tlsConfig)
+
+	if err := tlsConn.HandshakeContext(handshakeCtx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return tlsConn, nil
+}
+
+// Close closes the current connection, a later send dials a new one
+func (c *Conn) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.closeLocked()
+}
+
+func (c *Conn) closeLocked() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	err := c.conn.Close()
+	c.conn = nil
+	c.peerClosed = nil
+
+	return err
+}
+
+// watchPeer reads from a stream connection in the background until it is closed or broken, then closes peerClosed.
+// Receivers never write to us, so anything read is discarded and the read only returns once the connection ends.
+func watchPeer(conn net.Conn, peerClosed chan struct{}) {
+	defer close(peerClosed)
+
+	io.Copy(io.Discard, conn)
+}
@@ -0,0 +1,227 @@
+package socket
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+	"time"
+)
+
+// listen accepts stream connections on the loopback address and hands them to the test, wrapped in tls if serverConfig is set
+func listen(t *testing.T, serverConfig *tls.Config) (string, <-chan net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if serverConfig != nil {
+		listener = tls.NewListener(listener, serverConfig)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	accepted := make(chan net.Conn, 4)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			t.Cleanup(func() { conn.Close() })
+
+			// the client waits for the handshake while dialing, before the test reads anything
+			if tlsConn, ok := conn.(*tls.Conn); ok {
+				if err := tlsConn.Handshake(); err != nil {
+					continue
+				}
+			}
+
+			accepted <- conn
+		}
+	}()
+
+	return listener.Addr().String(), accepted
+}
+
+// receive reads exactly the wanted bytes from the next accepted connection and returns it
+func receive(t *testing.T, accepted <-chan net.Conn, want string) net.Conn {
+	t.Helper()
+
+	var conn net.Conn
+
+	select {
+	case conn = <-accepted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("got no connection, want one")
+	}
+
+	read(t, conn, want)
+
+	return conn
+}
+
+// read reads exactly the wanted bytes from the connection
+func read(t *testing.T, conn net.Conn, want string) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	got := make([]byte, len(want))
+
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+
+	if string(got) != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+// testTLSConfigs returns a server config with a certificate for the loopback address and a client config trusting it
+func testTLSConfigs(t *testing.T) (*tls.Config, *tls.Config) {
+	t.Helper()
+
+	// the test server only lends its certificate and the client trusting it
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	server.Close()
+
+	return server.TLS, server.Client().Transport.(*http.Transport).TLSClientConfig
+}
+
+func TestSendStream(t *testing.T) {
+	serverConfig, clientConfig := testTLSConfigs(t)
+
+	tests := []struct {
+		network      string
+		serverConfig *tls.Config
+	}{
+		{network: NetworkTCP},
+		{network: NetworkTLS, serverConfig: serverConfig},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.network, func(t *testing.T) {
+			address, accepted := listen(t, tt.serverConfig)
+
+			c := New(url.URL{Scheme: tt.network, Host: address}, nil, func() (*tls.Config, error) {
+				return clientConfig, nil
+			}, nil)
+			t.Cleanup(func() { c.Close() })
+
+			ctx := context.Background()
+
+			// payloads are written as is, one after another on the same connection
+			if err := c.Send(ctx, []byte("5 first")); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			first := receive(t, accepted, "5 first")
+
+			if err := c.Send(ctx, []byte("6 second")); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			read(t, first, "6 second")
+
+			// once the receiver closes the connection the next send dials a new one
+			first.Close()
+
+			c.mu.Lock()
+			peerClosed := c.peerClosed
+			c.mu.Unlock()
+
+			select {
+			case <-peerClosed:
+			case <-time.After(5 * time.Second):
+				t.Fatal("got the closed connection unnoticed, want it noticed")
+			}
+
+			if err := c.Send(ctx, []byte("5 third")); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+
+			receive(t, accepted, "5 third")
+		})
+	}
+}
+
+func TestSendDatagrams(t *testing.T) {
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	// every comma separated part of a payload is its own datagram
+	datagrams := func(payload []byte) ([][]byte, error) {
+		if len(payload) == 0 {
+			return nil, errors.New("empty payload")
+		}
+
+		return bytes.Split(payload, []byte(",")), nil
+	}
+
+	c := New(url.URL{Scheme: NetworkUDP, Host: listener.LocalAddr().String()}, nil, nil, datagrams)
+	t.Cleanup(func() { c.Close() })
+
+	if err := c.Send(context.Background(), []byte("a,bb,ccc")); err != nil {
+		t.Fatalf("send: %v", err)
+	}
+
+	got := []string{}
+	buf := make([]byte, 64)
+
+	for range 3 {
+		listener.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		n, _, err := listener.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+
+		got = append(got, string(buf[:n]))
+	}
+
+	if want := []string{"a", "bb", "ccc"}; !slices.Equal(got, want) {
+		t.Fatalf("got datagrams %q, want %q", got, want)
+	}
+
+	// a payload that can not be split is not a socket error, sending it again would fail the same way
+	socketErr := &Error{}
+
+	if err := c.Send(context.Background(), nil); err == nil || errors.As(err, &socketErr) {
+		t.Fatalf("got error %v, want one that is not a socket error", err)
+	}
+}
+
+func TestSendDialError(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// nothing listens on the address anymore
+	address := listener.Addr().String()
+	listener.Close()
+
+	c := New(url.URL{Scheme: NetworkTCP, Host: address}, nil, nil, nil)
+
+	socketErr := &Error{}
+
+	if err := c.Send(context.Background(), []byte("5 first")); !errors.As(err, &socketErr) || socketErr.Op != "dial "+address {
+		t.Fatalf("got error %v, want a socket error dialing %s", err, address)
+	}
+}
@@ -0,0 +1,201 @@
+package socket
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+const (
+	socks5Version = 5
+
+	socks5AuthNone         = 0x00
+	socks5AuthPassword     = 0x02
+	socks5AuthUnacceptable = 0xff
+
+	socks5CommandConnect = 0x01
+
+	socks5AddressIPv4   = 0x01
+	socks5AddressDomain = 0x03
+	socks5AddressIPv6   = 0x04
+
+	socks5DefaultPort = "1080"
+)
+
+// dialSocks5 connects to address through the socks5 proxy, the socks5h scheme resolves the host name on the proxy rather than locally
+//
+// https://www.rfc-editor.org/rfc/rfc1928, with the username and password authentication of https://www.rfc-editor.org/rfc/rfc1929
+func dialSocks5(ctx context.Context, dialer *net.Dialer, proxy *url.URL, address string) (net.Conn, error) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := strconv.ParseUint(portString, 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid port: %s", portString)
+	}
+
+	if proxy.Scheme == "socks5" && net.ParseIP(host) == nil {
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip", host)
+		if err != nil {
+			return nil, err
+		}
+
+		host = ips[0].String()
+	}
+
+	proxyAddress := proxy.Host
+
+	if proxy.Port() == "" {
+		proxyAddress = net.JoinHostPort(proxy.Hostname(), socks5DefaultPort)
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial socks5 proxy: %w", err)
+	}
+
+	deadline := time.Now().Add(dialTimeout)
+
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+
+	conn.SetDeadline(deadline)
+
+	if err := socks5Handshake(conn, proxy.User, host, uint16(port)); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("socks5 proxy: %w", err)
+	}
+
+	conn.SetDeadline(time.Time{})
+
+	return conn, nil
+}
+
+func socks5Handshake(conn net.Conn, user *url.Userinfo, host string, port uint16) error {
+	method := byte(socks5AuthNone)
+
+	if user != nil {
+		method = socks5AuthPassword
+	}
+
+	if _, err := conn.Write([]byte{socks5Version, 1, method}); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[0] != socks5Version {
+		return fmt.Errorf("unexpected protocol version: %d", reply[0])
+	}
+
+	if reply[1] == socks5AuthUnacceptable || reply[1] != method {
+		return errors.New("no acceptable authentication method")
+	}
+
+	if method == socks5AuthPassword {
+		if err := socks5Authenticate(conn, user); err != nil {
+			return err
+		}
+	}
+
+	request := []byte{socks5Version, socks5CommandConnect, 0}
+
+	if ip := net.ParseIP(host); ip == nil {
+		if len(host) > 255 {
+			return fmt.Errorf("host name is too long: %s", host)
+		}
+
+		request = append(request, socks5AddressDomain, byte(len(host)))
+		request = append(request, host...)
+	} else if ip4 := ip.To4(); ip4 != nil {
+		request = append(request, socks5AddressIPv4)
+		request = append(request, ip4...)
+	} else {
+		request = append(request, socks5AddressIPv6)
+		request = append(request, ip.To16()...)
+	}
+
+	request = binary.BigEndian.AppendUint16(request, port)
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	// version, reply code, reserved and the type of the bound address
+	header := make([]byte, 4)
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+
+	if header[1] != 0 {
+		return fmt.Errorf("connect failed with reply code: %d", header[1])
+	}
+
+	var boundSize int
+
+	switch header[3] {
+	case socks5AddressIPv4:
+		boundSize = net.IPv4len
+	case socks5AddressIPv6:
+		boundSize = net.IPv6len
+	case socks5AddressDomain:
+		size := make([]byte, 1)
+
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+
+		boundSize = int(size[0])
+	default:
+		return fmt.Errorf("unexpected bound address type: %d", header[3])
+	}
+
+	// the bound address and port are of no use to us
+	_, err := io.CopyN(io.Discard, conn, int64(boundSize+2))
+
+	return err
+}
+
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	username := user.Username()
+	password, _ := user.Password()
+
+	if len(username) > 255 || len(password) > 255 {
+		return errors.New("username and password must be at most 255 bytes")
+	}
+
+	request := []byte{1, byte(len(username))}
+	request = append(request, username...)
+	request = append(request, byte(len(password)))
+	request = append(request, password...)
+
+	if _, err := conn.Write(request); err != nil {
+		return err
+	}
+
+	reply := make([]byte, 2)
+
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return err
+	}
+
+	if reply[1] != 0 {
+		return errors.New("authentication failed")
+	}
+
+	return nil
+}
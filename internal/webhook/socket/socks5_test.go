@@ -0,0 +1,321 @@
+package socket
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// socks5Server is an in-process socks5 proxy that records the connect requests and echoes whatever is sent through it
+type socks5Server struct {
+	// username and password required of clients, no authentication is asked for if user is nil
+	user *url.Userinfo
+	// the reply code of the connect request
+	replyCode byte
+	// the bound address type and address sent back in the reply
+	boundType    byte
+	boundAddress []byte
+
+	// the connect requests received
+	requests chan request
+}
+
+// request is the address type, host and port of a connect request
+type request struct {
+	addressType byte
+	address     string
+}
+
+func (s *socks5Server) start(t *testing.T) string {
+	t.Helper()
+
+	s.requests = make(chan request, 1)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+
+		defer conn.Close()
+
+		if err := s.serve(conn); err == nil {
+			io.Copy(conn, conn)
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func (s *socks5Server) serve(conn net.Conn) error {
+	greeting := make([]byte, 2)
+
+	if _, err := io.ReadFull(conn, greeting); err != nil {
+		return err
+	}
+
+	methods := make([]byte, greeting[1])
+
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return err
+	}
+
+	method := byte(socks5AuthNone)
+
+	if s.user != nil {
+		method = socks5AuthPassword
+	}
+
+	if !strings.Contains(string(methods), string([]byte{method})) {
+		conn.Write([]byte{socks5Version, socks5AuthUnacceptable})
+		return io.EOF
+	}
+
+	conn.Write([]byte{socks5Version, method})
+
+	if method == socks5AuthPassword {
+		username, password, err := readCredentials(conn)
+		if err != nil {
+			return err
+		}
+
+		wantPassword, _ := s.user.Password()
+
+		if username != s.user.Username() || password != wantPassword {
+			conn.Write([]byte{1, 1})
+			return io.EOF
+		}
+
+		conn.Write([]byte{1, 0})
+	}
+
+	header := make([]byte, 4)
+
+	if _, err := io.ReadFull(conn, header); err != nil {
+		return err
+	}
+
+	addressType := header[3]
+
+	var host []byte
+
+	switch addressType {
+	case socks5AddressIPv4:
+		host = make([]byte, net.IPv4len)
+	case socks5AddressIPv6:
+		host = make([]byte, net.IPv6len)
+	case socks5AddressDomain:
+		size := make([]byte, 1)
+
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return err
+		}
+
+		host = make([]byte, size[0])
+	}
+
+	port := make([]byte, 2)
+
+	if _, err := io.ReadFull(conn, host); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return err
+	}
+
+	hostString := string(host)
+
+	if addressType != socks5AddressDomain {
+		hostString = net.IP(host).String()
+	}
+
+	s.requests <- request{
+		addressType: addressType,
+		address:     net.JoinHostPort(hostString, strconv.Itoa(int(binary.BigEndian.Uint16(port)))),
+	}
+
+	reply := []byte{socks5Version, s.replyCode, 0, s.boundType}
+	reply = append(reply, s.boundAddress...)
+	reply = append(reply, 0, 0)
+
+	conn.Write(reply)
+
+	if s.replyCode != 0 {
+		return io.EOF
+	}
+
+	return nil
+}
+
+// readCredentials reads a username and password authentication request, a version followed by the length prefixed username and password
+func readCredentials(conn net.Conn) (string, string, error) {
+	if _, err := io.ReadFull(conn, make([]byte, 1)); err != nil {
+		return "", "", err
+	}
+
+	fields := make([]string, 2)
+
+	for i := range fields {
+		size := make([]byte, 1)
+
+		if _, err := io.ReadFull(conn, size); err != nil {
+			return "", "", err
+		}
+
+		field := make([]byte, size[0])
+
+		if _, err := io.ReadFull(conn, field); err != nil {
+			return "", "", err
+		}
+
+		fields[i] = string(field)
+	}
+
+	return fields[0], fields[1], nil
+}
+
+func TestDialSocks5(t *testing.T) {
+	boundIPv4 := []byte{127, 0, 0, 1}
+
+	tests := []struct {
+		name      string
+		server    socks5Server
+		proxyUser *url.Userinfo
+		scheme    string
+		address   string
+
+		wantErr         string
+		wantAddressType byte
+		wantAddress     string
+	}{
+		{
+			name:            "ipv4 address",
+			server:          socks5Server{boundType: socks5AddressIPv4, boundAddress: boundIPv4},
+			address:         "10.0.0.1:514",
+			wantAddressType: socks5AddressIPv4,
+			wantAddress:     "10.0.0.1:514",
+		},
+		{
+			name:            "ipv6 address",
+			server:          socks5Server{boundType: socks5AddressIPv4, boundAddress: boundIPv4},
+			address:         "[2001:db8::1]:6514",
+			wantAddressType: socks5AddressIPv6,
+			wantAddress:     "[2001:db8::1]:6514",
+		},
+		{
+			name:            "domain resolved by the proxy",
+			server:          socks5Server{boundType: socks5AddressIPv4, boundAddress: boundIPv4},
+			scheme:          "socks5h",
+			address:         "logs.example.com:514",
+			wantAddressType: socks5AddressDomain,
+			wantAddress:     "logs.example.com:514",
+		},
+		{
+			name:            "ipv6 bound address",
+			server:          socks5Server{boundType: socks5AddressIPv6, boundAddress: net.IPv6loopback},
+			address:         "10.0.0.1:514",
+			wantAddressType: socks5AddressIPv4,
+			wantAddress:     "10.0.0.1:514",
+		},
+		{
+			name:            "domain bound address",
+			server:          socks5Server{boundType: socks5AddressDomain, boundAddress: append([]byte{5}, "proxy"...)},
+			address:         "10.0.0.1:514",
+			wantAddressType: socks5AddressIPv4,
+			wantAddress:     "10.0.0.1:514",
+		},
+		{
+			name:            "password authentication",
+			server:          socks5Server{user: url.UserPassword("alice", "secret"), boundType: socks5AddressIPv4, boundAddress: boundIPv4},
+			proxyUser:       url.UserPassword("alice", "secret"),
+			address:         "10.0.0.1:514",
+			wantAddressType: socks5AddressIPv4,
+			wantAddress:     "10.0.0.1:514",
+		},
+		{
+			name:      "wrong password",
+			server:    socks5Server{user: url.UserPassword("alice", "secret")},
+			proxyUser: url.UserPassword("alice", "wrong"),
+			address:   "10.0.0.1:514",
+			wantErr:   "authentication failed",
+		},
+		{
+			name:    "password required",
+			server:  socks5Server{user: url.UserPassword("alice", "secret")},
+			address: "10.0.0.1:514",
+			wantErr: "no acceptable authentication method",
+		},
+		{
+			name:    "connection refused by the target",
+			server:  socks5Server{replyCode: 5, boundType: socks5AddressIPv4, boundAddress: boundIPv4},
+			address: "10.0.0.1:514",
+			wantErr: "connect failed with reply code: 5",
+		},
+		{
+			name:    "unknown bound address type",
+			server:  socks5Server{boundType: 9},
+			address: "10.0.0.1:514",
+			wantErr: "unexpected bound address type: 9",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := &url.URL{Scheme: "socks5", Host: tt.server.start(t), User: tt.proxyUser}
+
+			if tt.scheme != "" {
+				proxy.Scheme = tt.scheme
+			}
+
+			conn, err := dialSocks5(context.Background(), &net.Dialer{}, proxy, tt.address)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("dial: %v", err)
+			}
+
+			defer conn.Close()
+
+			request := <-tt.server.requests
+
+			if request.addressType != tt.wantAddressType {
+				t.Fatalf("got address type %d, want %d", request.addressType, tt.wantAddressType)
+			}
+
+			if request.address != tt.wantAddress {
+				t.Fatalf("got address %s, want %s", request.address, tt.wantAddress)
+			}
+
+			// the connection is tunneled to the echoing proxy once the handshake is done
+			if _, err := conn.Write([]byte("ping")); err != nil {
+				t.Fatalf("write: %v", err)
+			}
+
+			reply := make([]byte, 4)
+
+			if _, err := io.ReadFull(conn, reply); err != nil || string(reply) != "ping" {
+				t.Fatalf("got %q and error %v, want %q", reply, err, "ping")
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+// reassemble joins gelf datagrams back into messages and decompresses them, datagrams without the chunk magic bytes are whole messages
+func reassemble(t *testing.T, datagrams [][]byte) []string {
+	t.Helper()
+
+	messages := []string{}
+	chunked := []byte{}
+
+	for _, datagram := range datagrams {
+		compressed := datagram
+
+		if bytes.HasPrefix(datagram, []byte{0x1e, 0x0f}) {
+			chunked = append(chunked, datagram[12:]...)
+
+			// the sequence number and count follow the magic bytes and the message id
+			if datagram[10] != datagram[11]-1 {
+				continue
+			}
+
+			compressed, chunked = chunked, []byte{}
+		}
+
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+
+		message, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatalf("gzip: %v", err)
+		}
+
+		messages = append(messages, string(message))
+	}
+
+	return messages
+}
+
+func TestDatagrams(t *testing.T) {
+	// random text compresses poorly, so a long message still needs several chunks once compressed
+	random := rand.New(rand.NewPCG(1, 2))
+	long := strings.Builder{}
+
+	for range 5000 {
+		long.WriteByte(byte('a' + random.IntN(26)))
+	}
+
+	tests := []struct {
+		name    string
+		mode    config.WebhookMode
+		payload string
+
+		want       []string
+		wantChunks bool
+	}{
+		{
+			name:    "syslog sends every message on its own",
+			mode:    config.WebhookModeSyslog,
+			payload: "3 one5 two 2",
+			want:    []string{"one", "two 2"},
+		},
+		{
+			name:    "gelf compresses every message on its own",
+			mode:    config.WebhookModeGelf,
+			payload: "{\"short_message\":\"one\"}\x00{\"short_message\":\"two\"}\x00",
+			want:    []string{`{"short_message":"one"}`, `{"short_message":"two"}`},
+		},
+		{
+			name:       "gelf chunks a message too large for one datagram",
+			mode:       config.WebhookModeGelf,
+			payload:    long.String() + "\x00{\"short_message\":\"two\"}\x00",
+			want:       []string{long.String(), `{"short_message":"two"}`},
+			wantChunks: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Destination{Name: "test", Config: config.Destination{WebhookMode: tt.mode}}
+
+			datagrams, err := d.datagrams([]byte(tt.payload))
+			if err != nil {
+				t.Fatalf("datagrams: %v", err)
+			}
+
+			if chunked := len(datagrams) > len(tt.want); chunked != tt.wantChunks {
+				t.Fatalf("got %d datagrams for %d messages, want them chunked %t", len(datagrams), len(tt.want), tt.wantChunks)
+			}
+
+			got := []string{}
+
+			if tt.mode == config.WebhookModeGelf {
+				got = reassemble(t, datagrams)
+			} else {
+				for _, datagram := range datagrams {
+					got = append(got, string(datagram))
+				}
+			}
+
+			if !slices.Equal(got, tt.want) {
+				t.Fatalf("got messages %.80q, want %.80q", got, tt.want)
+			}
+		})
+	}
+}
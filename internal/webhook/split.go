@@ -0,0 +1,128 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"unicode/utf8"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+)
+
+const truncatedMarker = "... [truncated]"
+
+var errPayloadTooLarge = errors.New("payload too large for the destination")
+
+// deliverFunc delivers a serialized payload of count logs, if splittable is true a payload rejected as too large
+// is not counted and errPayloadTooLarge is returned so the caller can split it
+type deliverFunc func(ctx context.Context, kind logKind, count int, payload []byte, splittable bool) error
+
+// deliverLogs queues logs in the spool if there is one, otherwise they are serialized and sent right away
+//
+// On failure the serialized payloads that could not be delivered are returned, unless they were kept elsewhere.
+func deliverLogs[T any](ctx context.Context, d *Destination, kind logKind, logs []T, encode func([]T) ([]byte, error)) ([]byte, error) {
+	if d.spool != nil {
+		return nil, enqueue(d, kind, logs)
+	}
+
+	return splitLogs(ctx, d, kind, logs, encode, d.deliver)
+}
+
+// splitLogs serializes and delivers logs, splitting them into multiple requests when the payload is larger than the destination
+// accepts, either up front based on the request size limit of the webhook mode or after deliver reported a 413 with errPayloadTooLarge.
+//
+// A single log that is still too large is delivered on its own and dealt with like any other rejected payload.
+// On failure the serialized payloads that could not be delivered are returned, unless they were kept elsewhere.
+func splitLogs[T any](ctx context.Context, d *Destination, kind logKind, logs []T, encode func([]T) ([]byte, error), deliver deliverFunc) ([]byte, error) {
+	payload, err := encode(logs)
+	if err != nil {
+		return nil, err
+	}
+
+	if maxBytes := d.Config.MaxRequestBytes(); len(logs) > 1 && maxBytes > 0 && int64(len(payload)) > maxBytes {
+		return splitHalves(ctx, d, kind, logs, encode, deliver)
+	}
+
+	if err := deliver(ctx, kind, len(logs), payload, len(logs) > 1); err != nil {
+		if errors.Is(err, errPayloadTooLarge) {
+			d.logger().Warn("webhook endpoint rejected payload as too large, splitting it",
+				slog.Int("log_count", len(logs)),
+				slog.Int("payload_bytes", len(payload)),
+			)
+
+			return splitHalves(ctx, d, kind, logs, encode, deliver)
+		}
+
+		return serializedOnFailure(payload, err), err
+	}
+
+	return nil, nil
+}
+
+func splitHalves[T any](ctx context.Context, d *Destination, kind logKind, logs []T, encode func([]T) ([]byte, error), deliver deliverFunc) ([]byte, error) {
+	half := len(logs) / 2
+
+	firstPayload, firstErr := splitLogs(ctx, d, kind, logs[:half], encode, deliver)
+	secondPayload, secondErr := splitLogs(ctx, d, kind, logs[half:], encode, deliver)
+
+	return bytes.Join(nonEmpty(firstPayload, secondPayload), []byte("\n")), errors.Join(firstErr, secondErr)
+}
+
+func nonEmpty(payloads ...[]byte) [][]byte {
+	result := [][]byte{}
+
+	for _, payload := range payloads {
+		if len(payload) > 0 {
+			result = append(result, payload)
+		}
+	}
+
+	return result
+}
+
+// isTooLarge reports whether the destination rejected a payload because of its size
+func isTooLarge(err error) bool {
+	var statusErr *generic.StatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusRequestEntityTooLarge
+}
+
+// truncateDeployLogs shortens log messages longer than the per log limit of the webhook mode, the logs are copied before being changed
+func truncateDeployLogs(mode config.WebhookMode, logs []environment_logs.EnvironmentLogWithMetadata) []environment_logs.EnvironmentLogWithMetadata {
+	maxEntryBytes := config.WebhookModeToConfig[mode].MaxEntryBytes
+	if maxEntryBytes <= 0 {
+		return logs
+	}
+
+	truncated := logs
+	copied := false
+
+	for i := range logs {
+		if len(logs[i].Log.Message) <= maxEntryBytes {
+			continue
+		}
+
+		if !copied {
+			truncated = append([]environment_logs.EnvironmentLogWithMetadata{}, logs...)
+			copied = true
+		}
+
+		truncated[i].Log.Message = truncateString(logs[i].Log.Message, maxEntryBytes)
+	}
+
+	return truncated
+}
+
+// truncateString cuts s to at most maxBytes including the truncation marker, without splitting a multi-byte character
+func truncateString(s string, maxBytes int) string {
+	cut := max(maxBytes-len(truncatedMarker), 0)
+
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+
+	return s[:cut] + truncatedMarker
+}
@@ -0,0 +1,182 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/railway/gql/subscriptions"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/webhook/generic"
+)
+
+func TestSplitLogs(t *testing.T) {
+	// every log is encoded as its own name followed by a comma
+	encode := func(logs []string) ([]byte, error) {
+		return []byte(strings.Join(logs, ",") + ","), nil
+	}
+
+	tests := []struct {
+		name string
+		logs []string
+		// the request size limit known up front, zero means none
+		maxRequestBytes config.ByteSize
+		// payloads larger than this are rejected with a 413 by the endpoint
+		acceptedBytes int
+
+		wantPayloads []string
+		wantFailed   string
+		wantErr      bool
+	}{
+		{
+			name:          "fits",
+			logs:          []string{"a", "b", "c", "d"},
+			acceptedBytes: 100,
+			wantPayloads:  []string{"a,b,c,d,"},
+		},
+		{
+			name:            "split up front at the request size limit",
+			logs:            []string{"a", "b", "c", "d"},
+			maxRequestBytes: 4,
+			acceptedBytes:   100,
+			wantPayloads:    []string{"a,b,", "c,d,"},
+		},
+		{
+			name:          "split after a 413",
+			logs:          []string{"a", "b", "c", "d"},
+			acceptedBytes: 4,
+			wantPayloads:  []string{"a,b,", "c,d,"},
+		},
+		{
+			name:          "split down to single logs",
+			logs:          []string{"a", "b", "c"},
+			acceptedBytes: 2,
+			wantPayloads:  []string{"a,", "b,", "c,"},
+		},
+		{
+			name:          "a single log that is still too large is rejected",
+			logs:          []string{"a", "bbbb", "c"},
+			acceptedBytes: 2,
+			wantPayloads:  []string{"a,", "c,"},
+			wantFailed:    "bbbb,",
+			wantErr:       true,
+		},
+	}
+
+	previous := config.Global
+	t.Cleanup(func() { config.Global = previous })
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Global.BatchMaxBytes = tt.maxRequestBytes
+
+			d := &Destination{Name: "test", Config: config.Destination{WebhookMode: config.WebhookModeJson}}
+
+			delivered := []string{}
+
+			deliver := func(ctx context.Context, kind logKind, count int, payload []byte, splittable bool) error {
+				if len(payload) > tt.acceptedBytes {
+					err := &generic.StatusError{StatusCode: http.StatusRequestEntityTooLarge}
+
+					if splittable {
+						return fmt.Errorf("%w: %w", errPayloadTooLarge, err)
+					}
+
+					return err
+				}
+
+				delivered = append(delivered, string(payload))
+
+				return nil
+			}
+
+			failed, err := splitLogs(context.Background(), d, logKindDeploy, tt.logs, encode, deliver)
+
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %t", err, tt.wantErr)
+			}
+
+			if !slices.Equal(delivered, tt.wantPayloads) {
+				t.Fatalf("got payloads %q, want %q", delivered, tt.wantPayloads)
+			}
+
+			if string(failed) != tt.wantFailed {
+				t.Fatalf("got failed payload %q, want %q", failed, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestIsTooLarge(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "413", err: &generic.StatusError{StatusCode: http.StatusRequestEntityTooLarge}, want: true},
+		{name: "wrapped 413", err: fmt.Errorf("send: %w", &generic.StatusError{StatusCode: http.StatusRequestEntityTooLarge}), want: true},
+		{name: "400", err: &generic.StatusError{StatusCode: http.StatusBadRequest}, want: false},
+		{name: "not a status error", err: errors.New("connection reset"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isTooLarge(tt.err); got != tt.want {
+				t.Fatalf("got %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateString(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		maxBytes int
+		want     string
+	}{
+		{name: "ascii", s: strings.Repeat("a", 30), maxBytes: 20, want: "aaaaa" + truncatedMarker},
+		{name: "does not split a multi-byte character", s: strings.Repeat("é", 15), maxBytes: 20, want: "éé" + truncatedMarker},
+		{name: "limit below the marker", s: strings.Repeat("a", 30), maxBytes: 5, want: truncatedMarker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncateString(tt.s, tt.maxBytes); got != tt.want {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncateDeployLogs(t *testing.T) {
+	maxEntryBytes := config.WebhookModeToConfig[config.WebhookModeDatadog].MaxEntryBytes
+
+	logs := []environment_logs.EnvironmentLogWithMetadata{
+		{Log: subscriptions.EnvironmentLog{Message: "short"}},
+		{Log: subscriptions.EnvironmentLog{Message: strings.Repeat("a", maxEntryBytes+1)}},
+	}
+
+	truncated := truncateDeployLogs(config.WebhookModeDatadog, logs)
+
+	if truncated[0].Log.Message != "short" {
+		t.Fatalf("got %q, want the short message untouched", truncated[0].Log.Message)
+	}
+
+	if got := len(truncated[1].Log.Message); got != maxEntryBytes || !strings.HasSuffix(truncated[1].Log.Message, truncatedMarker) {
+		t.Fatalf("got a message of %d bytes, want %d ending in the truncation marker", got, maxEntryBytes)
+	}
+
+	if len(logs[1].Log.Message) != maxEntryBytes+1 {
+		t.Fatal("the logs passed in were changed, want them copied")
+	}
+
+	if got := truncateDeployLogs(config.WebhookModeJson, logs); &got[0] != &logs[0] {
+		t.Fatal("got the logs copied for a mode without an entry limit, want them returned as is")
+	}
+}
@@ -0,0 +1,237 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/brody192/locomotive/internal/circuit"
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/spool"
+)
+
+type logKind byte
+
+const (
+	logKindDeploy logKind = iota
+	logKindHttp
+)
+
+func (k logKind) String() string {
+	if k == logKindHttp {
+		return "http"
+	}
+
+	return "deploy"
+}
+
+// openSpool opens the destination's on-disk spool, replays any batches left over
+// from a previous run and starts delivering queued batches until the context is cancelled
+func (d *Destination) openSpool(ctx context.Context, dir string) error {
+	s, err := spool.Open(spool.Options{
+		Dir:            dir,
+		MaxSegmentSize: int64(config.Global.SpoolSegmentSize),
+		MaxTotalSize:   int64(config.Global.SpoolMaxSize),
+		FsyncPolicy:    config.Global.SpoolFsync,
+		FsyncInterval:  config.Global.SpoolFsyncInterval,
+	})
+	if err != nil {
+		return err
+	}
+
+	if size := s.Size(); size > 0 {
+		logger.Stdout.Info("replaying undelivered batches from the spool",
+			slog.String("destination", d.Name),
+			slog.Int64("spool_bytes", size),
+		)
+	}
+
+	d.spool = s
+
+	go d.drainSpool(ctx)
+
+	return nil
+}
+
+// SpoolStats returns the number of bytes waiting in the spool and the number of batches evicted to stay under the size cap
+func (d *Destination) SpoolStats() (pendingBytes int64, evictedBatches int64, ok bool) {
+	if d.spool == nil {
+		return 0, 0, false
+	}
+
+	return d.spool.Size(), d.spool.Evicted(), true
+}
+
+// Close closes the destination's spool, batches queued in it are replayed on the next start.
+//
+// It must only be called once nothing is delivered through the destination anymore.
+func (d *Destination) Close() error {
+	if d.spool == nil {
+		return nil
+	}
+
+	return d.spool.Close()
+}
+
+// enqueue writes the logs to the spool, they are kept unserialized so a batch rejected as too large can still be split when it is delivered
+func enqueue[T any](d *Destination, kind logKind, logs []T) error {
+	data, err := json.Marshal(logs)
+	if err == nil {
+		err = d.spool.Append(append([]byte{byte(kind)}, data...))
+	}
+
+	if err != nil {
+		_, dropped, _ := d.Stats.forKind(kind)
+		dropped.Add(int64(len(logs)))
+
+		return fmt.Errorf("failed to write batch to spool: %w", err)
+	}
+
+	return nil
+}
+
+// drainSpool delivers spooled batches oldest first, a batch only leaves the spool once it was
+// delivered or permanently rejected, so an outage longer than the retry budget does not lose it.
+//
+// The spool stays open once the context is done, so batches flushed at shutdown can still be queued until Close is called.
+func (d *Destination) drainSpool(ctx context.Context) {
+	for {
+		record, err := d.spool.Next(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			d.logger().Error("error reading from spool", logger.ErrAttr(err))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(time.Second):
+				continue
+			}
+		}
+
+		if err := d.deliverSpoolRecord(ctx, record.Data); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+
+			d.logger().Error("discarding unreadable spool record", logger.ErrAttr(err))
+		}
+
+		if err := d.spool.Ack(record); err != nil {
+			d.logger().Error("error acknowledging spool record", logger.ErrAttr(err))
+		}
+	}
+}
+
+// deliverSpoolRecord decodes the logs of a spooled batch and delivers them, splitting them as the destination requires.
+// It only returns an error if the record can not be decoded or the context was cancelled before the batch was dealt with.
+func (d *Destination) deliverSpoolRecord(ctx context.Context, record []byte) error {
+	if len(record) < 1 {
+		return errors.New("spool record is too short")
+	}
+
+	var err error
+
+	switch kind := logKind(record[0]); kind {
+	case logKindDeploy:
+		logs := []environment_logs.EnvironmentLogWithMetadata{}
+
+		if err := json.Unmarshal(record[1:], &logs); err != nil {
+			return fmt.Errorf("spool record holds invalid deploy logs: %w", err)
+		}
+
+		_, err = splitLogs(ctx, d, kind, logs, d.reconstructDeployLogs, d.deliverSpooled)
+	case logKindHttp:
+		logs := []http_logs.DeploymentHttpLogWithMetadata{}
+
+		if err := json.Unmarshal(record[1:], &logs); err != nil {
+			return fmt.Errorf("spool record holds invalid http logs: %w", err)
+		}
+
+		_, err = splitLogs(ctx, d, kind, logs, d.reconstructHttpLogs, d.deliverSpooled)
+	default:
+		return fmt.Errorf("spool record has an unknown log kind: %d", kind)
+	}
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	// serializing the logs failed, they can not be sent in any later attempt either
+	if err != nil {
+		d.logger().Error("dropping spooled batch that could not be serialized", logger.ErrAttr(err))
+	}
+
+	return nil
+}
+
+// deliverSpooled retries a spooled payload until it succeeds or is permanently rejected, it is the deliverFunc of destinations with a spool.
+//
+// It only returns an error if the context was cancelled, or the payload was rejected as too large and can be split.
+func (d *Destination) deliverSpooled(ctx context.Context, kind logKind, count int, payload []byte, splittable bool) error {
+	sent, _, _ := d.Stats.forKind(kind)
+
+	for {
+		// every attempt waits for budget of its own, the spool itself is the buffer, the drop policy is rejected with a spool at startup
+		if err := d.acquire(ctx, count, true); err != nil {
+			return err
+		}
+
+		err := d.send(ctx, count, payload)
+		if err == nil {
+			sent.Add(int64(count))
+			return nil
+		}
+
+		if splittable && isTooLarge(err) {
+			return fmt.Errorf("%w: %w", errPayloadTooLarge, err)
+		}
+
+		if d.unacknowledged(kind, count, err) {
+			return nil
+		}
+
+		// only the items of a bulk request that were not delivered are kept
+		var failed int
+		payload, failed = undelivered(payload, count, err)
+		sent.Add(int64(count - failed))
+		count = failed
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		wait := config.Global.RetryMaxInterval
+
+		if errors.Is(err, circuit.ErrOpen) {
+			// keep the batch spooled without logging every attempt, the breaker logs when the endpoint comes back
+			wait = max(d.probeIn(), time.Second)
+		} else if !isRetryable(err) {
+			if err := d.reject(ctx, kind, count, payload, err); !errors.Is(err, ErrDeadLettered) {
+				d.logger().Error("dropping spooled batch rejected by the webhook endpoint",
+					slog.Int("log_count", count),
+					logger.ErrAttr(err),
+				)
+			}
+
+			return nil
+		} else {
+			d.logger().Warn("webhook endpoint still unavailable, keeping batch in spool", logger.ErrAttr(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
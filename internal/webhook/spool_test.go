@@ -0,0 +1,189 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+)
+
+// testServer answers webhook requests with the given status codes in turn, repeating the last one, and records the request bodies
+type testServer struct {
+	mu       sync.Mutex
+	statuses []int
+	bodies   []string
+}
+
+func (s *testServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+
+	s.bodies = append(s.bodies, string(body))
+
+	status := http.StatusOK
+
+	if len(s.statuses) > 0 {
+		status = s.statuses[min(len(s.bodies), len(s.statuses))-1]
+	}
+
+	w.WriteHeader(status)
+}
+
+func (s *testServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.bodies...)
+}
+
+// newTestDestination returns a json destination sending to a test server answering with the given status codes in turn
+func newTestDestination(t *testing.T, statuses ...int) (*Destination, *testServer) {
+	t.Helper()
+
+	server := &testServer{statuses: statuses}
+
+	httpServer := httptest.NewServer(server)
+	t.Cleanup(httpServer.Close)
+
+	u, err := url.Parse(httpServer.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := newDestination(config.NamedDestination{
+		Name: "test",
+		Destination: config.Destination{
+			WebhookUrl:  *u,
+			WebhookMode: config.WebhookModeJson,
+			Compression: config.CompressionNone,
+		},
+	})
+	if err != nil {
+		t.Fatalf("new destination: %v", err)
+	}
+
+	return d, server
+}
+
+func TestDeliverSpoolRecord(t *testing.T) {
+	setRetryConfig(t, 1, time.Millisecond, time.Millisecond, time.Second)
+
+	deployLogs := `[{"Log":{"message":"hello","severity":"info","timestamp":"2024-01-02T03:04:05Z"},"Metadata":{"service_name":"api"}}]`
+	httpLogs := `[{"path":"/health","httpStatus":200,"timestamp":"2024-01-02T03:04:05Z","Metadata":{"service_name":"api"}}]`
+
+	tests := []struct {
+		name     string
+		record   []byte
+		statuses []int
+
+		wantErr         string
+		wantRequests    int
+		wantDeploySent  int64
+		wantHttpSent    int64
+		wantDeployDrops int64
+	}{
+		{
+			name:    "too short",
+			record:  []byte{},
+			wantErr: "too short",
+		},
+		{
+			name:    "unknown log kind",
+			record:  append([]byte{9}, "[]"...),
+			wantErr: "unknown log kind",
+		},
+		{
+			name:    "invalid deploy logs",
+			record:  append([]byte{byte(logKindDeploy)}, "{"...),
+			wantErr: "invalid deploy logs",
+		},
+		{
+			name:           "deploy logs",
+			record:         append([]byte{byte(logKindDeploy)}, deployLogs...),
+			wantRequests:   1,
+			wantDeploySent: 1,
+		},
+		{
+			name:         "http logs",
+			record:       append([]byte{byte(logKindHttp)}, httpLogs...),
+			wantRequests: 1,
+			wantHttpSent: 1,
+		},
+		{
+			name:           "kept until the endpoint is back",
+			record:         append([]byte{byte(logKindDeploy)}, deployLogs...),
+			statuses:       []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusOK},
+			wantRequests:   3,
+			wantDeploySent: 1,
+		},
+		{
+			name:            "permanently rejected",
+			record:          append([]byte{byte(logKindDeploy)}, deployLogs...),
+			statuses:        []int{http.StatusBadRequest},
+			wantRequests:    1,
+			wantDeployDrops: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, server := newTestDestination(t, tt.statuses...)
+
+			err := d.deliverSpoolRecord(context.Background(), tt.record)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatalf("deliver: %v", err)
+			}
+
+			if got := len(server.requests()); got != tt.wantRequests {
+				t.Fatalf("got %d requests, want %d", got, tt.wantRequests)
+			}
+
+			if got := d.Stats.DeployLogsSent.Load(); got != tt.wantDeploySent {
+				t.Fatalf("got %d deploy logs sent, want %d", got, tt.wantDeploySent)
+			}
+
+			if got := d.Stats.HttpLogsSent.Load(); got != tt.wantHttpSent {
+				t.Fatalf("got %d http logs sent, want %d", got, tt.wantHttpSent)
+			}
+
+			if got := d.Stats.DeployLogsDropped.Load(); got != tt.wantDeployDrops {
+				t.Fatalf("got %d deploy logs dropped, want %d", got, tt.wantDeployDrops)
+			}
+		})
+	}
+}
+
+func TestDeliverSpooledCancelled(t *testing.T) {
+	setRetryConfig(t, 1, time.Millisecond, time.Millisecond, time.Second)
+
+	d, server := newTestDestination(t, http.StatusServiceUnavailable)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := d.deliverSpooled(ctx, logKindDeploy, 1, []byte(`[]`), false); err == nil {
+		t.Fatal("got no error, want the batch to be kept once the context is done")
+	}
+
+	if len(server.requests()) < 2 {
+		t.Fatalf("got %d requests, want the batch to be retried", len(server.requests()))
+	}
+
+	if got := d.Stats.DeployLogsDropped.Load(); got != 0 {
+		t.Fatalf("got %d deploy logs dropped, want them kept in the spool", got)
+	}
+}
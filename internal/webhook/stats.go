@@ -0,0 +1,56 @@
+package webhook
+
+import "sync/atomic"
+
+type stats struct {
+	// number of logs accepted by the destination
+	DeployLogsSent atomic.Int64
+	HttpLogsSent   atomic.Int64
+
+	// number of payload bytes before and after compression, counted once per batch regardless of retries
+	BytesRaw  atomic.Int64
+	BytesSent atomic.Int64
+
+	// number of webhook requests that were attempted again after a retryable failure
+	Retries atomic.Int64
+
+	// number of logs that could not be delivered, either because they were rejected or retries were exhausted
+	DeployLogsDropped atomic.Int64
+	HttpLogsDropped   atomic.Int64
+
+	// number of the dropped logs that were dropped because the destination's rate limit was exhausted
+	DeployLogsRateLimited atomic.Int64
+	HttpLogsRateLimited   atomic.Int64
+
+	// number of logs sent in requests the destination accepted but never acknowledged, they may or may not have been indexed
+	DeployLogsUnacknowledged atomic.Int64
+	HttpLogsUnacknowledged   atomic.Int64
+
+	// number of logs permanently rejected by the destination and written to a dead letter sink
+	DeployLogsDeadLettered atomic.Int64
+	HttpLogsDeadLettered   atomic.Int64
+}
+
+func (s *stats) forKind(kind logKind) (sent, dropped, deadLettered *atomic.Int64) {
+	if kind == logKindHttp {
+		return &s.HttpLogsSent, &s.HttpLogsDropped, &s.HttpLogsDeadLettered
+	}
+
+	return &s.DeployLogsSent, &s.DeployLogsDropped, &s.DeployLogsDeadLettered
+}
+
+func (s *stats) unacknowledgedForKind(kind logKind) *atomic.Int64 {
+	if kind == logKindHttp {
+		return &s.HttpLogsUnacknowledged
+	}
+
+	return &s.DeployLogsUnacknowledged
+}
+
+func (s *stats) rateLimitedForKind(kind logKind) *atomic.Int64 {
+	if kind == logKindHttp {
+		return &s.HttpLogsRateLimited
+	}
+
+	return &s.DeployLogsRateLimited
+}
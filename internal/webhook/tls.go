@@ -0,0 +1,183 @@
+package webhook
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+)
+
+// how often the certificate files of a destination are checked for changes, at most once per request
+const tlsReloadCheckInterval = 10 * time.Second
+
+// loadPEM returns the PEM contents of a setting that holds either the contents themselves or a path to a file with them
+func loadPEM(value string) ([]byte, error) {
+	if isInlinePEM(value) {
+		return []byte(value), nil
+	}
+
+	return os.ReadFile(value)
+}
+
+func isInlinePEM(value string) bool {
+	return strings.Contains(value, "-----BEGIN")
+}
+
+// newTLSConfig builds the tls config for a destination from its settings
+func newTLSConfig(settings config.TLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName: settings.ServerName,
+		MinVersion: uint16(settings.MinVersion),
+	}
+
+	if settings.ClientCert != "" {
+		certPEM, err := loadPEM(settings.ClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+
+		keyPEM, err := loadPEM(settings.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client key: %w", err)
+		}
+
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse tls client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if settings.CABundle != "" {
+		caPEM, err := loadPEM(settings.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls ca bundle: %w", err)
+		}
+
+		// the bundle adds to the system roots rather than replacing them
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("failed to parse tls ca bundle: no certificates found")
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// reloadingTransport rebuilds its transport when the certificate files it was built from change,
+// so rotated certificates are picked up without a restart
+type reloadingTransport struct {
+	settings config.TLS
+	logger   *slog.Logger
+
+	current atomic.Pointer[http.Transport]
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	modTimes  map[string]time.Time
+}
+
+func newReloadingTransport(settings config.TLS, logger *slog.Logger) (*reloadingTransport, error) {
+	t := &reloadingTransport{
+		settings: settings,
+		logger:   logger,
+	}
+
+	tlsConfig, err := newTLSConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	t.modTimes = t.statFiles()
+	t.checkedAt = time.Now()
+	t.current.Store(newTransport(tlsConfig))
+
+	return t, nil
+}
+
+func (t *reloadingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.reloadIfChanged()
+
+	return t.current.Load().RoundTrip(req)
+}
+
+// files returns the settings that are paths to files rather than inline PEM contents
+func (t *reloadingTransport) files() []string {
+	files := []string{}
+
+	for _, value := range []string{t.settings.ClientCert, t.settings.ClientKey, t.settings.CABundle} {
+		if value != "" && !isInlinePEM(value) {
+			files = append(files, value)
+		}
+	}
+
+	return files
+}
+
+func (t *reloadingTransport) statFiles() map[string]time.Time {
+	modTimes := map[string]time.Time{}
+
+	for _, file := range t.files() {
+		if info, err := os.Stat(file); err == nil {
+			modTimes[file] = info.ModTime()
+		}
+	}
+
+	return modTimes
+}
+
+func (t *reloadingTransport) reloadIfChanged() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if time.Since(t.checkedAt) < tlsReloadCheckInterval {
+		return
+	}
+
+	t.checkedAt = time.Now()
+
+	modTimes := t.statFiles()
+
+	changed := len(modTimes) != len(t.modTimes)
+
+	for file, modTime := range modTimes {
+		if !t.modTimes[file].Equal(modTime) {
+			changed = true
+		}
+	}
+
+	if !changed {
+		return
+	}
+
+	tlsConfig, err := newTLSConfig(t.settings)
+	if err != nil {
+		// certificates are often replaced one file at a time, keep the old ones and try again on the next check
+		t.logger.Warn("error reloading tls certificates, keeping the previous ones", logger.ErrAttr(err))
+		return
+	}
+
+	t.modTimes = modTimes
+
+	previous := t.current.Swap(newTransport(tlsConfig))
+	previous.CloseIdleConnections()
+
+	t.logger.Info("reloaded tls certificates")
+}
@@ -0,0 +1,288 @@
+package webhook
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+)
+
+// testCert is a certificate and key along with their PEM encodings
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM string
+	keyPEM  string
+}
+
+// newTestCert issues a certificate for the loopback address signed by parent, or a self signed ca if parent is nil
+func newTestCert(t *testing.T, name string, parent *testCert) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{name},
+	}
+
+	signer, signerKey := template, key
+
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	} else {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})),
+		keyPEM:  string(pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})),
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) string {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+// newMTLSServer starts a server requiring client certificates signed by ca, presenting a certificate signed by ca itself
+func newMTLSServer(t *testing.T, ca *testCert) *httptest.Server {
+	t.Helper()
+
+	serverCert := newTestCert(t, "logs.example.com", ca)
+
+	pair, err := tls.X509KeyPair([]byte(serverCert.certPEM), []byte(serverCert.keyPEM))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(ca.cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{pair},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    clientCAs,
+	}
+
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestNewTLSConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, "ca", nil)
+	client := newTestCert(t, "client", ca)
+
+	certFile := writeFile(t, filepath.Join(dir, "client.crt"), client.certPEM)
+	keyFile := writeFile(t, filepath.Join(dir, "client.key"), client.keyPEM)
+	caFile := writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM)
+
+	tests := []struct {
+		name     string
+		settings config.TLS
+
+		wantErr          string
+		wantCertificates int
+		wantRootCAs      bool
+	}{
+		{
+			name:     "no settings",
+			settings: config.TLS{},
+		},
+		{
+			name:             "inline pem",
+			settings:         config.TLS{ClientCert: client.certPEM, ClientKey: client.keyPEM, CABundle: ca.certPEM},
+			wantCertificates: 1,
+			wantRootCAs:      true,
+		},
+		{
+			name:             "files",
+			settings:         config.TLS{ClientCert: certFile, ClientKey: keyFile, CABundle: caFile},
+			wantCertificates: 1,
+			wantRootCAs:      true,
+		},
+		{
+			name:     "missing key file",
+			settings: config.TLS{ClientCert: certFile, ClientKey: filepath.Join(dir, "missing.key")},
+			wantErr:  "failed to load tls client key",
+		},
+		{
+			name:     "key of another certificate",
+			settings: config.TLS{ClientCert: certFile, ClientKey: ca.keyPEM},
+			wantErr:  "failed to parse tls client certificate",
+		},
+		{
+			name:     "ca bundle without certificates",
+			settings: config.TLS{CABundle: writeFile(t, filepath.Join(dir, "empty.crt"), "not a certificate")},
+			wantErr:  "no certificates found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tlsConfig, err := newTLSConfig(tt.settings)
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("new tls config: %v", err)
+			}
+
+			if len(tlsConfig.Certificates) != tt.wantCertificates {
+				t.Fatalf("got %d certificates, want %d", len(tlsConfig.Certificates), tt.wantCertificates)
+			}
+
+			if (tlsConfig.RootCAs != nil) != tt.wantRootCAs {
+				t.Fatalf("got root cas %v, want them set %t", tlsConfig.RootCAs, tt.wantRootCAs)
+			}
+		})
+	}
+}
+
+func TestReloadingTransport(t *testing.T) {
+	dir := t.TempDir()
+
+	ca := newTestCert(t, "ca", nil)
+	server := newMTLSServer(t, ca)
+
+	first := newTestCert(t, "first", ca)
+	second := newTestCert(t, "second", ca)
+
+	settings := config.TLS{
+		ClientCert: writeFile(t, filepath.Join(dir, "client.crt"), first.certPEM),
+		ClientKey:  writeFile(t, filepath.Join(dir, "client.key"), first.keyPEM),
+		CABundle:   writeFile(t, filepath.Join(dir, "ca.crt"), ca.certPEM),
+		ServerName: "logs.example.com",
+	}
+
+	transport, err := newReloadingTransport(settings, logger.Stderr)
+	if err != nil {
+		t.Fatalf("new transport: %v", err)
+	}
+
+	client := &http.Client{Transport: transport}
+
+	// the client certificate presented, as echoed by the server
+	presented := func() string {
+		t.Helper()
+
+		res, err := client.Get(server.URL)
+		if err != nil {
+			t.Fatalf("request: %v", err)
+		}
+
+		defer res.Body.Close()
+
+		body, err := io.ReadAll(res.Body)
+		if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+
+		return string(body)
+	}
+
+	if got := presented(); got != "first" {
+		t.Fatalf("got client certificate %q, want %q", got, "first")
+	}
+
+	writeFile(t, settings.ClientCert, second.certPEM)
+	writeFile(t, settings.ClientKey, second.keyPEM)
+
+	// the files are changed well after they were loaded, and checked again right away
+	later := time.Now().Add(time.Minute)
+
+	for _, file := range []string{settings.ClientCert, settings.ClientKey} {
+		if err := os.Chtimes(file, later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	transport.mu.Lock()
+	transport.checkedAt = time.Time{}
+	transport.mu.Unlock()
+
+	if got := presented(); got != "second" {
+		t.Fatalf("got client certificate %q after reloading, want %q", got, "second")
+	}
+}
+
+func TestMTLSRejectsUntrustedServer(t *testing.T) {
+	ca := newTestCert(t, "ca", nil)
+	server := newMTLSServer(t, ca)
+
+	client := newTestCert(t, "client", ca)
+
+	// without the ca bundle the server certificate is not trusted
+	tlsConfig, err := newTLSConfig(config.TLS{ClientCert: client.certPEM, ClientKey: client.keyPEM})
+	if err != nil {
+		t.Fatalf("new tls config: %v", err)
+	}
+
+	res, err := (&http.Client{Transport: newTransport(tlsConfig)}).Get(server.URL)
+	if err == nil {
+		res.Body.Close()
+		t.Fatal("got no error, want the untrusted server certificate rejected")
+	}
+}
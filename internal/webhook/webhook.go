@@ -1,25 +1,193 @@
 package webhook
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 
+	"github.com/brody192/locomotive/internal/config"
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/logline/reconstructor/reconstruct_elasticsearch"
 	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
 	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
 	"github.com/brody192/locomotive/internal/webhook/generic"
 )
 
-func SendDeployLogsWebhook(logs []environment_logs.EnvironmentLogWithMetadata) (serializedLogs []byte, err error) {
-	if serializedLogs, err := generic.SendWebhookForDeployLogs(logs, client); err != nil {
+// SendDeployLogs delivers deploy logs to the destination, on failure the serialized logs are returned
+// unless they were kept elsewhere, such as in a dead letter sink
+func (d *Destination) SendDeployLogs(ctx context.Context, logs []environment_logs.EnvironmentLogWithMetadata) (serializedLogs []byte, err error) {
+	logs = truncateDeployLogs(d.Config.WebhookMode, logs)
+
+	serializedLogs, err = deliverLogs(ctx, d, logKindDeploy, logs, func(logs []environment_logs.EnvironmentLogWithMetadata) ([]byte, error) {
+		return d.reconstructDeployLogs(logs)
+	})
+	if err != nil {
 		return serializedLogs, fmt.Errorf("failed to send webhook for deploy logs: %w", err)
 	}
 
 	return nil, nil
 }
 
-func SendHttpLogsWebhook(logs []http_logs.DeploymentHttpLogWithMetadata) (serializedLogs []byte, err error) {
-	if serializedLogs, err := generic.SendWebhookForHttpLogs(logs, client); err != nil {
+// SendHttpLogs delivers http logs to the destination, on failure the serialized logs are returned
+// unless they were kept elsewhere, such as in a dead letter sink
+func (d *Destination) SendHttpLogs(ctx context.Context, logs []http_logs.DeploymentHttpLogWithMetadata) (serializedLogs []byte, err error) {
+	serializedLogs, err = deliverLogs(ctx, d, logKindHttp, logs, func(logs []http_logs.DeploymentHttpLogWithMetadata) ([]byte, error) {
+		return d.reconstructHttpLogs(logs)
+	})
+	if err != nil {
 		return serializedLogs, fmt.Errorf("failed to send webhook for http logs: %w", err)
 	}
 
 	return nil, nil
 }
+
+// serializedOnFailure returns the undelivered payload of a failed delivery for logging, unless it was kept elsewhere or dropped on purpose
+func serializedOnFailure(payload []byte, err error) []byte {
+	if errors.Is(err, ErrDeadLettered) || errors.Is(err, ErrRateLimited) {
+		return nil
+	}
+
+	payload, _ = undelivered(payload, 0, err)
+
+	return payload
+}
+
+// deliver sends the payload right away once the rate limit allows it, it is the deliverFunc of destinations without a spool
+func (d *Destination) deliver(ctx context.Context, kind logKind, count int, payload []byte, splittable bool) error {
+	sent, dropped, _ := d.Stats.forKind(kind)
+
+	if err := d.acquire(ctx, count, d.Config.RateLimitPolicy == config.RateLimitPolicyBlock); err != nil {
+		dropped.Add(int64(count))
+
+		if errors.Is(err, ErrRateLimited) {
+			d.Stats.rateLimitedForKind(kind).Add(int64(count))
+		}
+
+		return err
+	}
+
+	if err := d.send(ctx, count, payload); err != nil {
+		if splittable && isTooLarge(err) {
+			return fmt.Errorf("%w: %w", errPayloadTooLarge, err)
+		}
+
+		if d.unacknowledged(kind, count, err) {
+			return nil
+		}
+
+		payload, failed := undelivered(payload, count, err)
+		sent.Add(int64(count - failed))
+
+		return d.reject(ctx, kind, failed, payload, err)
+	}
+
+	sent.Add(int64(count))
+
+	return nil
+}
+
+// send delivers a serialized payload of count logs, retrying according to the configured retry policy.
+//
+// The caller takes the rate limit budget for the first attempt, every retry waits for budget of its own.
+func (d *Destination) send(ctx context.Context, count int, payload []byte) error {
+	body, contentEncoding, err := d.compress(payload)
+	if err != nil {
+		return err
+	}
+
+	d.Stats.BytesRaw.Add(int64(len(payload)))
+	d.Stats.BytesSent.Add(int64(len(body)))
+
+	// a bulk request accepted in part is narrowed to its failed items between attempts
+	narrowed := false
+
+	attempt := 0
+
+	err = d.sendWithRetry(ctx, func(ctx context.Context) error {
+		if attempt++; attempt > 1 {
+			if err := d.acquire(ctx, count, true); err != nil {
+				return err
+			}
+		}
+
+		e, err := d.pickEndpoint()
+		if err != nil {
+			return err
+		}
+
+		if e.socket != nil {
+			err = e.socket.Send(ctx, body)
+			e.recordOutcome(err)
+
+			return err
+		}
+
+		destination := d.Config
+		destination.WebhookUrl = e.url
+
+		err = generic.SendRawWebhook(ctx, body, contentEncoding, destination, d.auth, d.client)
+
+		if d.auth != nil && isUnauthorized(err) {
+			// the token may have been revoked or expired early, fetch a new one and try once more right away
+			d.auth.Invalidate()
+
+			err = generic.SendRawWebhook(ctx, body, contentEncoding, destination, d.auth, d.client)
+		}
+
+		e.recordOutcome(err)
+
+		var bulkErr *generic.BulkError
+		if errors.As(err, &bulkErr) {
+			payload, err = narrowBulk(payload, bulkErr)
+			count = reconstruct_elasticsearch.Count(payload)
+			narrowed = true
+
+			var compressErr error
+			if body, contentEncoding, compressErr = d.compress(payload); compressErr != nil {
+				return compressErr
+			}
+		}
+
+		return err
+	})
+
+	if err != nil && narrowed {
+		return &partialError{payload: payload, count: count, err: err}
+	}
+
+	return err
+}
+
+// unacknowledged reports whether the request was accepted without being acknowledged and is not sent again,
+// its logs are counted as unacknowledged since they are neither known to be delivered nor to have failed
+func (d *Destination) unacknowledged(kind logKind, count int, err error) bool {
+	var ackErr *generic.AckError
+	if !errors.As(err, &ackErr) || ackErr.Retry {
+		return false
+	}
+
+	d.Stats.unacknowledgedForKind(kind).Add(int64(count))
+
+	d.logger().Warn("collector did not acknowledge the request in time, it may or may not have been indexed",
+		slog.String("log_kind", kind.String()),
+		slog.Int("log_count", count),
+		logger.ErrAttr(err),
+	)
+
+	return true
+}
+
+// compress encodes payloads at or above the configured minimum size, returning the body to send and its Content-Encoding
+func (d *Destination) compress(payload []byte) ([]byte, string, error) {
+	if d.compression == config.CompressionNone || int64(len(payload)) < int64(d.Config.CompressionMinSize) {
+		return payload, "", nil
+	}
+
+	body, err := generic.Compress(d.compression, payload)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return body, string(d.compression), nil
+}
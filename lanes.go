@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+
+	"github.com/brody192/locomotive/internal/batch"
+)
+
+// lanes spreads logs over a fixed number of delivery lanes by key, every lane batches and sends its logs in order on its own goroutine,
+// so logs sharing a key are delivered in the order they arrived while logs with different keys are delivered concurrently
+type lanes[T any] struct {
+	queues []chan []T
+	key    func(T) string
+
+	// done once every lane has sent what it was holding at shutdown
+	stopped sync.WaitGroup
+}
+
+func newLanes[T any](concurrency int, queueSize int, key func(T) string) *lanes[T] {
+	queues := make([]chan []T, max(concurrency, 1))
+
+	for i := range queues {
+		queues[i] = make(chan []T, queueSize)
+	}
+
+	return &lanes[T]{
+		queues: queues,
+		key:    key,
+	}
+}
+
+// start runs every lane until its input is closed, batches are handed to send in order per lane.
+//
+// Once its input is closed a lane sends its pending batch and returns, sends are only cut short by the context,
+// which should outlive a shutdown signal so the logs already taken are not lost.
+func (l *lanes[T]) start(ctx context.Context, opts batch.Options, size func(T) int64, send func(ctx context.Context, logs []T)) {
+	for _, queue := range l.queues {
+		l.stopped.Add(1)
+
+		go func() {
+			defer l.stopped.Done()
+
+			batcher := batch.New(opts, size)
+
+			for {
+				select {
+				case <-batcher.Linger():
+					send(ctx, batcher.Flush())
+				case logs, ok := <-queue:
+					if !ok {
+						if pending := batcher.Flush(); len(pending) > 0 {
+							send(ctx, pending)
+						}
+
+						return
+					}
+
+					for _, log := range logs {
+						for _, ready := range batcher.Add(log) {
+							send(ctx, ready)
+						}
+					}
+				}
+			}
+		}()
+	}
+}
+
+// close closes the input of every lane, it must be called once nothing is pushed anymore
+func (l *lanes[T]) close() {
+	for _, queue := range l.queues {
+		close(queue)
+	}
+}
+
+// wait blocks until every lane has sent what it was holding after close
+func (l *lanes[T]) wait() {
+	l.stopped.Wait()
+}
+
+// push hands the logs to their lanes, waiting while a lane's queue is full so a slow destination pushes back on its buffer
+func (l *lanes[T]) push(logs []T) {
+	if len(l.queues) == 1 {
+		l.queues[0] <- logs
+		return
+	}
+
+	grouped := make([][]T, len(l.queues))
+
+	for _, log := range logs {
+		lane := l.laneFor(l.key(log))
+		grouped[lane] = append(grouped[lane], log)
+	}
+
+	for lane, logs := range grouped {
+		if len(logs) > 0 {
+			l.queues[lane] <- logs
+		}
+	}
+}
+
+func (l *lanes[T]) laneFor(key string) int {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+
+	return int(h.Sum32() % uint32(len(l.queues)))
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/brody192/locomotive/internal/batch"
+)
+
+func TestLanes(t *testing.T) {
+	tests := []struct {
+		name        string
+		concurrency int
+		opts        batch.Options
+		keys        []string
+		// the number of logs pushed per key, in batches of pushSize
+		perKey   int
+		pushSize int
+	}{
+		{
+			name:        "a single lane",
+			concurrency: 1,
+			opts:        batch.Options{MaxCount: 3},
+			keys:        []string{"a", "b"},
+			perKey:      10,
+			pushSize:    4,
+		},
+		{
+			name:        "keys spread over lanes",
+			concurrency: 4,
+			opts:        batch.Options{MaxCount: 3},
+			keys:        []string{"a", "b", "c", "d", "e", "f"},
+			perKey:      20,
+			pushSize:    7,
+		},
+		{
+			name:        "pending batches are sent on close",
+			concurrency: 2,
+			opts:        batch.Options{MaxCount: 1000, MaxLinger: time.Hour},
+			keys:        []string{"a", "b", "c"},
+			perKey:      5,
+			pushSize:    2,
+		},
+		{
+			name:        "zero concurrency runs a single lane",
+			concurrency: 0,
+			opts:        batch.Options{MaxCount: 2},
+			keys:        []string{"a", "b"},
+			perKey:      5,
+			pushSize:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// logs are named <key>:<sequence>
+			key := func(log string) string {
+				return strings.Split(log, ":")[0]
+			}
+
+			l := newLanes(tt.concurrency, 1, key)
+
+			mu := sync.Mutex{}
+			sent := map[string][]string{}
+
+			l.start(context.Background(), tt.opts, func(string) int64 { return 1 }, func(ctx context.Context, logs []string) {
+				mu.Lock()
+				defer mu.Unlock()
+
+				for _, log := range logs {
+					sent[key(log)] = append(sent[key(log)], log)
+				}
+			})
+
+			// the keys are interleaved, as logs of different services arrive mixed
+			logs := []string{}
+
+			for i := range tt.perKey {
+				for _, k := range tt.keys {
+					logs = append(logs, fmt.Sprintf("%s:%d", k, i))
+				}
+			}
+
+			for chunk := range slices.Chunk(logs, tt.pushSize) {
+				l.push(chunk)
+			}
+
+			l.close()
+			l.wait()
+
+			for _, k := range tt.keys {
+				want := make([]string, tt.perKey)
+
+				for i := range want {
+					want[i] = fmt.Sprintf("%s:%d", k, i)
+				}
+
+				if !slices.Equal(sent[k], want) {
+					t.Fatalf("got %q for key %s, want %q", sent[k], k, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLaneFor(t *testing.T) {
+	l := newLanes(8, 1, func(s string) string { return s })
+
+	for _, key := range []string{"", "a", "instance-1", "instance-2"} {
+		lane := l.laneFor(key)
+
+		if lane < 0 || lane >= 8 {
+			t.Fatalf("got lane %d for key %q, want one of the 8 lanes", lane, key)
+		}
+
+		if again := l.laneFor(key); again != lane {
+			t.Fatalf("got lane %d and then %d for key %q, want the same lane", lane, again, key)
+		}
+	}
+}
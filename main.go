@@ -2,26 +2,45 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log/slog"
+	"net/url"
 	"os"
+	"os/signal"
 	"sync/atomic"
+	"syscall"
+	"time"
 
+	"github.com/brody192/locomotive/internal/buffer"
 	"github.com/brody192/locomotive/internal/config"
 	"github.com/brody192/locomotive/internal/errgroup"
 	"github.com/brody192/locomotive/internal/logger"
 	"github.com/brody192/locomotive/internal/railway"
 	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
 	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
+	"github.com/brody192/locomotive/internal/webhook"
 )
 
 func main() {
+	config.Load()
+
+	if len(os.Args) > 1 && os.Args[1] == "redrive" {
+		redrive(os.Args[2:])
+		return
+	}
+
 	logger.Stdout.Info("Preparing the locomotive for departure...")
 
+	var railwayProxyUrl *url.URL
+
+	if config.Global.RailwayProxyUrl.Host != "" {
+		railwayProxyUrl = &config.Global.RailwayProxyUrl
+	}
+
 	gqlClient, err := railway.NewClient(&railway.GraphQLClient{
 		AuthToken:           config.Global.RailwayApiKey,
 		BaseURL:             "https://backboard.railway.app/graphql/v2",
 		BaseSubscriptionURL: "wss://backboard.railway.app/graphql/internal",
+		ProxyURL:            railwayProxyUrl,
 	})
 	if err != nil {
 		logger.Stderr.Error("error creating graphql client", logger.ErrAttr(err))
@@ -44,34 +63,56 @@ func main() {
 
 		os.Exit(1)
 	}
-	if !config.Global.MinSeverity.IsValid() {
-		logger.Stderr.Error("invalid MIN_SEVERITY value",
-			slog.String("min_severity", string(config.Global.MinSeverity)),
-		)
+
+	// on shutdown the subscriptions end and every destination sends the logs it is still holding
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	if err := webhook.OpenDeadLetters(); err != nil {
+		logger.Stderr.Error("error opening dead letter sink", logger.ErrAttr(err))
 		os.Exit(1)
 	}
 
+	destinations, err := webhook.NewDestinations(ctx)
+	if err != nil {
+		logger.Stderr.Error("error creating destinations", logger.ErrAttr(err))
+		os.Exit(1)
+	}
+
+	pipelines, err := newDestinationPipelines(destinations)
+	if err != nil {
+		logger.Stderr.Error("error creating destination pipelines", logger.ErrAttr(err))
+		os.Exit(1)
+	}
+
+	destinationNames := make([]string, 0, len(destinations))
+
+	for _, destination := range destinations {
+		destinationNames = append(destinationNames, destination.Name)
+	}
+
 	logger.Stdout.Info("The locomotive is ready to depart...",
-		slog.String("webhook_url_host", config.Global.WebhookUrl.Host),
+		slog.Any("destinations", destinationNames),
 		slog.Any("service_ids", config.Global.ServiceIds),
 		slog.Any("environment_id", config.Global.EnvironmentId),
-		slog.Any("webhook_mode", config.Global.WebhookMode),
 		slog.Bool("enable_http_logs", config.Global.EnableHttpLogs),
 		slog.Bool("enable_deploy_logs", config.Global.EnableDeployLogs),
-		slog.String("min_severity", string(config.Global.MinSeverity)),
+		slog.Int("delivery_concurrency", config.Global.DeliveryConcurrency),
+		slog.String("buffer_policy", string(config.Global.BufferPolicy)),
+		slog.String("spool_dir", config.Global.SpoolDir),
+		slog.String("dead_letter_dir", config.Global.DeadLetterDir),
 	)
-	fmt.Printf("severity level: %s\n", config.Global.MinSeverity)
-	fmt.Println("whitelist filter:")
-	for _, v := range config.Global.Whitelist {
-		fmt.Printf("  \"%s\"\n", v)
-	}
 
-	fmt.Println("blacklist filter:")
-	for _, v := range config.Global.Blacklist {
-		fmt.Printf("  \"%s\"\n", v)
+	for _, destination := range destinations {
+		logger.Stdout.Info("destination configured",
+			slog.String("destination", destination.Name),
+			slog.String("webhook_url_host", destination.Config.WebhookUrl.Host),
+			slog.Any("webhook_mode", destination.Config.WebhookMode),
+			slog.String("min_severity", string(destination.Config.MinSeverity)),
+			slog.Any("whitelist", destination.Config.Whitelist),
+			slog.Any("blacklist", destination.Config.Blacklist),
+		)
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
 	serviceLogTrack := make(chan []environment_logs.EnvironmentLogWithMetadata)
 	httpLogTrack := make(chan []http_logs.DeploymentHttpLogWithMetadata)
@@ -79,20 +120,24 @@ func main() {
 	deployLogsProcessed := atomic.Int64{}
 	httpLogsProcessed := atomic.Int64{}
 
-	reportStatusAsync(&deployLogsProcessed, &httpLogsProcessed)
-	filter_settings, _ := NewFilterSettings(
-		config.Global.MinSeverity,
-		config.Global.Whitelist,
-		config.Global.Blacklist,
-	)
-	fmt.Printf("%s\n", filter_settings)
-	handleDeployLogsAsync(
-		ctx,
-		&deployLogsProcessed,
-		serviceLogTrack,
-		filter_settings,
-	)
-	handleHttpLogsAsync(ctx, &httpLogsProcessed, httpLogTrack)
+	deployLogBuffer := buffer.New(bufferOptions(), estimateDeployLogSize, deployLogSeverity)
+	httpLogBuffer := buffer.New(bufferOptions(), estimateHttpLogSize, httpLogSeverity)
+
+	reportStatusAsync(&deployLogsProcessed, &httpLogsProcessed, deployLogBuffer, httpLogBuffer, pipelines)
+
+	// sends are only cut short once the shutdown timeout is up, so batches taken before a shutdown signal are still delivered
+	deliveryCtx, cancelDelivery := context.WithCancel(context.Background())
+	defer cancelDelivery()
+
+	for _, pipeline := range pipelines {
+		pipeline.start(deliveryCtx)
+	}
+
+	bufferLogsAsync(ctx, "deploy logs", serviceLogTrack, deployLogBuffer)
+	bufferLogsAsync(ctx, "http logs", httpLogTrack, httpLogBuffer)
+
+	handleDeployLogsAsync(deliveryCtx, &deployLogsProcessed, deployLogBuffer, pipelines)
+	handleHttpLogsAsync(deliveryCtx, &httpLogsProcessed, httpLogBuffer, pipelines)
 
 	errGroup := errgroup.NewErrGroup()
 
@@ -116,8 +161,44 @@ func main() {
 
 	logger.Stdout.Info("The locomotive is waiting for cargo...")
 
-	if err := errGroup.Wait(); err != nil {
-		logger.Stderr.Error("error returned from subscription(s)", logger.ErrAttr(err))
-		os.Exit(1)
+	go func() {
+		if err := errGroup.Wait(); err != nil && ctx.Err() == nil {
+			logger.Stderr.Error("error returned from subscription(s)", logger.ErrAttr(err))
+			os.Exit(1)
+		}
+
+		cancel()
+	}()
+
+	// the subscriptions are not waited for once a shutdown signal arrives, only the delivery of what was already received
+	<-ctx.Done()
+
+	logger.Stdout.Info("The locomotive is unloading its remaining cargo...",
+		slog.Duration("shutdown_timeout", config.Global.ShutdownTimeout),
+	)
+
+	// the buffered logs are drained through every destination's buffer and lanes in turn, which then send what they are holding
+	deployLogBuffer.Close()
+	httpLogBuffer.Close()
+
+	// whatever is not delivered by then fails right away and is counted as dropped, unless it is queued in the spool
+	shutdownTimer := time.AfterFunc(config.Global.ShutdownTimeout, cancelDelivery)
+	defer shutdownTimer.Stop()
+
+	for _, pipeline := range pipelines {
+		pipeline.stop()
 	}
+
+	finalStatus := []any{
+		slog.Int64("deploy_logs_processed", deployLogsProcessed.Load()),
+		slog.Int64("http_logs_processed", httpLogsProcessed.Load()),
+		bufferStatusAttr("deploy_log_buffer", deployLogBuffer),
+		bufferStatusAttr("http_log_buffer", httpLogBuffer),
+	}
+
+	for _, pipeline := range pipelines {
+		finalStatus = append(finalStatus, destinationStatusAttr(pipeline))
+	}
+
+	logger.Stdout.Info("The locomotive has unloaded its cargo", finalStatus...)
 }
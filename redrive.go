@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/webhook"
+)
+
+// redrive re-sends the payloads in the dead letter directory, it is run with `locomotive redrive [-destination name]`
+func redrive(args []string) {
+	flags := flag.NewFlagSet("redrive", flag.ExitOnError)
+
+	destination := flags.String("destination", "", "only re-drive entries for this destination")
+
+	flags.Parse(args)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	logger.Stdout.Info("Re-driving dead lettered payloads...", slog.String("destination", *destination))
+
+	result, err := webhook.RedriveDeadLetters(ctx, *destination)
+
+	logger.Stdout.Info("Finished re-driving dead lettered payloads",
+		slog.Int("delivered", result.Delivered),
+		slog.Int("failed", result.Failed),
+		slog.Int("skipped", result.Skipped),
+	)
+
+	if err != nil {
+		logger.Stderr.Error("error re-driving dead lettered payloads", logger.ErrAttr(err))
+		os.Exit(1)
+	}
+
+	if result.Failed > 0 {
+		os.Exit(1)
+	}
+}
@@ -6,12 +6,21 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/brody192/locomotive/internal/buffer"
 	"github.com/brody192/locomotive/internal/config"
 	"github.com/brody192/locomotive/internal/logger"
+	"github.com/brody192/locomotive/internal/railway/subscribe/environment_logs"
+	"github.com/brody192/locomotive/internal/railway/subscribe/http_logs"
 	"github.com/brody192/locomotive/internal/util"
 )
 
-func reportStatusAsync(deployLogsProcessed *atomic.Int64, httpLogsProcessed *atomic.Int64) {
+func reportStatusAsync(
+	deployLogsProcessed *atomic.Int64,
+	httpLogsProcessed *atomic.Int64,
+	deployLogBuffer *buffer.Queue[environment_logs.EnvironmentLogWithMetadata],
+	httpLogBuffer *buffer.Queue[http_logs.DeploymentHttpLogWithMetadata],
+	pipelines []*destinationPipeline,
+) {
 	initReport := make(chan struct{}, 1)
 
 	var prevDeployLogs, prevHttpLogs int64
@@ -56,8 +65,14 @@ func reportStatusAsync(deployLogsProcessed *atomic.Int64, httpLogsProcessed *ato
 			statusLog := logger.Stdout.With(
 				slog.Int64("deploy_logs_processed", deployLogsProcessed),
 				slog.Int64("http_logs_processed", httpLogsProcessed),
+				bufferStatusAttr("deploy_log_buffer", deployLogBuffer),
+				bufferStatusAttr("http_log_buffer", httpLogBuffer),
 			)
 
+			for _, pipeline := range pipelines {
+				statusLog = statusLog.With(destinationStatusAttr(pipeline))
+			}
+
 			if logger.StdoutLvl.Level() == slog.LevelDebug {
 				memStats := &runtime.MemStats{}
 				runtime.ReadMemStats(memStats)
@@ -83,3 +98,39 @@ func reportStatusAsync(deployLogsProcessed *atomic.Int64, httpLogsProcessed *ato
 		}
 	}()
 }
+
+func destinationStatusAttr(pipeline *destinationPipeline) slog.Attr {
+	destination := pipeline.destination
+
+	attrs := []any{
+		slog.Int64("deploy_logs_sent", destination.Stats.DeployLogsSent.Load()),
+		slog.Int64("http_logs_sent", destination.Stats.HttpLogsSent.Load()),
+		slog.Int64("deploy_logs_dropped", destination.Stats.DeployLogsDropped.Load()),
+		slog.Int64("http_logs_dropped", destination.Stats.HttpLogsDropped.Load()),
+		slog.Int64("deploy_logs_rate_limited", destination.Stats.DeployLogsRateLimited.Load()),
+		slog.Int64("http_logs_rate_limited", destination.Stats.HttpLogsRateLimited.Load()),
+		slog.Int64("deploy_logs_unacknowledged", destination.Stats.DeployLogsUnacknowledged.Load()),
+		slog.Int64("http_logs_unacknowledged", destination.Stats.HttpLogsUnacknowledged.Load()),
+		slog.Int64("deploy_logs_dead_lettered", destination.Stats.DeployLogsDeadLettered.Load()),
+		slog.Int64("http_logs_dead_lettered", destination.Stats.HttpLogsDeadLettered.Load()),
+		slog.Int64("retries", destination.Stats.Retries.Load()),
+		slog.String("circuit_state", destination.CircuitState().String()),
+		slog.String("active_endpoint", destination.ActiveEndpoint()),
+		slog.String("bytes_raw", util.ByteCountIEC(uint64(destination.Stats.BytesRaw.Load()))),
+		slog.String("bytes_sent", util.ByteCountIEC(uint64(destination.Stats.BytesSent.Load()))),
+	}
+
+	attrs = append(attrs,
+		bufferStatusAttr("deploy_log_buffer", pipeline.deployLogBuffer),
+		bufferStatusAttr("http_log_buffer", pipeline.httpLogBuffer),
+	)
+
+	if pendingBytes, evictedBatches, ok := destination.SpoolStats(); ok {
+		attrs = append(attrs,
+			slog.String("spool_pending", util.ByteCountIEC(uint64(pendingBytes))),
+			slog.Int64("spool_batches_evicted", evictedBatches),
+		)
+	}
+
+	return slog.Group("destination_"+destination.Name, attrs...)
+}